@@ -4,89 +4,69 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/tests/copilot/ryder/internal/backend"
+	"github.com/tests/copilot/ryder/internal/migrate"
 )
 
-func autoMigrate(db *sql.DB) {
-	tables := []string{
-		`CREATE TABLE IF NOT EXISTS players (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL,
-			email TEXT,
-			hcp REAL
-		);`,
-		`CREATE TABLE IF NOT EXISTS teams (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL,
-			color TEXT DEFAULT '#2563eb'
-		);`,
-		`CREATE TABLE IF NOT EXISTS team_players (
-			team_id INTEGER NOT NULL,
-			player_id INTEGER NOT NULL,
-			PRIMARY KEY (team_id, player_id),
-			FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE,
-			FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
-		);`,
-		`CREATE TABLE IF NOT EXISTS matches (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			team_a_id INTEGER,
-			team_b_id INTEGER,
-			format TEXT NOT NULL,
-			status TEXT NOT NULL,
-			start_time TEXT,
-			holes TEXT DEFAULT '18',
-			FOREIGN KEY (team_a_id) REFERENCES teams(id),
-			FOREIGN KEY (team_b_id) REFERENCES teams(id)
-		);`,
-		`CREATE TABLE IF NOT EXISTS match_players (
-			match_id INTEGER NOT NULL,
-			player_id INTEGER NOT NULL,
-			team_side TEXT NOT NULL, -- 'A' or 'B'
-			PRIMARY KEY (match_id, player_id),
-			FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE,
-			FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
-		);`,
-		`CREATE TABLE IF NOT EXISTS scores (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			match_id INTEGER NOT NULL,
-			player_id INTEGER NOT NULL,
-			hole INTEGER NOT NULL,
-			strokes INTEGER NOT NULL,
-			FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE,
-			FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
-		);`,
-		`CREATE TABLE IF NOT EXISTS hole_results (
-			match_id INTEGER NOT NULL,
-			hole INTEGER NOT NULL,
-			result TEXT,
-			PRIMARY KEY (match_id, hole)
-		);`,
-	}
-	for _, stmt := range tables {
-		if _, err := db.Exec(stmt); err != nil {
-			log.Fatalf("failed to migrate: %v", err)
+// openDB opens the database named by DB_URL, a postgres:// URL or the path of
+// a SQLite file (ryder.db when unset), and returns it with its driver name.
+func openDB() (*sql.DB, string) {
+	dsn := os.Getenv("DB_URL")
+	driver := "sqlite3"
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		driver = "postgres"
+	} else {
+		if dsn == "" {
+			dsn = "ryder.db"
+		}
+		// Transactions take the write lock when they begin, so two of them
+		// can't deadlock upgrading their read locks
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
 		}
+		dsn += sep + "_txlock=immediate"
 	}
-
-	// Add HCP column if not exists
-	_, _ = db.Exec("ALTER TABLE players ADD COLUMN hcp REAL;")
-	// Add holes column if not exists
-	_, _ = db.Exec("ALTER TABLE matches ADD COLUMN holes TEXT DEFAULT '18';")
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		log.Fatalf("failed to open database: %v", err)
+	}
+	return db, driver
 }
 
 func main() {
+	// Load .env file if present, DB_URL may come from it
+	_ = godotenv.Load()
+
+	db, driver := openDB()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(db, driver, os.Args[2:])
+		return
+	}
+
 	fmt.Println("Hello, Ryder!")
 
-	db, err := sql.Open("sqlite3", "ryder.db")
+	m, err := migrate.New(db, driver)
 	if err != nil {
-		log.Fatalf("failed to open database: %v", err)
+		log.Fatalf("failed to migrate: %v", err)
+	}
+	applied, err := m.Up()
+	for _, mig := range applied {
+		fmt.Printf("Applied migration %03d_%s\n", mig.Version, mig.Name)
+	}
+	if err != nil {
+		log.Fatalf("failed to migrate: %v", err)
 	}
-	autoMigrate(db)
-	backend.DB = db
-
-	// TODO: Run DB migrations if needed
 
-	backend.StartServer()
+	if driver == "postgres" {
+		backend.StartServer(backend.NewPostgresStore(db))
+	} else {
+		backend.StartServer(backend.NewSQLiteStore(db))
+	}
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/tests/copilot/ryder/internal/migrate"
+)
+
+const migrateUsage = "usage: ryder migrate up | down [n] | status"
+
+// runMigrate implements `ryder migrate up|down [n]|status`.
+func runMigrate(db *sql.DB, driver string, args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+	m, err := migrate.New(db, driver)
+	if err != nil {
+		log.Fatalf("failed to migrate: %v", err)
+	}
+	switch args[0] {
+	case "up":
+		applied, err := m.Up()
+		for _, mig := range applied {
+			fmt.Printf("Applied %03d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Nothing to apply")
+		}
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				log.Fatal(migrateUsage)
+			}
+		}
+		reverted, err := m.Down(n)
+		for _, mig := range reverted {
+			fmt.Printf("Reverted %03d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("Nothing to revert")
+		}
+	case "status":
+		list, err := m.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, st := range list {
+			applied := st.AppliedAt
+			if applied == "" {
+				applied = "pending"
+			} else if st.Changed {
+				applied += " (changed since)"
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\n", st.Version, st.Name, applied)
+		}
+		w.Flush()
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
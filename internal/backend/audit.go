@@ -0,0 +1,233 @@
+package backend
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditEntry records one change to a match: its status, concession, points,
+// the result of a hole, the result a side entered on its card for a hole or
+// the strokes of a player on a hole. Old and New are empty for values that
+// were not set.
+type AuditEntry struct {
+	ID       int    `json:"id"`
+	MatchID  int    `json:"match_id"`
+	Actor    string `json:"actor"`
+	At       string `json:"at"`    // RFC 3339, UTC
+	Field    string `json:"field"` // status, conceded, points, hole, side_a, side_b or strokes
+	Hole     int    `json:"hole,omitempty"`
+	PlayerID int    `json:"player_id,omitempty"`
+	Old      string `json:"old"`
+	New      string `json:"new"`
+}
+
+// auditActor names who made a request: the logged in user, or the holder of
+// the match's scorecard.
+func auditActor(r *http.Request) string {
+	if u := currentUser(r); u != nil {
+		return u.Username
+	}
+	if scorerTokenFromRequest(r) != "" {
+		return "scorecard"
+	}
+	return "anonymous"
+}
+
+// matchSnapshot is the audited state of a match. Its holes are the 18 hole
+// results, with an empty Result for holes without one.
+type matchSnapshot struct {
+	status   string
+	conceded string
+	points   string
+	holes    []HoleResult
+	sides    map[string][]string // A and B, the 18 results each side entered
+	strokes  map[[2]int]int      // hole, player id -> strokes
+}
+
+// newSnapshot returns the state of a match without any results.
+func newSnapshot(status string) *matchSnapshot {
+	snap := &matchSnapshot{status: status, holes: make([]HoleResult, 18), strokes: map[[2]int]int{},
+		sides: map[string][]string{"A": make([]string, 18), "B": make([]string, 18)}}
+	for i := range snap.holes {
+		snap.holes[i].Hole = i + 1
+	}
+	return snap
+}
+
+func snapshotMatch(st Store, matchID int) (*matchSnapshot, error) {
+	m, err := st.GetMatch(matchID)
+	if err != nil {
+		return nil, err
+	}
+	snap := newSnapshot(m.Status)
+	snap.conceded = m.Conceded
+	if m.Points != nil {
+		snap.points = strconv.FormatFloat(*m.Points, 'f', -1, 64)
+	}
+	holes, err := st.HoleResults(matchID)
+	if err != nil {
+		return nil, err
+	}
+	for _, hr := range holes {
+		if hr.Hole >= 1 && hr.Hole <= 18 && hr.Result != "" {
+			snap.holes[hr.Hole-1] = hr
+		}
+	}
+	entries, err := st.SideResults(matchID)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if results, ok := snap.sides[e.Side]; ok && e.Hole >= 1 && e.Hole <= 18 {
+			results[e.Hole-1] = e.Result
+		}
+	}
+	scores, err := st.ListScores(matchID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sc := range scores {
+		snap.strokes[[2]int{sc.Hole, sc.PlayerID}] = sc.Strokes
+	}
+	return snap, nil
+}
+
+// diff returns the audit entries and the history events that turn snapshot a
+// into b, without actor and time. Points are audited only, the source of a
+// hole result is kept in the history only.
+func (a *matchSnapshot) diff(b *matchSnapshot) ([]AuditEntry, []MatchEvent) {
+	var entries []AuditEntry
+	var events []MatchEvent
+	if a.status != b.status {
+		entries = append(entries, AuditEntry{Field: "status", Old: a.status, New: b.status})
+		events = append(events, MatchEvent{Type: MatchEventStatusChanged, Value: b.status})
+	}
+	if a.conceded != b.conceded {
+		entries = append(entries, AuditEntry{Field: "conceded", Old: a.conceded, New: b.conceded})
+		events = append(events, MatchEvent{Type: MatchEventConceded, Value: b.conceded})
+	}
+	if a.points != b.points {
+		entries = append(entries, AuditEntry{Field: "points", Old: a.points, New: b.points})
+	}
+	for i, h := range b.holes {
+		old := a.holes[i]
+		if old.Result != h.Result {
+			entries = append(entries, AuditEntry{Field: "hole", Hole: i + 1, Old: old.Result, New: h.Result})
+		}
+		switch {
+		case h.Result == "" && old.Result != "":
+			events = append(events, MatchEvent{Type: MatchEventHoleCleared, Hole: i + 1})
+		case h.Result != "" && (old.Result != h.Result || old.Source != h.Source):
+			events = append(events, MatchEvent{Type: MatchEventHoleSet, Hole: i + 1, Value: h.Result, Source: h.Source})
+		}
+	}
+	for _, side := range []string{"A", "B"} {
+		for i, v := range b.sides[side] {
+			if old := a.sides[side][i]; old != v {
+				entries = append(entries, AuditEntry{Field: "side_" + strings.ToLower(side), Hole: i + 1, Old: old, New: v})
+				events = append(events, MatchEvent{Type: MatchEventSideSet, Hole: i + 1, Value: v, Source: side})
+			}
+		}
+	}
+	strokes := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+	keys := slices.Collect(maps.Keys(b.strokes))
+	for k := range a.strokes {
+		if _, ok := b.strokes[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(x, y [2]int) int { return cmp.Or(cmp.Compare(x[0], y[0]), cmp.Compare(x[1], y[1])) })
+	for _, k := range keys {
+		if a.strokes[k] != b.strokes[k] {
+			entries = append(entries, AuditEntry{Field: "strokes", Hole: k[0], PlayerID: k[1], Old: strokes(a.strokes[k]), New: strokes(b.strokes[k])})
+			events = append(events, MatchEvent{Type: MatchEventStrokesSet, Hole: k[0], PlayerID: k[1], Value: strokes(b.strokes[k])})
+		}
+	}
+	return entries, events
+}
+
+// audited runs fn, which changes a match through tx, and logs every change
+// it made to the match's status, points, hole results and strokes, including
+// the ones derived from the request's. The changes are appended to the
+// match's history as one action.
+func audited(tx Store, r *http.Request, matchID int, fn func() error) error {
+	return recordAction(tx, r, matchID, 0, fn)
+}
+
+// recordAction is audited for an action that undoes an earlier one, undoes is
+// the number of that action or 0. A change of a hole result or of a side's
+// card voids the signatures of the match's card.
+func recordAction(tx Store, r *http.Request, matchID, undoes int, fn func() error) error {
+	before, err := snapshotMatch(tx, matchID)
+	if err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	after, err := snapshotMatch(tx, matchID)
+	if err != nil {
+		return err
+	}
+	actor, at := auditActor(r), time.Now().UTC().Format(time.RFC3339)
+	entries, events := before.diff(after)
+	for _, e := range entries {
+		e.MatchID, e.Actor, e.At = matchID, actor, at
+		if err := tx.AddAuditEntry(&e); err != nil {
+			return fmt.Errorf("audit log: %w", err)
+		}
+	}
+	if slices.ContainsFunc(events, func(e MatchEvent) bool {
+		return e.Type == MatchEventHoleSet || e.Type == MatchEventHoleCleared || e.Type == MatchEventSideSet
+	}) {
+		if err := tx.ClearCardSignatures(matchID); err != nil {
+			return err
+		}
+	}
+	if slices.ContainsFunc(events, func(e MatchEvent) bool { return e.Type == MatchEventSideSet }) {
+		noteCardChange(r, matchID)
+	}
+	if undoes != 0 {
+		events = append(events, MatchEvent{Type: MatchEventUndo, Value: strconv.Itoa(undoes)})
+	}
+	if len(events) > 0 {
+		for i := range events {
+			events[i].Actor, events[i].At = actor, at
+		}
+		if err := tx.AddMatchEvents(matchID, events); err != nil {
+			return fmt.Errorf("match history: %w", err)
+		}
+	}
+	noteLiveChanges(r, matchID, events)
+	return nil
+}
+
+// --- Audit Handlers ---
+
+// ListAudit returns the audit log of a match, oldest first.
+func (srv *Server) ListAudit(w http.ResponseWriter, r *http.Request) {
+	matchID, err := strconv.Atoi(r.URL.Query().Get("match_id"))
+	if err != nil {
+		http.Error(w, "Invalid match id", http.StatusBadRequest)
+		return
+	}
+	entries, err := srv.store.AuditEntries(matchID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"entries": entries})
+}
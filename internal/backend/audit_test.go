@@ -0,0 +1,113 @@
+package backend
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// auditLines returns the audit log of a match as "actor field hole/player old>new".
+func (c *testClient) auditLines(matchID string) []string {
+	c.t.Helper()
+	var res struct {
+		Entries []AuditEntry `json:"entries"`
+	}
+	c.get("/api/audit?match_id="+matchID, &res)
+	var lines []string
+	for _, e := range res.Entries {
+		if e.At == "" {
+			c.t.Errorf("audit entry without a time: %+v", e)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %d/%d %s>%s", e.Actor, e.Field, e.Hole, e.PlayerID, e.Old, e.New))
+	}
+	return lines
+}
+
+func TestAuditLog(t *testing.T) {
+	c, _ := newTestServer(t)
+	singlesMatch(c)
+	c.mustPost("/api/player/add", `{"name":"Carol","hcp":10,"team_id":2}`)
+	scorer := c.as(c.scorerToken("1", ""))
+
+	// Strokes, and the hole result they decide
+	scorer.mustPost("/api/score/submit", `{"match_id":1,"player_id":1,"hole":1,"strokes":4}`)
+	scorer.mustPost("/api/score/submit", `{"match_id":1,"player_id":2,"hole":1,"strokes":5}`)
+	// A hole edit
+	c.mustPost("/api/match/holescore", `{"match_id":1,"holes":["B"]}`)
+	// A status change
+	c.mustPost("/api/match/status", `{"match_id":1,"status":"running"}`)
+	// A player change: Carol plays for Bob, whose strokes go
+	c.mustPost("/api/match/edit", `{"id":1,"team_a":1,"team_b":2,"format":"singles","holes":"front9","players_a":[1],"players_b":[3]}`)
+
+	want := []string{
+		"scorecard strokes 1/1 >4",
+		"scorecard hole 1/0 >A",
+		"scorecard strokes 1/2 >5",
+		"admin hole 1/0 A>B",
+		"admin status 0/0 prepared>running",
+		"admin strokes 1/2 5>",
+	}
+	if got := c.auditLines("1"); !slices.Equal(got, want) {
+		t.Errorf("audit log:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+// TestAuditedClearsSignatures voids the signatures of a card when a hole
+// result is set or cleared, and only then.
+func TestAuditedClearsSignatures(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(tx Store, matchID int) error
+		voids  bool
+	}{
+		{"hole set", func(tx Store, matchID int) error {
+			return tx.SetHoleResult(matchID, HoleResult{Hole: 6, Result: "B", Source: SourceManual})
+		}, true},
+		{"hole changed", func(tx Store, matchID int) error {
+			return tx.SetHoleResult(matchID, HoleResult{Hole: 1, Result: "AS", Source: SourceManual})
+		}, true},
+		{"hole cleared", func(tx Store, matchID int) error {
+			return tx.SetHoleResult(matchID, HoleResult{Hole: 1})
+		}, true},
+		{"same result", func(tx Store, matchID int) error {
+			return tx.SetHoleResult(matchID, HoleResult{Hole: 1, Result: "A", Source: SourceManual})
+		}, false},
+		{"status", func(tx Store, matchID int) error {
+			m, err := tx.GetMatch(matchID)
+			if err != nil {
+				return err
+			}
+			m.Status = "running"
+			return tx.UpdateMatch(*m)
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := NewMemoryStore()
+			m := Match{TeamA: 1, TeamB: 2, Format: Singles, Holes: "front9", Status: "prepared"}
+			if err := st.AddMatch(&m); err != nil {
+				t.Fatal(err)
+			}
+			for hole := 1; hole <= 5; hole++ {
+				if err := st.SetHoleResult(m.ID, HoleResult{Hole: hole, Result: "A", Source: SourceManual}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := st.AddCardSignature(&CardSignature{MatchID: m.ID, Side: "A", Signer: "Alice"}); err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest("POST", "/api/match/holescore", nil)
+			if err := audited(st, r, m.ID, func() error { return tt.change(st, m.ID) }); err != nil {
+				t.Fatal(err)
+			}
+			sigs, err := st.CardSignatures(m.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if voided := len(sigs) == 0; voided != tt.voids {
+				t.Errorf("signatures voided: got %v, want %v", voided, tt.voids)
+			}
+		})
+	}
+}
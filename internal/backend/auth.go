@@ -0,0 +1,458 @@
+package backend
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrLoginRequired is returned for writes by visitors who aren't logged in.
+var ErrLoginRequired = errors.New("login required")
+
+// ErrForbidden is returned for requests the user's role doesn't allow.
+var ErrForbidden = errors.New("not allowed for your role")
+
+const (
+	loginCookie = "ryder_login"
+	loginTTL    = 14 * 24 * time.Hour
+)
+
+// readRoutes are open to everyone on GET, the dashboard and score pages use them.
+var readRoutes = map[string]bool{
+	"/api/event/list":      true,
+	"/api/player/list":     true,
+	"/api/team/list":       true,
+	"/api/team/players":    true,
+	"/api/course/list":     true,
+	"/api/session/list":    true,
+	"/api/match/list":      true,
+	"/api/match/score":     true,
+	"/api/match/holescore": true,
+	"/api/match/history":   true,
+	"/api/match/card":      true,
+	"/api/score/list":      true,
+	"/api/dashboard":       true,
+	"/api/events":          true,
+}
+
+// userRoutes can be read by every logged in user.
+var userRoutes = map[string]bool{
+	"/api/audit": true,
+}
+
+// scoreRoutes are the writes of scorers, see authorizeScoring.
+var scoreRoutes = map[string]bool{
+	"/api/score/submit":    true,
+	"/api/match/holescore": true,
+	"/api/match/status":    true,
+	"/api/match/undo":      true,
+	"/api/match/sync":      true,
+}
+
+// sideRoutes are the writes to the card of one side of a match and its
+// concession, see authorizeSide.
+var sideRoutes = map[string]bool{
+	"/api/match/card":    true,
+	"/api/match/sign":    true,
+	"/api/match/concede": true,
+}
+
+// captainRoutes are the writes to rosters and lineups, see authorizeCaptain.
+var captainRoutes = map[string]bool{
+	"/api/team/assign": true,
+	"/api/player/add":  true,
+	"/api/player/edit": true,
+	"/api/match/edit":  true,
+}
+
+type userKey struct{}
+
+// currentUser returns the user logged in for the request, nil for visitors.
+func currentUser(r *http.Request) *User {
+	u, _ := r.Context().Value(userKey{}).(*User)
+	return u
+}
+
+// hashToken is what the store keeps of a login token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// userFromRequest returns the user of the request's login cookie, nil when
+// there is none or it expired.
+func (srv *Server) userFromRequest(r *http.Request) (*User, error) {
+	c, err := r.Cookie(loginCookie)
+	if err != nil || c.Value == "" {
+		return nil, nil
+	}
+	u, err := srv.store.LoginUser(hashToken(c.Value))
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return u, err
+}
+
+// authenticate wraps the routes: it puts the logged in user in the request
+// context and rejects the requests their role doesn't allow. Visitors are sent
+// to the login page for /admin.
+func (srv *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, err := srv.userFromRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if u != nil {
+			r = r.WithContext(context.WithValue(r.Context(), userKey{}, u))
+		}
+		if r.URL.Path == "/admin" || r.URL.Path == "/admin/" {
+			if u == nil {
+				http.Redirect(w, r, "/login?next=/admin", http.StatusSeeOther)
+				return
+			}
+		} else if err := srv.authorize(r, u); err != nil {
+			writeError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorize decides whether the user, nil for visitors, may make a request.
+// Pages and reads are open, the cards of a side are written by that side
+// only, admins may do anything else, the writes of the other roles are
+// checked against their team or matches. Everything else under /api/
+// is for admins only. Writes only take POST, see Handler.
+func (srv *Server) authorize(r *http.Request, u *User) error {
+	path := r.URL.Path
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead
+	switch {
+	case !strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/api/auth/"):
+		return nil
+	case readRoutes[path] && read:
+		return nil
+	case sideRoutes[path]:
+		return srv.authorizeSide(r, u)
+	case u != nil && u.Role == RoleAdmin:
+		return nil
+	case u != nil && u.Role == RoleCaptain && captainRoutes[path]:
+		return srv.authorizeCaptain(r, u)
+	case scoreRoutes[path]:
+		return srv.authorizeScoring(r, u)
+	case u == nil:
+		return ErrLoginRequired
+	case userRoutes[path] && read:
+		return nil
+	}
+	return ErrForbidden
+}
+
+// peekBody decodes the JSON body of a request into v and leaves it in place
+// for the handler.
+func peekBody(r *http.Request, v any) error {
+	data, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	if err := json.Unmarshal(data, v); err != nil {
+		return badRequest{err}
+	}
+	return nil
+}
+
+// authorizeScoring lets scorers write the matches they are assigned to, and
+// anyone holding a match's scorer token write that match.
+func (srv *Server) authorizeScoring(r *http.Request, u *User) error {
+	var body struct {
+		MatchID int `json:"match_id"`
+	}
+	if err := peekBody(r, &body); err != nil {
+		return err
+	}
+	if u != nil && u.Role == RoleScorer {
+		ids, err := srv.store.UserMatches(u.ID)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(ids, body.MatchID) {
+			return nil
+		}
+	}
+	err := srv.checkScorerToken(r, body.MatchID, "")
+	if errors.Is(err, ErrScorerToken) && u != nil {
+		return ErrForbidden
+	}
+	return err
+}
+
+// authorizeSide lets the captain of a side's team, and anyone holding the
+// scorer token of that side, enter and sign the side's card or concede. Nobody else may,
+// admins and the match's scorers included, so that each side vouches for its
+// own card.
+func (srv *Server) authorizeSide(r *http.Request, u *User) error {
+	var body struct {
+		MatchID int    `json:"match_id"`
+		Side    string `json:"side"`
+	}
+	if err := peekBody(r, &body); err != nil {
+		return err
+	}
+	if body.Side != "A" && body.Side != "B" {
+		return badRequest{errors.New("invalid side")}
+	}
+	captain, err := isSideCaptain(srv.store, u, body.MatchID, body.Side)
+	if err != nil || captain {
+		return err
+	}
+	err = srv.checkScorerToken(r, body.MatchID, body.Side)
+	if errors.Is(err, ErrScorerToken) && u != nil {
+		return fmt.Errorf("%w: only side %s can do that", ErrForbidden, body.Side)
+	}
+	return err
+}
+
+// isSideCaptain reports whether u is the captain of the team playing a side
+// of a match.
+func isSideCaptain(st Store, u *User, matchID int, side string) (bool, error) {
+	if u == nil || u.Role != RoleCaptain || u.TeamID == nil {
+		return false, nil
+	}
+	m, err := st.GetMatch(matchID)
+	if err != nil {
+		return false, err
+	}
+	team := m.TeamA
+	if side == "B" {
+		team = m.TeamB
+	}
+	return team == *u.TeamID, nil
+}
+
+// checkConcessionWithdrawal lets admins, and the captain or the token holder of
+// the side that conceded a match, withdraw the concession: reopening the match
+// or undoing the concession. The match's scorers may not, they get a conflict.
+// st is the store of the transaction.
+func checkConcessionWithdrawal(st Store, r *http.Request, matchID int, side string) error {
+	u := currentUser(r)
+	if u != nil && u.Role == RoleAdmin {
+		return nil
+	}
+	captain, err := isSideCaptain(st, u, matchID, side)
+	if err != nil || captain {
+		return err
+	}
+	token, err := st.ScorerToken(matchID, side)
+	if err != nil {
+		return err
+	}
+	if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(scorerTokenFromRequest(r))) == 1 {
+		return nil
+	}
+	return conflict{fmt.Errorf("side %s conceded the match, only they or an admin can reopen it", side)}
+}
+
+// authorizeCaptain lets captains change the players of their team and their
+// side of its matches.
+func (srv *Server) authorizeCaptain(r *http.Request, u *User) error {
+	if u.TeamID == nil {
+		return ErrForbidden
+	}
+	team := *u.TeamID
+	var body struct {
+		ID     int  `json:"id"`
+		TeamID *int `json:"team_id"`
+	}
+	switch r.URL.Path {
+	case "/api/team/assign", "/api/player/add":
+		if err := peekBody(r, &body); err != nil {
+			return err
+		}
+		if body.TeamID != nil && *body.TeamID == team {
+			return nil
+		}
+	case "/api/player/edit":
+		if err := peekBody(r, &body); err != nil {
+			return err
+		}
+		if body.TeamID == nil || *body.TeamID != team {
+			return fmt.Errorf("%w: captains can't move players off their team", ErrForbidden)
+		}
+		players, err := srv.store.TeamPlayers(team)
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(players, func(p Player) bool { return p.ID == body.ID }) {
+			return nil
+		}
+	case "/api/match/edit":
+		var m matchRequest
+		if err := peekBody(r, &m); err != nil {
+			return err
+		}
+		return srv.checkLineupEdit(m, team)
+	}
+	return ErrForbidden
+}
+
+// checkLineupEdit allows an edit of a match that only changes the players of
+// the given team's side.
+func (srv *Server) checkLineupEdit(body matchRequest, team int) error {
+	m, err := srv.store.GetMatch(body.ID)
+	if err != nil {
+		return err
+	}
+	side, others := "", body.PlayersA
+	switch team {
+	case m.TeamA:
+		side, others = "A", body.PlayersB
+	case m.TeamB:
+		side = "B"
+	default:
+		return ErrForbidden
+	}
+	entrants, err := srv.store.MatchPlayers(m.ID)
+	if err != nil {
+		return err
+	}
+	var current []int
+	for _, e := range entrants {
+		if e.Side != side {
+			current = append(current, e.ID)
+		}
+	}
+	// Both "" and 18 are a full round
+	holes := func(h string) string { return cmp.Or(h, "18") }
+	same := body.TeamA == m.TeamA && body.TeamB == m.TeamB && holes(body.Holes) == holes(m.Holes) && body.StartTime == m.StartTime &&
+		(body.Format == "" || MatchFormat(body.Format) == m.Format) &&
+		equalPtr(body.SessionID, m.SessionID) && equalPtr(body.CourseID, m.CourseID) &&
+		equalPtr(body.TeeID, m.TeeID) && equalPtr(body.Points, m.Points) &&
+		slices.Equal(others, current)
+	if !same {
+		return fmt.Errorf("%w: captains can only change their own team's players", ErrForbidden)
+	}
+	return nil
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// bootstrapAdmin creates the first admin from ADMIN_USER (default admin) and
+// ADMIN_PASSWORD while there are no users.
+func bootstrapAdmin(store Store) error {
+	users, err := store.ListUsers()
+	if err != nil || len(users) > 0 {
+		return err
+	}
+	password := os.Getenv("ADMIN_PASSWORD")
+	if password == "" {
+		fmt.Println("No users yet: set ADMIN_PASSWORD to create the first admin")
+		return nil
+	}
+	u := User{Username: os.Getenv("ADMIN_USER"), Role: RoleAdmin}
+	if u.Username == "" {
+		u.Username = "admin"
+	}
+	if u.PasswordHash, err = hashPassword(password); err != nil {
+		return err
+	}
+	if err := store.AddUser(&u); err != nil {
+		return err
+	}
+	fmt.Printf("Created admin user %s\n", u.Username)
+	return nil
+}
+
+// --- Auth Handlers ---
+
+// Login checks a username and password and sets the login cookie.
+func (srv *Server) Login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	u, err := srv.store.UserByName(strings.TrimSpace(body.Username))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err != nil || bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(body.Password)) != nil {
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+	token := newToken()
+	expires := time.Now().Add(loginTTL)
+	if err := srv.store.AddLogin(hashToken(token), u.ID, expires); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	u.PasswordHash = ""
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(u)
+}
+
+// Logout ends the login of the request.
+func (srv *Server) Logout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(loginCookie); err == nil {
+		if err := srv.store.RemoveLogin(hashToken(c.Value)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: loginCookie, Path: "/", MaxAge: -1})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Me returns the logged in user with the matches they score.
+func (srv *Server) Me(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+	if u == nil {
+		writeError(w, ErrLoginRequired)
+		return
+	}
+	if u.Role == RoleScorer {
+		var err error
+		if u.MatchIDs, err = srv.store.UserMatches(u.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(u)
+}
@@ -0,0 +1,393 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SideResult is the result of a hole as the scorer of one side of a match
+// entered it.
+type SideResult struct {
+	Side   string `json:"side"` // A or B
+	Hole   int    `json:"hole"`
+	Result string `json:"result"`
+	Actor  string `json:"actor"`
+	At     string `json:"at"` // RFC 3339, UTC
+}
+
+// CardSignature is the sign-off of one side on the final card of a match,
+// with the hole results as they were signed. Signer is the player who signed,
+// or the side's captain when there is no PlayerID.
+type CardSignature struct {
+	ID       int      `json:"id"`
+	MatchID  int      `json:"match_id"`
+	Side     string   `json:"side"`
+	PlayerID int      `json:"player_id,omitempty"`
+	Signer   string   `json:"signer"`
+	Actor    string   `json:"actor"`
+	Holes    []string `json:"holes"`     // the 18 hole results, "" for holes without one
+	Result   string   `json:"result"`    // 3&2, 2 Up or Halved
+	SignedAt string   `json:"signed_at"` // RFC 3339, UTC
+}
+
+// MatchCard is the scorecard of a match: the hole results that count, the
+// ones the scorer of each side entered, the holes the sides disagree on and
+// the latest signature of each side. Signatures are cleared when a result
+// changes, so they are always of the card as it is.
+type MatchCard struct {
+	MatchID    int                 `json:"match_id"`
+	Holes      []string            `json:"holes"`
+	Sides      map[string][]string `json:"sides"` // A and B, 18 results each
+	Disputed   []int               `json:"disputed"`
+	Result     string              `json:"result"` // "" while the match is live
+	Signatures []CardSignature     `json:"signatures"`
+	Attested   bool                `json:"attested"` // both sides signed the card
+	Version    int                 `json:"version"`
+}
+
+// otherSide returns the opponents of a side.
+func otherSide(side string) string {
+	if side == "A" {
+		return "B"
+	}
+	return "A"
+}
+
+// loadMatchCard returns the card of a match.
+func loadMatchCard(st Store, matchID int) (*MatchCard, error) {
+	m, err := st.GetMatch(matchID)
+	if err != nil {
+		return nil, err
+	}
+	holes, err := loadHoleResults(st, matchID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := st.SideResults(matchID)
+	if err != nil {
+		return nil, err
+	}
+	card := &MatchCard{
+		MatchID:    matchID,
+		Holes:      holes,
+		Sides:      map[string][]string{"A": make([]string, 18), "B": make([]string, 18)},
+		Disputed:   []int{},
+		Result:     ComputeMatchState(m.Holes, holes).Result,
+		Signatures: []CardSignature{},
+	}
+	for _, e := range entries {
+		if results, ok := card.Sides[e.Side]; ok && e.Hole >= 1 && e.Hole <= 18 {
+			results[e.Hole-1] = e.Result
+		}
+	}
+	start, end := holeRange(m.Holes)
+	for i := start; i < end; i++ {
+		if a, b := card.Sides["A"][i], card.Sides["B"][i]; a != "" && b != "" && a != b {
+			card.Disputed = append(card.Disputed, i+1)
+		}
+	}
+	sigs, err := st.CardSignatures(matchID)
+	if err != nil {
+		return nil, err
+	}
+	latest := map[string]CardSignature{}
+	for _, sig := range sigs {
+		latest[sig.Side] = sig
+	}
+	for _, side := range []string{"A", "B"} {
+		if sig, ok := latest[side]; ok {
+			card.Signatures = append(card.Signatures, sig)
+		}
+	}
+	card.Attested = len(card.Signatures) == 2
+	events, err := st.MatchEvents(matchID)
+	if err != nil {
+		return nil, err
+	}
+	card.Version = matchVersion(events)
+	return card, nil
+}
+
+// checkCardAttested returns a conflict unless both sides signed the card of a
+// match.
+func checkCardAttested(st Store, matchID int) error {
+	card, err := loadMatchCard(st, matchID)
+	if err != nil {
+		return err
+	}
+	if !card.Attested {
+		return conflict{errors.New("both sides have to sign the card first")}
+	}
+	return nil
+}
+
+// checkResultsOpen returns a conflict when the results of a match can only
+// change through the cards of its sides: once the match is completed or a
+// side signed its card.
+func checkResultsOpen(st Store, matchID int) error {
+	m, err := st.GetMatch(matchID)
+	if err != nil {
+		return err
+	}
+	if m.Status == "completed" {
+		return conflict{errors.New("the match is completed, reopen it to change its results")}
+	}
+	sigs, err := st.CardSignatures(matchID)
+	if err != nil {
+		return err
+	}
+	if len(sigs) > 0 {
+		return conflict{errors.New("the card is signed, the sides have to change their results")}
+	}
+	return nil
+}
+
+// unconfirmedHoles returns the holes of a card in play whose result isn't
+// the one both sides entered.
+func unconfirmedHoles(card *MatchCard, holesPlayed string) []int {
+	var holes []int
+	start, end := holeRange(holesPlayed)
+	for i := start; i < end; i++ {
+		if card.Sides["A"][i] != card.Holes[i] || card.Sides["B"][i] != card.Holes[i] {
+			holes = append(holes, i+1)
+		}
+	}
+	return holes
+}
+
+// holeList joins hole numbers for messages.
+func holeList(holes []int) string {
+	s := make([]string, len(holes))
+	for i, h := range holes {
+		s[i] = strconv.Itoa(h)
+	}
+	return strings.Join(s, ", ")
+}
+
+// confirmHole sets the result of a hole from what the sides entered, a and
+// b: the result both entered is confirmed. A hole the sides disagree on loses
+// its result, and so does a confirmed one that only one side has now.
+func confirmHole(tx Store, matchID, hole int, a, b string) error {
+	current := HoleResult{Hole: hole}
+	results, err := tx.HoleResults(matchID)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		if r.Hole == hole {
+			current = r
+		}
+	}
+	switch {
+	case a != "" && a == b:
+		if current.Result != a || current.Source != SourceConfirmed {
+			return tx.SetHoleResult(matchID, HoleResult{Hole: hole, Result: a, Source: SourceConfirmed})
+		}
+	case a != "" && b != "", current.Source == SourceConfirmed:
+		if current.Result != "" {
+			return tx.SetHoleResult(matchID, HoleResult{Hole: hole})
+		}
+	}
+	return nil
+}
+
+// signer returns the name a signature of a side is made in: the player's
+// when one is given, who has to play for the side, else the name of the
+// side's captain making the request.
+func signer(tx Store, r *http.Request, matchID int, side string, playerID int) (string, error) {
+	if playerID == 0 {
+		u := currentUser(r)
+		captain, err := isSideCaptain(tx, u, matchID, side)
+		if err != nil {
+			return "", err
+		}
+		if !captain {
+			return "", badRequest{errors.New("player_id is required")}
+		}
+		return u.Username, nil
+	}
+	entrants, err := tx.MatchPlayers(matchID)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entrants {
+		if e.ID == playerID && e.Side == side {
+			return e.Name, nil
+		}
+	}
+	return "", badRequest{fmt.Errorf("player %d doesn't play for side %s", playerID, side)}
+}
+
+// writeCard writes the card of a match as JSON.
+func writeCard(w http.ResponseWriter, card *MatchCard) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(card)
+}
+
+// --- Card Handlers ---
+
+// GetMatchCard returns the card of a match.
+func (srv *Server) GetMatchCard(w http.ResponseWriter, r *http.Request) {
+	matchID, err := strconv.Atoi(r.URL.Query().Get("match_id"))
+	if err != nil {
+		http.Error(w, "Invalid match id", http.StatusBadRequest)
+		return
+	}
+	card, err := loadMatchCard(srv.store, matchID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeCard(w, card)
+}
+
+// SubmitSideResults saves the hole results the scorer of one side entered.
+// A hole counts once both sides entered the same result; where they differ
+// it is disputed and counts for nobody until one of them changes theirs.
+func (srv *Server) SubmitSideResults(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		MatchID int      `json:"match_id"`
+		Side    string   `json:"side"`  // A or B
+		Holes   []string `json:"holes"` // 18 values: "A", "B", "AS", or ""
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Side != "A" && body.Side != "B" {
+		http.Error(w, "Invalid side", http.StatusBadRequest)
+		return
+	}
+	for _, v := range body.Holes {
+		if v != "" && v != "A" && v != "B" && v != "AS" {
+			http.Error(w, "Invalid result "+v, http.StatusBadRequest)
+			return
+		}
+	}
+	defer srv.lockMatch(r, body.MatchID)()
+	if err := srv.checkMatchWritable(body.MatchID); err != nil {
+		writeError(w, err)
+		return
+	}
+	var card *MatchCard
+	err := srv.store.Tx(func(tx Store) error {
+		m, err := tx.GetMatch(body.MatchID)
+		if err != nil {
+			return err
+		}
+		if m.Status == "completed" {
+			return conflict{errors.New("the match is completed, reopen it to change its card")}
+		}
+		if card, err = loadMatchCard(tx, body.MatchID); err != nil {
+			return err
+		}
+		actor, at := auditActor(r), time.Now().UTC().Format(time.RFC3339)
+		err = audited(tx, r, body.MatchID, func() error {
+			for i := 0; i < 18; i++ {
+				v := ""
+				if i < len(body.Holes) {
+					v = body.Holes[i]
+				}
+				if v == card.Sides[body.Side][i] {
+					continue
+				}
+				if err := tx.SetSideResult(body.MatchID, SideResult{Side: body.Side, Hole: i + 1, Result: v, Actor: actor, At: at}); err != nil {
+					return err
+				}
+				a, b := v, card.Sides[otherSide(body.Side)][i]
+				if body.Side == "B" {
+					a, b = b, a
+				}
+				if err := confirmHole(tx, body.MatchID, i+1, a, b); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		card, err = loadMatchCard(tx, body.MatchID)
+		return err
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	noteCardChange(r, body.MatchID)
+	writeCard(w, card)
+}
+
+// SignCard signs the final card of a match for one side, by one of its
+// players or its captain. The match has to be decided and every hole played
+// must have the result both sides entered. The second signature completes
+// the match, see CloseMatchIfDecided.
+func (srv *Server) SignCard(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		MatchID  int    `json:"match_id"`
+		Side     string `json:"side"`      // A or B
+		PlayerID int    `json:"player_id"` // the player who signs, captains sign without one
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Side != "A" && body.Side != "B" {
+		http.Error(w, "Invalid side", http.StatusBadRequest)
+		return
+	}
+	defer srv.lockMatch(r, body.MatchID)()
+	if err := srv.checkMatchWritable(body.MatchID); err != nil {
+		writeError(w, err)
+		return
+	}
+	var card *MatchCard
+	err := srv.store.Tx(func(tx Store) error {
+		m, err := tx.GetMatch(body.MatchID)
+		if err != nil {
+			return err
+		}
+		if m.Status == "completed" {
+			return conflict{errors.New("the match is completed already")}
+		}
+		if card, err = loadMatchCard(tx, body.MatchID); err != nil {
+			return err
+		}
+		if len(card.Disputed) > 0 {
+			return conflict{fmt.Errorf("the sides disagree on hole %s", holeList(card.Disputed))}
+		}
+		if card.Result == "" {
+			return conflict{errors.New("the match is not decided yet")}
+		}
+		if holes := unconfirmedHoles(card, m.Holes); len(holes) > 0 {
+			return conflict{fmt.Errorf("both sides have to enter hole %s", holeList(holes))}
+		}
+		sig := CardSignature{MatchID: body.MatchID, Side: body.Side, PlayerID: body.PlayerID, Actor: auditActor(r),
+			Holes: card.Holes, Result: card.Result, SignedAt: time.Now().UTC().Format(time.RFC3339)}
+		if sig.Signer, err = signer(tx, r, body.MatchID, body.Side, body.PlayerID); err != nil {
+			return err
+		}
+		err = audited(tx, r, body.MatchID, func() error {
+			if err := tx.AddCardSignature(&sig); err != nil {
+				return err
+			}
+			return CloseMatchIfDecided(tx, body.MatchID)
+		})
+		if err != nil {
+			return err
+		}
+		card, err = loadMatchCard(tx, body.MatchID)
+		return err
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	noteCardChange(r, body.MatchID)
+	writeCard(w, card)
+}
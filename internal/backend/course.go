@@ -0,0 +1,280 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+type CourseHole struct {
+	Hole        int `json:"hole"`
+	Par         int `json:"par"`
+	StrokeIndex int `json:"stroke_index"`
+}
+
+type Tee struct {
+	ID           int      `json:"id"`
+	CourseID     int      `json:"course_id"`
+	Name         string   `json:"name"`
+	Color        string   `json:"color"`
+	CourseRating *float64 `json:"course_rating,omitempty"`
+	Slope        *int     `json:"slope,omitempty"`
+	Yards        []int    `json:"yards,omitempty"` // index 0 is hole 1
+}
+
+type Course struct {
+	ID    int          `json:"id"`
+	Name  string       `json:"name"`
+	Holes []CourseHole `json:"holes"`
+	Tees  []Tee        `json:"tees"`
+}
+
+// Par returns the total par of the course.
+func (c *Course) Par() int {
+	par := 0
+	for _, h := range c.Holes {
+		par += h.Par
+	}
+	return par
+}
+
+// validateCourseHoles checks that a course has 18 holes with sane pars and
+// that the stroke indexes are a permutation of 1..18.
+func validateCourseHoles(holes []CourseHole) error {
+	if len(holes) != 18 {
+		return fmt.Errorf("course must have 18 holes, got %d", len(holes))
+	}
+	seen := map[int]bool{}
+	for i, h := range holes {
+		if h.Hole == 0 {
+			holes[i].Hole = i + 1
+		} else if h.Hole != i+1 {
+			return fmt.Errorf("holes must be ordered 1-18, got %d at position %d", h.Hole, i+1)
+		}
+		if h.Par < 3 || h.Par > 6 {
+			return fmt.Errorf("hole %d: invalid par %d", i+1, h.Par)
+		}
+		if h.StrokeIndex < 1 || h.StrokeIndex > 18 || seen[h.StrokeIndex] {
+			return fmt.Errorf("hole %d: invalid or duplicate stroke index %d", i+1, h.StrokeIndex)
+		}
+		seen[h.StrokeIndex] = true
+	}
+	return nil
+}
+
+// validateTeeYards checks that a tee has no yardages or one per hole.
+func validateTeeYards(yards []int) error {
+	if len(yards) != 0 && len(yards) != 18 {
+		return fmt.Errorf("tee must have 18 yardages, got %d", len(yards))
+	}
+	for i, y := range yards {
+		if y <= 0 {
+			return fmt.Errorf("hole %d: invalid yardage %d", i+1, y)
+		}
+	}
+	return nil
+}
+
+// rescorePlayedOn runs fn, which changes a course or tee, and recomputes the
+// results of the matches played on it. Finished results don't change: while
+// a match of an archived event, or a completed or signed one, is played on
+// it, the change is rejected like a removal.
+func rescorePlayedOn(tx Store, r *http.Request, what string, playedOn func(Match) bool, fn func() error) error {
+	events, err := tx.ListEvents()
+	if err != nil {
+		return err
+	}
+	var rescore []int
+	for _, e := range events {
+		matches, err := tx.ListMatches(e.ID)
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			if !playedOn(m) {
+				continue
+			}
+			if e.Archived {
+				return conflict{fmt.Errorf("%s is used by a match of an archived event", what)}
+			}
+			if err := checkResultsOpen(tx, m.ID); err != nil {
+				return conflict{fmt.Errorf("%s is used by a completed or signed match", what)}
+			}
+			rescore = append(rescore, m.ID)
+		}
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	for _, id := range rescore {
+		err := audited(tx, r, id, func() error { return RecomputeHoleResults(tx, id) })
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --- Course Handlers ---
+func (srv *Server) ListCourses(w http.ResponseWriter, r *http.Request) {
+	courses, err := srv.store.ListCourses()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"courses": courses}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (srv *Server) AddCourse(w http.ResponseWriter, r *http.Request) {
+	var c Course
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateCourseHoles(c.Holes); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := srv.store.AddCourse(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (srv *Server) EditCourse(w http.ResponseWriter, r *http.Request) {
+	var c Course
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateCourseHoles(c.Holes); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err := srv.store.Tx(func(tx Store) error {
+		playedOn := func(m Match) bool { return m.CourseID != nil && *m.CourseID == c.ID }
+		return rescorePlayedOn(tx, r, "Course", playedOn, func() error {
+			return tx.UpdateCourse(c)
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (srv *Server) RemoveCourse(w http.ResponseWriter, r *http.Request) {
+	idStr := r.URL.Query().Get("id")
+	id, _ := strconv.Atoi(idStr)
+	err := srv.store.Tx(func(tx Store) error {
+		used, err := tx.CourseInUse(id)
+		if err != nil {
+			return err
+		}
+		if used {
+			return conflict{errors.New("Course is used by a match")}
+		}
+		return tx.RemoveCourse(id)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Tee Handlers ---
+func (srv *Server) AddTee(w http.ResponseWriter, r *http.Request) {
+	var t Tee
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateTeeYards(t.Yards); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := srv.store.GetCourse(t.CourseID); errors.Is(err, ErrNotFound) {
+		http.Error(w, "Unknown course", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := srv.store.AddTee(&t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// EditTee saves a tee and returns it as stored. Tees stay on their course.
+func (srv *Server) EditTee(w http.ResponseWriter, r *http.Request) {
+	var t Tee
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateTeeYards(t.Yards); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	stored, err := srv.store.GetTee(t.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if t.CourseID != 0 && t.CourseID != stored.CourseID {
+		http.Error(w, "A tee can't move to another course", http.StatusBadRequest)
+		return
+	}
+	err = srv.store.Tx(func(tx Store) error {
+		playedOn := func(m Match) bool { return m.TeeID != nil && *m.TeeID == t.ID }
+		return rescorePlayedOn(tx, r, "Tee", playedOn, func() error {
+			return tx.UpdateTee(t)
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if stored, err = srv.store.GetTee(t.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(stored); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (srv *Server) RemoveTee(w http.ResponseWriter, r *http.Request) {
+	idStr := r.URL.Query().Get("id")
+	id, _ := strconv.Atoi(idStr)
+	err := srv.store.Tx(func(tx Store) error {
+		used, err := tx.TeeInUse(id)
+		if err != nil {
+			return err
+		}
+		if used {
+			return conflict{errors.New("Tee is used by a match")}
+		}
+		return tx.RemoveTee(id)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,184 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Event is one Ryder Cup: it owns its teams and matches. Archived events are
+// kept read-only for history.
+type Event struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	StartDate string `json:"start_date"`
+	Archived  bool   `json:"archived"`
+}
+
+// ErrEventArchived is returned for writes to an archived event.
+var ErrEventArchived = errors.New("event is archived and read-only")
+
+// eventFromRequest returns the event selected by the event_id query parameter,
+// defaulting to the current event.
+func (srv *Server) eventFromRequest(r *http.Request) (int, error) {
+	if s := r.URL.Query().Get("event_id"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := srv.store.GetEvent(id); err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
+	return srv.store.CurrentEventID()
+}
+
+// checkEventWritable returns ErrEventArchived for archived events.
+func (srv *Server) checkEventWritable(eventID int) error {
+	e, err := srv.store.GetEvent(eventID)
+	if err != nil {
+		return err
+	}
+	if e.Archived {
+		return ErrEventArchived
+	}
+	return nil
+}
+
+// checkTeamWritable returns ErrEventArchived when the team belongs to an archived event.
+func (srv *Server) checkTeamWritable(teamID int) error {
+	t, err := srv.store.GetTeam(teamID)
+	if err != nil {
+		return err
+	}
+	return srv.checkEventWritable(t.EventID)
+}
+
+// checkMatchWritable returns ErrEventArchived when the match belongs to an
+// archived event and ErrSessionLocked when its session is locked.
+func (srv *Server) checkMatchWritable(matchID int) error {
+	m, err := srv.store.GetMatch(matchID)
+	if err != nil {
+		return err
+	}
+	if m.SessionID != nil {
+		return srv.checkSessionWritable(*m.SessionID)
+	}
+	return srv.checkEventWritable(m.EventID)
+}
+
+// badRequest marks a validation error, written as 400 by writeError.
+type badRequest struct{ error }
+
+// conflict marks a change the current data doesn't allow, written as 409.
+type conflict struct{ error }
+
+// writeError maps validation, conflict, login, role, token, lookup and archive
+// errors to HTTP status codes.
+func writeError(w http.ResponseWriter, err error) {
+	var invalid badRequest
+	var clash conflict
+	switch {
+	case errors.As(err, &invalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.As(err, &clash):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrScorerToken), errors.Is(err, ErrLoginRequired):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrEventArchived):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrSessionLocked):
+		http.Error(w, err.Error(), http.StatusLocked)
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// --- Event Handlers ---
+func (srv *Server) ListEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := srv.store.ListEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	current, _ := srv.store.CurrentEventID()
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"events": events, "current_id": current}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (srv *Server) AddEvent(w http.ResponseWriter, r *http.Request) {
+	var e Event
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := srv.store.AddEvent(&e); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (srv *Server) EditEvent(w http.ResponseWriter, r *http.Request) {
+	var e Event
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := srv.checkEventWritable(e.ID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := srv.store.UpdateEvent(e); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ArchiveEvent makes an event read-only. Reopening it is a separate action,
+// see UnarchiveEvent.
+func (srv *Server) ArchiveEvent(w http.ResponseWriter, r *http.Request) {
+	srv.setEventArchived(w, r, true)
+}
+
+// UnarchiveEvent makes an archived event writable again.
+func (srv *Server) UnarchiveEvent(w http.ResponseWriter, r *http.Request) {
+	srv.setEventArchived(w, r, false)
+}
+
+func (srv *Server) setEventArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	type req struct {
+		EventID  int   `json:"event_id"`
+		Archived *bool `json:"archived"` // no longer used, archiving is one-way
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Archived != nil && *body.Archived != archived {
+		http.Error(w, "Use /api/event/unarchive to reopen an event", http.StatusBadRequest)
+		return
+	}
+	if _, err := srv.store.GetEvent(body.EventID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := srv.store.SetEventArchived(body.EventID, archived); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,52 @@
+package backend
+
+import "fmt"
+
+// formatRules describes how a match format is played.
+type formatRules struct {
+	Name       string // display name
+	Min, Max   int    // players per side
+	TeamBall   bool   // one ball per side, recorded against any of its players
+	Stableford bool   // holes are decided by Stableford points, needs par
+}
+
+var formats = map[MatchFormat]formatRules{
+	Singles:         {Name: "Singles", Min: 1, Max: 1},
+	Foursome:        {Name: "Foursome", Min: 2, Max: 2, TeamBall: true},
+	TexasScramble:   {Name: "Texas Scramble", Min: 2, Max: 4, TeamBall: true},
+	FourBall:        {Name: "Four-ball", Min: 2, Max: 2},
+	Greensomes:      {Name: "Greensomes", Min: 2, Max: 2, TeamBall: true},
+	Chapman:         {Name: "Chapman", Min: 2, Max: 2, TeamBall: true},
+	StablefordMatch: {Name: "Stableford Match", Min: 1, Max: 2, Stableford: true},
+}
+
+// FormatName returns the display name of a format.
+func FormatName(format MatchFormat) string {
+	if rules, ok := formats[format]; ok {
+		return rules.Name
+	}
+	return string(format)
+}
+
+// ValidateMatchFormat checks that a format is known, that both sides field
+// the number of players the format requires and that Stableford formats have
+// a course to take par from.
+func ValidateMatchFormat(format MatchFormat, playersA, playersB int, hasCourse bool) error {
+	rules, ok := formats[format]
+	if !ok {
+		return fmt.Errorf("unknown match format %q", format)
+	}
+	for i, n := range []int{playersA, playersB} {
+		side := []string{"A", "B"}[i]
+		if n < rules.Min || n > rules.Max {
+			if rules.Min == rules.Max {
+				return fmt.Errorf("%s requires %d player(s) per side, side %s has %d", format, rules.Min, side, n)
+			}
+			return fmt.Errorf("%s requires %d-%d players per side, side %s has %d", format, rules.Min, rules.Max, side, n)
+		}
+	}
+	if rules.Stableford && !hasCourse {
+		return fmt.Errorf("%s requires a course", format)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package backend
+
+import (
+	"math"
+	"sort"
+)
+
+// MatchEntrant is a player of a match with the side they play for.
+type MatchEntrant struct {
+	ID   int
+	Name string
+	Side string // "A" or "B"
+	HCP  float64
+}
+
+// scrambleWeights are the team handicap weights for a scramble side, lowest
+// course handicap first.
+var scrambleWeights = map[int][]float64{
+	1: {1.0},
+	2: {0.35, 0.15},
+	3: {0.30, 0.20, 0.10},
+	4: {0.25, 0.20, 0.15, 0.10},
+}
+
+// pairWeights are the team handicap weights for greensomes and Chapman pairs.
+var pairWeights = []float64{0.6, 0.4}
+
+// weightedHandicap sorts course handicaps from the lowest up and sums them
+// with the given weights.
+func weightedHandicap(chs []float64, weights []float64) float64 {
+	sort.Float64s(chs)
+	var hcp float64
+	for i, ch := range chs {
+		if i < len(weights) {
+			hcp += ch * weights[i]
+		}
+	}
+	return hcp
+}
+
+// CourseHandicap converts a handicap index to a course handicap for a tee.
+// Without a rating and slope the index is used as is.
+func CourseHandicap(index float64, tee *Tee, par int) float64 {
+	if tee == nil || tee.Slope == nil || tee.CourseRating == nil {
+		return index
+	}
+	return index*float64(*tee.Slope)/113 + (*tee.CourseRating - float64(par))
+}
+
+// teamHandicaps applies the match play allowance of a format and returns the
+// handicap of every competitor, keyed by player id. In team ball formats each
+// player of a side carries the handicap of the side.
+func teamHandicaps(format MatchFormat, entrants []MatchEntrant, course map[int]float64) map[int]float64 {
+	handicaps := map[int]float64{}
+	bySide := map[string][]MatchEntrant{}
+	for _, e := range entrants {
+		bySide[e.Side] = append(bySide[e.Side], e)
+	}
+	for _, side := range bySide {
+		var hcp float64
+		chs := make([]float64, 0, len(side))
+		for _, e := range side {
+			chs = append(chs, course[e.ID])
+		}
+		switch format {
+		case Foursome:
+			// 50% of the combined course handicaps
+			for _, ch := range chs {
+				hcp += ch
+			}
+			hcp *= 0.5
+		case TexasScramble:
+			// Weighted from the lowest course handicap up
+			hcp = weightedHandicap(chs, scrambleWeights[len(chs)])
+		case Greensomes, Chapman:
+			// 60% of the lower and 40% of the higher course handicap
+			hcp = weightedHandicap(chs, pairWeights)
+		case FourBall:
+			// 90% of the course handicap per player
+			for _, e := range side {
+				handicaps[e.ID] = course[e.ID] * 0.9
+			}
+			continue
+		case StablefordMatch:
+			// Full handicap in singles, 90% when played as better ball
+			allowance := 1.0
+			if len(side) > 1 {
+				allowance = 0.9
+			}
+			for _, e := range side {
+				handicaps[e.ID] = course[e.ID] * allowance
+			}
+			continue
+		default:
+			// Singles: full course handicap per player
+			for _, e := range side {
+				handicaps[e.ID] = course[e.ID]
+			}
+			continue
+		}
+		for _, e := range side {
+			handicaps[e.ID] = hcp
+		}
+	}
+	return handicaps
+}
+
+// AllocateStrokes spreads strokes over the holes in play by stroke index.
+// The result is indexed by hole (index 0 is hole 1).
+func AllocateStrokes(strokes int, course *Course, holes string) []int {
+	alloc := make([]int, 18)
+	if strokes <= 0 || course == nil || len(course.Holes) != 18 {
+		return alloc
+	}
+	start, end := holeRange(holes)
+	inPlay := make([]CourseHole, 0, end-start)
+	for _, h := range course.Holes[start:end] {
+		inPlay = append(inPlay, h)
+	}
+	sort.Slice(inPlay, func(i, j int) bool { return inPlay[i].StrokeIndex < inPlay[j].StrokeIndex })
+	for i, h := range inPlay {
+		alloc[h.Hole-1] = strokes / len(inPlay)
+		if i < strokes%len(inPlay) {
+			alloc[h.Hole-1]++
+		}
+	}
+	return alloc
+}
+
+// MatchStrokes returns the strokes received on each hole by every player of a
+// match. Allowances are taken off the lowest competitor and halved for nine
+// hole matches. Without course data nobody receives strokes.
+func MatchStrokes(format MatchFormat, holes string, course *Course, tee *Tee, entrants []MatchEntrant) map[int][]int {
+	strokes := map[int][]int{}
+	if course == nil || len(course.Holes) != 18 || len(entrants) == 0 {
+		return strokes
+	}
+	chs := map[int]float64{}
+	for _, e := range entrants {
+		chs[e.ID] = CourseHandicap(e.HCP, tee, course.Par())
+	}
+	handicaps := teamHandicaps(format, entrants, chs)
+	low := math.Inf(1)
+	for _, h := range handicaps {
+		low = math.Min(low, h)
+	}
+	for _, e := range entrants {
+		diff := handicaps[e.ID] - low
+		if holes == "front9" || holes == "back9" {
+			diff /= 2
+		}
+		strokes[e.ID] = AllocateStrokes(int(math.Round(diff)), course, holes)
+	}
+	return strokes
+}
+
+// loadMatchCourse returns the course and tee a match is played on, either may be nil.
+func loadMatchCourse(st Store, m Match) (*Course, *Tee, error) {
+	if m.CourseID == nil {
+		return nil, nil, nil
+	}
+	course, err := st.GetCourse(*m.CourseID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tee *Tee
+	if m.TeeID != nil {
+		if tee, err = st.GetTee(*m.TeeID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return course, tee, nil
+}
+
+// LoadMatchStrokes computes the strokes received per hole for every player of a match.
+func LoadMatchStrokes(st Store, m Match) (map[int][]int, error) {
+	course, tee, err := loadMatchCourse(st, m)
+	if err != nil {
+		return nil, err
+	}
+	if course == nil {
+		return map[int][]int{}, nil
+	}
+	entrants, err := st.MatchPlayers(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	return MatchStrokes(m.Format, m.Holes, course, tee, entrants), nil
+}
@@ -0,0 +1,150 @@
+package backend
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// testCourse returns 18 holes of par 4 with the given stroke indexes, in hole
+// order.
+func testCourse(strokeIndex func(hole int) int) *Course {
+	holes := make([]CourseHole, 18)
+	for i := range holes {
+		holes[i] = CourseHole{Hole: i + 1, Par: 4, StrokeIndex: strokeIndex(i + 1)}
+	}
+	return &Course{Name: "Links", Holes: holes}
+}
+
+func TestTeamHandicaps(t *testing.T) {
+	tests := []struct {
+		format MatchFormat
+		a, b   []float64 // course handicaps of the sides, players 1.. then 11..
+		want   []float64 // handicaps of players 1.. then 11..
+	}{
+		{Singles, []float64{10}, []float64{20}, []float64{10, 20}},
+		{Foursome, []float64{10, 20}, []float64{4, 6}, []float64{15, 15, 5, 5}},
+		{TexasScramble, []float64{20, 10}, []float64{8, 16, 4, 12}, []float64{6.5, 6.5, 6, 6, 6, 6}},
+		{Greensomes, []float64{20, 10}, []float64{5, 5}, []float64{14, 14, 5, 5}},
+		{Chapman, []float64{10, 20}, []float64{0, 10}, []float64{14, 14, 4, 4}},
+		{FourBall, []float64{10, 20}, []float64{0, 5}, []float64{9, 18, 0, 4.5}},
+		{StablefordMatch, []float64{10}, []float64{20}, []float64{10, 20}},
+		{StablefordMatch, []float64{10, 20}, []float64{0, 5}, []float64{9, 18, 0, 4.5}},
+	}
+	for _, tt := range tests {
+		var entrants []MatchEntrant
+		course := map[int]float64{}
+		var ids []int
+		for i, ch := range tt.a {
+			entrants = append(entrants, MatchEntrant{ID: 1 + i, Side: "A"})
+			course[1+i] = ch
+			ids = append(ids, 1+i)
+		}
+		for i, ch := range tt.b {
+			entrants = append(entrants, MatchEntrant{ID: 11 + i, Side: "B"})
+			course[11+i] = ch
+			ids = append(ids, 11+i)
+		}
+		handicaps := teamHandicaps(tt.format, entrants, course)
+		var got []float64
+		for _, id := range ids {
+			got = append(got, math.Round(handicaps[id]*100)/100)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s %v against %v: got %v, want %v", tt.format, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestAllocateStrokes(t *testing.T) {
+	inOrder := testCourse(func(hole int) int { return hole })
+	// The odd stroke indexes on the back nine, the even ones on the front
+	split := testCourse(func(hole int) int {
+		if hole <= 9 {
+			return 2 * hole
+		}
+		return 2*(hole-9) - 1
+	})
+	tests := []struct {
+		name    string
+		strokes int
+		course  *Course
+		holes   string
+		want    map[int]int // hole -> strokes, the others get none
+	}{
+		{"none", 0, inOrder, "18", map[int]int{}},
+		{"by stroke index", 3, inOrder, "18", map[int]int{1: 1, 2: 1, 3: 1}},
+		{"a second round", 20, inOrder, "18", nil},
+		{"front nine by its own order", 2, split, "front9", map[int]int{1: 1, 2: 1}},
+		{"back nine by its own order", 2, split, "back9", map[int]int{10: 1, 11: 1}},
+		{"no course", 5, nil, "18", map[int]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := make([]int, 18)
+			if tt.want == nil {
+				// Everyone gets one, stroke index 1 and 2 a second
+				for i := range want {
+					want[i] = 1
+				}
+				want[0], want[1] = 2, 2
+			}
+			for hole, n := range tt.want {
+				want[hole-1] = n
+			}
+			if got := AllocateStrokes(tt.strokes, tt.course, tt.holes); !slices.Equal(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMatchStrokes(t *testing.T) {
+	course := testCourse(func(hole int) int { return hole })
+	sum := func(strokes []int) int {
+		n := 0
+		for _, s := range strokes {
+			n += s
+		}
+		return n
+	}
+	tests := []struct {
+		name  string
+		holes string
+		tee   *Tee
+		hcpA  float64
+		hcpB  float64
+		wantA int
+		wantB int
+	}{
+		{"difference off the lowest", "18", nil, 10, 13.4, 0, 3},
+		{"rounded half up", "18", nil, 10, 12.5, 0, 3},
+		{"rounded down", "18", nil, 12.4, 10, 2, 0},
+		{"halved for the front nine", "front9", nil, 10, 16, 0, 3},
+		{"halved and rounded for the back nine", "back9", nil, 10, 15, 0, 3},
+		{"course handicap of the tee", "18", &Tee{Slope: ptr(130), CourseRating: ptr(74.0)}, 0, 10, 0, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entrants := []MatchEntrant{{ID: 1, Side: "A", HCP: tt.hcpA}, {ID: 2, Side: "B", HCP: tt.hcpB}}
+			strokes := MatchStrokes(Singles, tt.holes, course, tt.tee, entrants)
+			if got := sum(strokes[1]); got != tt.wantA {
+				t.Errorf("A: got %d strokes, want %d", got, tt.wantA)
+			}
+			if got := sum(strokes[2]); got != tt.wantB {
+				t.Errorf("B: got %d strokes, want %d", got, tt.wantB)
+			}
+			start, end := holeRange(tt.holes)
+			for i, s := range strokes[2] {
+				if s > 0 && (i < start || i >= end) {
+					t.Errorf("a stroke on hole %d, which isn't played", i+1)
+				}
+			}
+		})
+	}
+	if strokes := MatchStrokes(Singles, "18", nil, nil, []MatchEntrant{{ID: 1, Side: "A", HCP: 10}}); len(strokes) != 0 {
+		t.Errorf("without a course: got %v, want no strokes", strokes)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
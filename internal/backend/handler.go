@@ -1,11 +1,13 @@
 package backend
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 )
 
 type Player struct {
@@ -19,6 +21,7 @@ type Player struct {
 
 type Team struct {
 	ID      int      `json:"id"`
+	EventID int      `json:"event_id"`
 	Name    string   `json:"name"`
 	Color   string   `json:"color"`
 	Players []Player `json:"players,omitempty"`
@@ -27,20 +30,30 @@ type Team struct {
 type MatchFormat string
 
 const (
-	Singles       MatchFormat = "singles"
-	TexasScramble MatchFormat = "texas_scramble"
-	Foursome      MatchFormat = "foursome"
+	Singles         MatchFormat = "singles"
+	TexasScramble   MatchFormat = "texas_scramble"
+	Foursome        MatchFormat = "foursome"
+	FourBall        MatchFormat = "four_ball"
+	Greensomes      MatchFormat = "greensomes"
+	Chapman         MatchFormat = "chapman"    // also known as Pinehurst
+	StablefordMatch MatchFormat = "stableford" // holes decided by Stableford points
 )
 
+// Match is a match as stored, the roster is kept separately.
 type Match struct {
 	ID        int         `json:"id"`
-	TeamA     *Team       `json:"team_a"`
-	TeamB     *Team       `json:"team_b"`
+	EventID   int         `json:"event_id"`
+	SessionID *int        `json:"session_id,omitempty"`
+	TeamA     int         `json:"team_a"`
+	TeamB     int         `json:"team_b"`
 	Format    MatchFormat `json:"format"`
 	Status    string      `json:"status"` // prepared, running, completed
+	Holes     string      `json:"holes"`  // 18, front9, back9
 	StartTime string      `json:"start_time"`
-	PlayersA  []Player    `json:"players_a"`
-	PlayersB  []Player    `json:"players_b"`
+	CourseID  *int        `json:"course_id,omitempty"`
+	TeeID     *int        `json:"tee_id,omitempty"`
+	Points    *float64    `json:"points,omitempty"`   // nil falls back to the session's points, then 1
+	Conceded  string      `json:"conceded,omitempty"` // the side that conceded, A or B
 }
 
 type Score struct {
@@ -51,11 +64,34 @@ type Score struct {
 	Strokes  int `json:"strokes"`
 }
 
-// DB is a global variable for the SQLite connection (to be initialized elsewhere)
-var DB *sql.DB
+// matchPoints returns the points a match is worth.
+func matchPoints(m Match, sessions map[int]Session) float64 {
+	if m.Points != nil {
+		return *m.Points
+	}
+	if m.SessionID != nil {
+		if s, ok := sessions[*m.SessionID]; ok && s.Points != nil {
+			return *s.Points
+		}
+	}
+	return 1
+}
+
+// sessionsByID loads the sessions of an event keyed by id.
+func (srv *Server) sessionsByID(eventID int) (map[int]Session, []Session, error) {
+	list, err := srv.store.ListSessions(eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := map[int]Session{}
+	for _, s := range list {
+		byID[s.ID] = s
+	}
+	return byID, list, nil
+}
 
 // --- Assign Multiple Players to Team ---
-func AssignPlayersToTeam(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) AssignPlayersToTeam(w http.ResponseWriter, r *http.Request) {
 	type req struct {
 		TeamID    int   `json:"team_id"`
 		PlayerIDs []int `json:"player_ids"`
@@ -65,147 +101,151 @@ func AssignPlayersToTeam(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	// Remove all current assignments for this team
-	if _, err := DB.Exec("DELETE FROM team_players WHERE team_id=?", body.TeamID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := srv.checkTeamWritable(body.TeamID); err != nil {
+		writeError(w, err)
 		return
 	}
-	// Add new assignments
-	for _, pid := range body.PlayerIDs {
-		if _, err := DB.Exec("INSERT INTO team_players (team_id, player_id) VALUES (?, ?)", body.TeamID, pid); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	// Replaces all current assignments for this team
+	if err := srv.store.SetTeamPlayers(body.TeamID, body.PlayerIDs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // --- List Players by Team ---
-func ListPlayersByTeam(w http.ResponseWriter, r *http.Request) {
-	teamID := r.URL.Query().Get("team_id")
-	rows, err := DB.Query("SELECT p.id, p.name, p.email FROM players p JOIN team_players tp ON p.id=tp.player_id WHERE tp.team_id=? ORDER BY p.name", teamID)
+func (srv *Server) ListPlayersByTeam(w http.ResponseWriter, r *http.Request) {
+	teamID, _ := strconv.Atoi(r.URL.Query().Get("team_id"))
+	players, err := srv.store.TeamPlayers(teamID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer func() { _ = rows.Close() }()
-	var players []Player
-	for rows.Next() {
-		var p Player
-		if err := rows.Scan(&p.ID, &p.Name, &p.Email); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		players = append(players, p)
-	}
 	if err := json.NewEncoder(w).Encode(map[string]interface{}{"players": players}); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
 // --- Match List Handler ---
-func ListMatches(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) ListMatches(w http.ResponseWriter, r *http.Request) {
 	type MatchPlayer struct {
-		ID   int     `json:"id"`
-		Name string  `json:"name"`
-		HCP  float64 `json:"hcp"`
+		ID      int     `json:"id"`
+		Name    string  `json:"name"`
+		HCP     float64 `json:"hcp"`
+		Strokes []int   `json:"strokes,omitempty"` // strokes received per hole
+	}
+	type MatchTeam struct {
+		ID      int           `json:"id"`
+		Name    string        `json:"name"`
+		Color   string        `json:"color"`
+		Players []MatchPlayer `json:"players"`
 	}
 	type Match struct {
-		ID        int    `json:"id"`
-		Format    string `json:"format"`
-		Holes     string `json:"holes"`
-		Status    string `json:"status"`
-		StartTime string `json:"start_time"`
-		TeamA     struct {
-			ID      int           `json:"id"`
-			Name    string        `json:"name"`
-			Color   string        `json:"color"`
-			Players []MatchPlayer `json:"players"`
-		} `json:"team_a"`
-		TeamB struct {
-			ID      int           `json:"id"`
-			Name    string        `json:"name"`
-			Color   string        `json:"color"`
-			Players []MatchPlayer `json:"players"`
-		} `json:"team_b"`
-	}
-	rows, err := DB.Query(`SELECT m.id, m.format, m.holes, m.status, m.start_time, ta.id, ta.name, ta.color, tb.id, tb.name, tb.color FROM matches m JOIN teams ta ON m.team_a_id=ta.id JOIN teams tb ON m.team_b_id=tb.id`)
+		ID        int       `json:"id"`
+		Format    string    `json:"format"`
+		Holes     string    `json:"holes"`
+		Status    string    `json:"status"`
+		Conceded  string    `json:"conceded,omitempty"`
+		Points    float64   `json:"points"`
+		OwnPoints *float64  `json:"own_points"` // null when the match scores its session's points
+		StartTime string    `json:"start_time"`
+		SessionID *int      `json:"session_id,omitempty"`
+		CourseID  *int      `json:"course_id,omitempty"`
+		TeeID     *int      `json:"tee_id,omitempty"`
+		Course    *Course   `json:"course,omitempty"`
+		TeamA     MatchTeam `json:"team_a"`
+		TeamB     MatchTeam `json:"team_b"`
+	}
+	eventID, err := srv.eventFromRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	stored, err := srv.store.ListMatches(eventID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	sessionsByID, sessions, err := srv.sessionsByID(eventID)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
-	defer rows.Close()
+	teams := map[int]Team{}
+	eventTeams, _ := srv.store.ListTeams(eventID)
+	for _, t := range eventTeams {
+		teams[t.ID] = t
+	}
 	matches := []Match{}
-	for rows.Next() {
-		var m Match
-		var taID, tbID int
-		var taName, tbName string
-		var tbColor, taColor string
-		var startTime string
-		if err := rows.Scan(&m.ID, &m.Format, &m.Holes, &m.Status, &startTime, &taID, &taName, &taColor, &tbID, &tbName, &tbColor); err != nil {
-			continue
-		}
-		m.TeamA.ID, m.TeamA.Name, m.TeamA.Color = taID, taName, taColor
-		m.TeamB.ID, m.TeamB.Name, m.TeamB.Color = tbID, tbName, tbColor
-		m.StartTime = startTime
-		// Fetch players for each team in this match
-		paRows, _ := DB.Query(`SELECT p.id, p.name, p.hcp FROM match_players mp JOIN players p ON mp.player_id=p.id WHERE mp.match_id=? AND mp.team_side='A'`, m.ID)
-		for paRows.Next() {
-			var p MatchPlayer
-			paRows.Scan(&p.ID, &p.Name, &p.HCP)
-			m.TeamA.Players = append(m.TeamA.Players, p)
-		}
-		paRows.Close()
-		pbRows, _ := DB.Query(`SELECT p.id, p.name, p.hcp FROM match_players mp JOIN players p ON mp.player_id=p.id WHERE mp.match_id=? AND mp.team_side='B'`, m.ID)
-		for pbRows.Next() {
-			var p MatchPlayer
-			pbRows.Scan(&p.ID, &p.Name, &p.HCP)
-			m.TeamB.Players = append(m.TeamB.Players, p)
-		}
-		pbRows.Close()
+	courses := map[int]*Course{}
+	for _, sm := range stored {
+		m := Match{ID: sm.ID, Format: string(sm.Format), Holes: sm.Holes, Status: sm.Status, Conceded: sm.Conceded, Points: matchPoints(sm, sessionsByID),
+			OwnPoints: sm.Points, StartTime: sm.StartTime, SessionID: sm.SessionID, CourseID: sm.CourseID, TeeID: sm.TeeID}
+		if sm.CourseID != nil {
+			m.Course = courses[*sm.CourseID]
+			if m.Course == nil {
+				if c, err := srv.store.GetCourse(*sm.CourseID); err == nil {
+					courses[*sm.CourseID] = c
+					m.Course = c
+				}
+			}
+		}
+		ta, tb := teams[sm.TeamA], teams[sm.TeamB]
+		m.TeamA = MatchTeam{ID: sm.TeamA, Name: ta.Name, Color: ta.Color}
+		m.TeamB = MatchTeam{ID: sm.TeamB, Name: tb.Name, Color: tb.Color}
+		// Players of each side in this match, with their handicap strokes
+		received, _ := LoadMatchStrokes(srv.store, sm)
+		entrants, _ := srv.store.MatchPlayers(sm.ID)
+		for _, e := range entrants {
+			p := MatchPlayer{ID: e.ID, Name: e.Name, HCP: e.HCP, Strokes: received[e.ID]}
+			if e.Side == "A" {
+				m.TeamA.Players = append(m.TeamA.Players, p)
+			} else {
+				m.TeamB.Players = append(m.TeamB.Players, p)
+			}
+		}
 		matches = append(matches, m)
 	}
+	// Nest matches under their sessions, unscheduled matches go last
+	type SessionMatches struct {
+		Session
+		Matches []Match `json:"matches"`
+	}
+	nested := []SessionMatches{}
+	index := map[int]int{}
+	for _, s := range sessions {
+		index[s.ID] = len(nested)
+		nested = append(nested, SessionMatches{Session: s, Matches: []Match{}})
+	}
+	var unscheduled []Match
+	for _, m := range matches {
+		if m.SessionID != nil {
+			if i, ok := index[*m.SessionID]; ok {
+				nested[i].Matches = append(nested[i].Matches, m)
+				continue
+			}
+		}
+		unscheduled = append(unscheduled, m)
+	}
+	if len(unscheduled) > 0 {
+		nested = append(nested, SessionMatches{Session: Session{EventID: eventID, Name: "Unscheduled", Position: len(nested) + 1}, Matches: unscheduled})
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"matches": matches})
+	json.NewEncoder(w).Encode(map[string]interface{}{"matches": matches, "sessions": nested})
 }
 
 // --- Player List Handler ---
-func ListPlayers(w http.ResponseWriter, r *http.Request) {
-	rows, err := DB.Query(`SELECT p.id, p.name, p.email, p.hcp, tp.team_id, t.name
-		FROM players p
-		LEFT JOIN team_players tp ON p.id = tp.player_id
-		LEFT JOIN teams t ON tp.team_id = t.id
-		ORDER BY p.hcp ASC, p.name ASC`)
+func (srv *Server) ListPlayers(w http.ResponseWriter, r *http.Request) {
+	eventID, err := srv.eventFromRequest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			fmt.Println("error closing rows:", err)
-		}
-	}()
-	var players []Player
-	for rows.Next() {
-		var p Player
-		var hcp sql.NullFloat64
-		var teamID sql.NullInt64
-		var teamName sql.NullString
-		if err := rows.Scan(&p.ID, &p.Name, &p.Email, &hcp, &teamID, &teamName); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		if hcp.Valid {
-			p.HCP = &hcp.Float64
-		}
-		if teamID.Valid {
-			tid := int(teamID.Int64)
-			p.TeamID = &tid
-		}
-		if teamName.Valid {
-			p.TeamName = teamName.String
-		}
-		players = append(players, p)
+	// Players are shared across events, their team is the one in the selected event
+	players, err := srv.store.ListPlayers(eventID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 	if err := json.NewEncoder(w).Encode(map[string]interface{}{"players": players}); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -213,40 +253,35 @@ func ListPlayers(w http.ResponseWriter, r *http.Request) {
 }
 
 // --- Team List Handler ---
-func ListTeams(w http.ResponseWriter, r *http.Request) {
-	rows, err := DB.Query("SELECT id, name, color FROM teams ORDER BY name")
+func (srv *Server) ListTeams(w http.ResponseWriter, r *http.Request) {
+	eventID, err := srv.eventFromRequest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			fmt.Println("error closing rows:", err)
-		}
-	}()
-	var teams []Team
-	for rows.Next() {
-		var t Team
-		if err := rows.Scan(&t.ID, &t.Name, &t.Color); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		teams = append(teams, t)
+	teams, err := srv.store.ListTeams(eventID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+	sort.SliceStable(teams, func(i, j int) bool { return teams[i].Name < teams[j].Name })
 	if err := json.NewEncoder(w).Encode(map[string]interface{}{"teams": teams}); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
 // --- Team Edit/Remove Handlers ---
-func EditTeam(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) EditTeam(w http.ResponseWriter, r *http.Request) {
 	var t Team
 	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	_, err := DB.Exec("UPDATE teams SET name=?, color=? WHERE id=?", t.Name, t.Color, t.ID)
-	if err != nil {
+	if err := srv.checkTeamWritable(t.ID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := srv.store.UpdateTeam(t); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -255,54 +290,124 @@ func EditTeam(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func RemoveTeam(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) RemoveTeam(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	id, _ := strconv.Atoi(idStr)
-	_, err := DB.Exec("DELETE FROM teams WHERE id=?", id)
+	if err := srv.checkTeamWritable(id); err != nil {
+		writeError(w, err)
+		return
+	}
+	err := srv.store.Tx(func(tx Store) error {
+		used, err := tx.TeamInUse(id)
+		if err != nil {
+			return err
+		}
+		if used {
+			return conflict{fmt.Errorf("Team plays in a match")}
+		}
+		return tx.RemoveTeam(id)
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // --- Player Handlers ---
-func AddPlayer(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) AddPlayer(w http.ResponseWriter, r *http.Request) {
 	var p Player
 	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	res, err := DB.Exec("INSERT INTO players (name, email, hcp) VALUES (?, ?, ?)", p.Name, p.Email, p.HCP)
+	if p.TeamID != nil {
+		if err := srv.checkTeamWritable(*p.TeamID); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	err := srv.store.Tx(func(tx Store) error {
+		if err := tx.AddPlayer(&p); err != nil {
+			return err
+		}
+		if p.TeamID != nil {
+			return tx.AddTeamPlayer(*p.TeamID, p.ID)
+		}
+		return nil
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	id, _ := res.LastInsertId()
-	p.ID = int(id)
-	if p.TeamID != nil {
-		_, _ = DB.Exec("INSERT OR IGNORE INTO team_players (team_id, player_id) VALUES (?, ?)", *p.TeamID, p.ID)
-	}
 	if err := json.NewEncoder(w).Encode(p); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
-func EditPlayer(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) EditPlayer(w http.ResponseWriter, r *http.Request) {
 	var p Player
 	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	_, err := DB.Exec("UPDATE players SET name=?, email=?, hcp=? WHERE id=?", p.Name, p.Email, p.HCP, p.ID)
+	// Team membership is changed in the event of the selected team
+	eventID, err := srv.eventFromRequest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
-	// Remove from all teams, then add to selected team if provided
-	_, _ = DB.Exec("DELETE FROM team_players WHERE player_id=?", p.ID)
 	if p.TeamID != nil {
-		_, _ = DB.Exec("INSERT OR IGNORE INTO team_players (team_id, player_id) VALUES (?, ?)", *p.TeamID, p.ID)
+		t, err := srv.store.GetTeam(*p.TeamID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		eventID = t.EventID
+	}
+	if err := srv.checkEventWritable(eventID); err != nil {
+		writeError(w, err)
+		return
+	}
+	err = srv.store.Tx(func(tx Store) error {
+		if err := tx.UpdatePlayer(p); err != nil {
+			return err
+		}
+		// Matches in play get the new handicap and the results it gives.
+		// Finished ones, in archived events, completed or signed, keep the
+		// handicap they were played with.
+		matches, err := tx.PlayerMatches(p.ID)
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			e, err := tx.GetEvent(m.EventID)
+			if err != nil {
+				return err
+			}
+			if e.Archived || checkResultsOpen(tx, m.ID) != nil {
+				continue
+			}
+			err = audited(tx, r, m.ID, func() error {
+				entrants, err := tx.MatchPlayers(m.ID)
+				if err != nil {
+					return err
+				}
+				if err := tx.SetMatchPlayers(m.ID, entrants); err != nil {
+					return err
+				}
+				return RecomputeHoleResults(tx, m.ID)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		// Remove from all teams of the event, then add to selected team if provided
+		return tx.SetPlayerTeam(eventID, p.ID, p.TeamID)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 	if err := json.NewEncoder(w).Encode(p); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -310,37 +415,57 @@ func EditPlayer(w http.ResponseWriter, r *http.Request) {
 }
 
 // --- Remove Player Handler ---
-func RemovePlayer(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) RemovePlayer(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	id, _ := strconv.Atoi(idStr)
-	_, err := DB.Exec("DELETE FROM players WHERE id=?", id)
+	// Players who played in an archived event are kept for its history
+	played, err := srv.store.PlayerMatches(id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	for _, m := range played {
+		if err := srv.checkEventWritable(m.EventID); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if err := srv.store.RemovePlayer(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // --- Team Handlers ---
-func AddTeam(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) AddTeam(w http.ResponseWriter, r *http.Request) {
 	var t Team
 	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	res, err := DB.Exec("INSERT INTO teams (name, color) VALUES (?, ?)", t.Name, t.Color)
-	if err != nil {
+	if t.EventID == 0 {
+		eventID, err := srv.eventFromRequest(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		t.EventID = eventID
+	}
+	if err := srv.checkEventWritable(t.EventID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := srv.store.AddTeam(&t); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	id, _ := res.LastInsertId()
-	t.ID = int(id)
 	if err := json.NewEncoder(w).Encode(t); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
-func AssignPlayerToTeam(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) AssignPlayerToTeam(w http.ResponseWriter, r *http.Request) {
 	type req struct {
 		PlayerID int `json:"player_id"`
 		TeamID   int `json:"team_id"`
@@ -350,8 +475,11 @@ func AssignPlayerToTeam(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	_, err := DB.Exec("INSERT INTO team_players (team_id, player_id) VALUES (?, ?)", body.TeamID, body.PlayerID)
-	if err != nil {
+	if err := srv.checkTeamWritable(body.TeamID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := srv.store.AddTeamPlayer(body.TeamID, body.PlayerID); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -359,50 +487,253 @@ func AssignPlayerToTeam(w http.ResponseWriter, r *http.Request) {
 }
 
 // --- Match Handlers ---
-func AddMatch(w http.ResponseWriter, r *http.Request) {
-	type req struct {
-		Format    string `json:"format"`
-		Holes     string `json:"holes"`
-		TeamA     int    `json:"team_a"`
-		TeamB     int    `json:"team_b"`
-		PlayersA  []int  `json:"players_a"`
-		PlayersB  []int  `json:"players_b"`
-		StartTime string `json:"start_time"`
+
+// matchRequest is the body of AddMatch and EditMatch.
+type matchRequest struct {
+	ID        int      `json:"id"`
+	Format    string   `json:"format"`
+	Holes     string   `json:"holes"`
+	TeamA     int      `json:"team_a"`
+	TeamB     int      `json:"team_b"`
+	PlayersA  []int    `json:"players_a"`
+	PlayersB  []int    `json:"players_b"`
+	StartTime string   `json:"start_time"`
+	CourseID  *int     `json:"course_id"`
+	TeeID     *int     `json:"tee_id"`
+	Points    *float64 `json:"points"` // null falls back to the session's points, then 1
+	SessionID *int     `json:"session_id"`
+}
+
+// entrants returns the roster of a match request.
+func (body matchRequest) entrants() []MatchEntrant {
+	var entrants []MatchEntrant
+	for _, pid := range body.PlayersA {
+		entrants = append(entrants, MatchEntrant{ID: pid, Side: "A"})
 	}
-	var body req
+	for _, pid := range body.PlayersB {
+		entrants = append(entrants, MatchEntrant{ID: pid, Side: "B"})
+	}
+	return entrants
+}
+
+// validateMatch checks a match request and returns the event it belongs to.
+// Matches of a session default to the session's format.
+func (srv *Server) validateMatch(body *matchRequest) (int, error) {
+	var session *Session
+	if body.SessionID != nil {
+		var err error
+		if session, err = srv.store.GetSession(*body.SessionID); err != nil {
+			return 0, err
+		}
+		if body.Format == "" {
+			body.Format = string(session.Format)
+		}
+		if err := srv.checkSessionWritable(*body.SessionID); err != nil {
+			return 0, err
+		}
+	}
+	if err := ValidateMatchFormat(MatchFormat(body.Format), len(body.PlayersA), len(body.PlayersB), body.CourseID != nil); err != nil {
+		return 0, badRequest{err}
+	}
+	if err := srv.validateMatchCourse(body.CourseID, body.TeeID); err != nil {
+		return 0, badRequest{err}
+	}
+	if body.Points != nil && *body.Points < 0 {
+		return 0, badRequest{fmt.Errorf("Invalid points")}
+	}
+	// The match belongs to the event of its teams
+	teamA, err := srv.store.GetTeam(body.TeamA)
+	if err != nil {
+		return 0, err
+	}
+	teamB, err := srv.store.GetTeam(body.TeamB)
+	if err != nil {
+		return 0, err
+	}
+	if teamA.EventID != teamB.EventID || (session != nil && session.EventID != teamA.EventID) {
+		return 0, badRequest{fmt.Errorf("Teams and session belong to different events")}
+	}
+	if err := srv.checkEventWritable(teamA.EventID); err != nil {
+		return 0, err
+	}
+	if err := srv.validateMatchRoster(body.TeamA, body.TeamB, body.PlayersA, body.PlayersB); err != nil {
+		return 0, badRequest{err}
+	}
+	return teamA.EventID, nil
+}
+
+func (srv *Server) AddMatch(w http.ResponseWriter, r *http.Request) {
+	var body matchRequest
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	res, err := DB.Exec("INSERT INTO matches (team_a_id, team_b_id, format, status, holes, start_time) VALUES (?, ?, ?, ?, ?, ?)", body.TeamA, body.TeamB, body.Format, "prepared", body.Holes, body.StartTime)
+	eventID, err := srv.validateMatch(&body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	m := Match{EventID: eventID, SessionID: body.SessionID, TeamA: body.TeamA, TeamB: body.TeamB, Format: MatchFormat(body.Format), Status: "prepared",
+		Holes: body.Holes, StartTime: body.StartTime, CourseID: body.CourseID, TeeID: body.TeeID, Points: body.Points}
+	err = srv.store.Tx(func(tx Store) error {
+		if err := tx.AddMatch(&m); err != nil {
+			return err
+		}
+		for _, side := range []string{"", "A", "B"} {
+			if err := tx.SetScorerToken(m.ID, side, newToken()); err != nil {
+				return err
+			}
+		}
+		return tx.SetMatchPlayers(m.ID, body.entrants())
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	matchID, _ := res.LastInsertId()
-	for _, pid := range body.PlayersA {
-		_, _ = DB.Exec("INSERT INTO match_players (match_id, player_id, team_side) VALUES (?, ?, ?)", matchID, pid, "A")
+	w.WriteHeader(http.StatusCreated)
+}
+
+// validateMatchCourse checks that a match's tee belongs to its course.
+func (srv *Server) validateMatchCourse(courseID, teeID *int) error {
+	if teeID != nil && courseID == nil {
+		return fmt.Errorf("tee requires a course")
 	}
-	for _, pid := range body.PlayersB {
-		_, _ = DB.Exec("INSERT INTO match_players (match_id, player_id, team_side) VALUES (?, ?, ?)", matchID, pid, "B")
+	if courseID == nil {
+		return nil
 	}
-	w.WriteHeader(http.StatusCreated)
+	if _, err := srv.store.GetCourse(*courseID); err != nil {
+		return fmt.Errorf("unknown course %d", *courseID)
+	}
+	if teeID != nil {
+		tee, err := srv.store.GetTee(*teeID)
+		if err != nil || tee.CourseID != *courseID {
+			return fmt.Errorf("tee %d does not belong to course %d", *teeID, *courseID)
+		}
+	}
+	return nil
+}
+
+// validateMatchRoster checks that every player of a side belongs to that side's
+// team and that nobody plays on both sides.
+func (srv *Server) validateMatchRoster(teamA, teamB int, playersA, playersB []int) error {
+	if teamA == teamB {
+		return fmt.Errorf("a match needs two different teams")
+	}
+	seen := map[int]bool{}
+	check := func(teamID int, players []int) error {
+		members, err := srv.store.TeamPlayers(teamID)
+		if err != nil {
+			return err
+		}
+		onTeam := map[int]bool{}
+		for _, p := range members {
+			onTeam[p.ID] = true
+		}
+		for _, pid := range players {
+			if seen[pid] {
+				return fmt.Errorf("player %d is listed twice", pid)
+			}
+			seen[pid] = true
+			if !onTeam[pid] {
+				return fmt.Errorf("player %d is not on team %d", pid, teamID)
+			}
+		}
+		return nil
+	}
+	if err := check(teamA, playersA); err != nil {
+		return err
+	}
+	return check(teamB, playersB)
 }
 
-func EditMatch(w http.ResponseWriter, r *http.Request) {
-	// ...edit match details...
+// EditMatch updates a match and its roster in place. Hole results are kept and
+// recomputed, strokes of players dropped from the roster are removed.
+func (srv *Server) EditMatch(w http.ResponseWriter, r *http.Request) {
+	var body matchRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer srv.lockMatch(r, body.ID)()
+	if err := srv.checkMatchWritable(body.ID); err != nil {
+		writeError(w, err)
+		return
+	}
+	m, err := srv.store.GetMatch(body.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	eventID, err := srv.validateMatch(&body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// Teams may change, but not the event the match belongs to
+	if eventID != m.EventID {
+		http.Error(w, "Teams and session must belong to the match's event", http.StatusBadRequest)
+		return
+	}
+	if body.Holes == "" {
+		body.Holes = "18"
+	}
+	// The signatures are of the holes the card had
+	holesChanged := m.Holes != body.Holes
+	m.SessionID, m.TeamA, m.TeamB, m.Format, m.Holes = body.SessionID, body.TeamA, body.TeamB, MatchFormat(body.Format), body.Holes
+	m.StartTime, m.CourseID, m.TeeID, m.Points = body.StartTime, body.CourseID, body.TeeID, body.Points
+	err = srv.store.Tx(func(tx Store) error {
+		return audited(tx, r, m.ID, func() error {
+			if err := tx.UpdateMatch(*m); err != nil {
+				return err
+			}
+			if holesChanged {
+				if err := tx.ClearCardSignatures(m.ID); err != nil {
+					return err
+				}
+			}
+			entrants := body.entrants()
+			if err := tx.SetMatchPlayers(m.ID, entrants); err != nil {
+				return err
+			}
+			onRoster := map[int]bool{}
+			for _, e := range entrants {
+				onRoster[e.ID] = true
+			}
+			scores, err := tx.ListScores(m.ID)
+			if err != nil {
+				return err
+			}
+			for _, sc := range scores {
+				if !onRoster[sc.PlayerID] {
+					sc.Strokes = 0
+					if err := tx.SetScore(sc); err != nil {
+						return err
+					}
+				}
+			}
+			return RecomputeHoleResults(tx, m.ID)
+		})
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 }
 
-func RemoveMatch(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) RemoveMatch(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
 		http.Error(w, "Invalid match id", http.StatusBadRequest)
 		return
 	}
-	_, err = DB.Exec("DELETE FROM matches WHERE id=?", id)
-	if err != nil {
+	defer srv.lockMatch(r, id)()
+	if err := srv.checkMatchWritable(id); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := srv.store.RemoveMatch(id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -410,198 +741,231 @@ func RemoveMatch(w http.ResponseWriter, r *http.Request) {
 }
 
 // --- Score Handlers ---
-func SubmitScore(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) SubmitScore(w http.ResponseWriter, r *http.Request) {
 	var s Score
 	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	_, err := DB.Exec("INSERT INTO scores (match_id, player_id, hole, strokes) VALUES (?, ?, ?, ?)", s.MatchID, s.PlayerID, s.Hole, s.Strokes)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if s.Hole < 1 || s.Hole > 18 {
+		http.Error(w, "Invalid hole", http.StatusBadRequest)
 		return
 	}
-	w.WriteHeader(http.StatusCreated)
-}
-
-// Submit scores for a match
-func SubmitMatchScore(w http.ResponseWriter, r *http.Request) {
-	type req struct {
-		MatchID int                `json:"match_id"`
-		Scores  map[string]float64 `json:"scores"` // key: team_side ("A"/"B"), value: score
-	}
-	var body req
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, err.Error(), 400)
+	defer srv.lockMatch(r, s.MatchID)()
+	if err := srv.checkMatchWritable(s.MatchID); err != nil {
+		writeError(w, err)
 		return
 	}
-	for side, score := range body.Scores {
-		_, err := DB.Exec("INSERT OR REPLACE INTO scores (match_id, team_side, score) VALUES (?, ?, ?)", body.MatchID, side, score)
-		if err != nil {
-			http.Error(w, err.Error(), 500)
-			return
+	err := srv.store.Tx(func(tx Store) error {
+		if err := checkResultsOpen(tx, s.MatchID); err != nil {
+			return err
 		}
+		return audited(tx, r, s.MatchID, func() error {
+			// One entry per player and hole; zero strokes clears the entry
+			if err := tx.SetScore(s); err != nil {
+				return err
+			}
+			return RecomputeHoleResults(tx, s.MatchID)
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
 	}
-	w.WriteHeader(http.StatusNoContent)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// SubmitMatchScore used to store a total per side, which the scores table
+// no longer has. Results are entered per hole.
+func (srv *Server) SubmitMatchScore(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "Scores are entered per hole, use /api/match/holescore", http.StatusGone)
 }
 
-// Get scores for a match
-func GetMatchScore(w http.ResponseWriter, r *http.Request) {
-	matchID := r.URL.Query().Get("match_id")
-	rows, err := DB.Query("SELECT team_side, score FROM scores WHERE match_id=?", matchID)
+// GetMatchScore returns the holes won by each side.
+func (srv *Server) GetMatchScore(w http.ResponseWriter, r *http.Request) {
+	matchID, _ := strconv.Atoi(r.URL.Query().Get("match_id"))
+	m, err := srv.store.GetMatch(matchID)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		writeError(w, err)
 		return
 	}
-	defer rows.Close()
-	scores := map[string]float64{}
-	for rows.Next() {
-		var side string
-		var score float64
-		rows.Scan(&side, &score)
-		scores[side] = score
+	results, err := loadHoleResults(srv.store, matchID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
 	}
+	state := ComputeMatchState(m.Holes, results)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"scores": scores})
+	json.NewEncoder(w).Encode(map[string]interface{}{"scores": map[string]float64{"A": float64(state.WonA), "B": float64(state.WonB)}})
+}
+
+// matchStanding returns the dashboard fields of a running or completed match,
+// its score and result, and the points each team gets from it: the winner
+// takes them, a halve splits them. Both are nil for other matches.
+func matchStanding(sm Match, status, conceded string, results []string, points float64, teamNames map[int]string) (map[string]interface{}, map[int]float64) {
+	if status != "completed" && status != "running" {
+		return nil, nil
+	}
+	ta, tb := sm.TeamA, sm.TeamB
+	m := map[string]interface{}{}
+	state := ComputeMatchState(sm.Holes, results)
+	a, b := state.WonA, state.WonB
+	share := map[int]float64{ta: points / 2, tb: points / 2}
+	switch {
+	case status == "completed" && conceded == "A":
+		share = map[int]float64{tb: points}
+	case status == "completed" && conceded == "B":
+		share = map[int]float64{ta: points}
+	case a > b:
+		share = map[int]float64{ta: points}
+	case b > a:
+		share = map[int]float64{tb: points}
+	}
+	m["score_a"] = a
+	m["score_b"] = b
+	m["dormie"] = state.Dormie
+	m["holes_remaining"] = state.Remaining
+	leader := map[string]string{"A": teamNames[ta], "B": teamNames[tb]}[state.Leader()]
+	lead := a - b
+	if lead < 0 {
+		lead = -lead
+	}
+	switch {
+	case status == "completed" && (conceded == "A" || conceded == "B"):
+		winner := map[string]string{"A": teamNames[tb], "B": teamNames[ta]}[conceded]
+		m["conceded"] = conceded
+		m["result"] = "Conceded"
+		m["score_text"] = fmt.Sprintf("%s wins, conceded", winner)
+	case state.Result == "Halved" || (status == "completed" && lead == 0):
+		m["result"] = "Halved"
+		m["score_text"] = "Halved"
+	case status == "completed" && state.Result == "":
+		// Finished early by hand
+		m["result"] = fmt.Sprintf("%d Up", lead)
+		m["score_text"] = fmt.Sprintf("%s %d Up", leader, lead)
+	case state.Result != "":
+		m["result"] = state.Result
+		m["score_text"] = fmt.Sprintf("%s %s", leader, state.Result)
+	case lead > 0:
+		m["score_text"] = fmt.Sprintf("%s %d Up", leader, lead)
+	default:
+		m["score_text"] = "A/S"
+	}
+	return m, share
 }
 
 // --- Dashboard Handler ---
-func Dashboard(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) Dashboard(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	// 1. Get all teams
-	teamRows, err := DB.Query("SELECT id, name, color FROM teams")
+	eventID, err := srv.eventFromRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	event, err := srv.store.GetEvent(eventID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// Replay the matches as of a past moment, from their history. It holds the
+	// hole results, status and concession; points and rosters are the current
+	// ones, see projectEvents.
+	var asOf *time.Time
+	if v := r.URL.Query().Get("as_of"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid as_of, use RFC 3339", http.StatusBadRequest)
+			return
+		}
+		asOf = &t
+	}
+	// 1. Get all teams of the event
+	eventTeams, err := srv.store.ListTeams(eventID)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
-	defer teamRows.Close()
 	teams := []map[string]interface{}{}
 	teamScores := map[int]float64{}
 	projectedScores := map[int]float64{}
 	teamNames := map[int]string{}
-	for teamRows.Next() {
-		var id int
-		var name string
-		var color string
-		teamRows.Scan(&id, &name, &color)
-		teams = append(teams, map[string]interface{}{"id": id, "name": name, "score": 0.0, "color": color})
-		teamScores[id] = 0.0
-		projectedScores[id] = 0.0
-		teamNames[id] = name
+	for _, t := range eventTeams {
+		teams = append(teams, map[string]interface{}{"id": t.ID, "name": t.Name, "score": 0.0, "color": t.Color})
+		teamScores[t.ID] = 0.0
+		projectedScores[t.ID] = 0.0
+		teamNames[t.ID] = t.Name
 	}
 	// 2. Get all finished matches and accumulate scores
-	matchRows, _ := DB.Query("SELECT id, team_a_id, team_b_id, status, start_time FROM matches")
-	defer matchRows.Close()
+	sessionsByID, sessionList, err := srv.sessionsByID(eventID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	stored, err := srv.store.ListMatches(eventID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
 	matches := []map[string]interface{}{}
-	for matchRows.Next() {
-		var id, ta, tb int
-		var status string
-		var startTime string
-		matchRows.Scan(&id, &ta, &tb, &status, &startTime)
-		m := map[string]interface{}{"id": id, "team_a_id": ta, "team_b_id": tb, "status": status, "team_a_name": teamNames[ta], "team_b_name": teamNames[tb], "start_time": startTime}
-		// Add player names and HCPs for each team
-		paRows, err := DB.Query(`SELECT p.name, p.hcp FROM match_players mp JOIN players p ON mp.player_id=p.id WHERE mp.match_id=? AND mp.team_side='A'`, id)
-		if err != nil {
-			m["players_a"] = []map[string]interface{}{}
-		} else {
-			playersA := []map[string]interface{}{}
-			for paRows.Next() {
-				var n string
-				var hcp sql.NullFloat64
-				paRows.Scan(&n, &hcp)
-				playersA = append(playersA, map[string]interface{}{"name": n, "hcp": hcp.Float64})
+	sessionScores := map[int]map[int]float64{}
+	for _, sm := range stored {
+		id, ta, tb, status, conceded := sm.ID, sm.TeamA, sm.TeamB, sm.Status, sm.Conceded
+		var holeResults []string
+		if asOf != nil {
+			events, err := srv.store.MatchEvents(id)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
 			}
-			paRows.Close()
-			m["players_a"] = playersA
-		}
-		pbRows, err := DB.Query(`SELECT p.name, p.hcp FROM match_players mp JOIN players p ON mp.player_id=p.id WHERE mp.match_id=? AND mp.team_side='B'`, id)
-		if err != nil {
-			m["players_b"] = []map[string]interface{}{}
-		} else {
-			playersB := []map[string]interface{}{}
-			for pbRows.Next() {
-				var n string
-				var hcp sql.NullFloat64
-				pbRows.Scan(&n, &hcp)
-				playersB = append(playersB, map[string]interface{}{"name": n, "hcp": hcp.Float64})
+			past := projectEvents(eventsAsOf(events, *asOf))
+			status, conceded = past.status, past.conceded
+			for _, h := range past.holes {
+				holeResults = append(holeResults, h.Result)
 			}
-			pbRows.Close()
-			m["players_b"] = playersB
+		} else if holeResults, err = loadHoleResults(srv.store, id); err != nil {
+			holeResults = make([]string, 18)
 		}
-		// Add per-hole results for this match
-		holeResults := make([]string, 18)
-		hrRows, err := DB.Query("SELECT hole, result FROM hole_results WHERE match_id=?", id)
-		if err == nil {
-			for hrRows.Next() {
-				var hole int
-				var result string
-				hrRows.Scan(&hole, &result)
-				if hole >= 1 && hole <= 18 {
-					holeResults[hole-1] = result
-				}
+		points := matchPoints(sm, sessionsByID)
+		sessionID := 0
+		if sm.SessionID != nil {
+			sessionID = *sm.SessionID
+		}
+		m := map[string]interface{}{"id": id, "team_a_id": ta, "team_b_id": tb, "status": status, "team_a_name": teamNames[ta], "team_b_name": teamNames[tb], "start_time": sm.StartTime, "points": points, "session_id": sessionID}
+		// Add player names and HCPs for each team
+		playersA := []map[string]interface{}{}
+		playersB := []map[string]interface{}{}
+		entrants, _ := srv.store.MatchPlayers(id)
+		for _, e := range entrants {
+			p := map[string]interface{}{"name": e.Name, "hcp": e.HCP}
+			if e.Side == "A" {
+				playersA = append(playersA, p)
+			} else {
+				playersB = append(playersB, p)
 			}
-			hrRows.Close()
 		}
+		m["players_a"] = playersA
+		m["players_b"] = playersB
+		// Add per-hole results for this match
 		m["holeResults"] = holeResults
 		// Add holes type (18, front9, back9) to match object
-		var holesType string
-		_ = DB.QueryRow("SELECT holes FROM matches WHERE id=?", id).Scan(&holesType)
+		holesType := sm.Holes
 		m["holes"] = holesType
 		// Get per-match winner if finished, or current score if running
-		if status == "completed" || status == "running" {
-			rows, err := DB.Query("SELECT result, COUNT(*) FROM hole_results WHERE match_id=? GROUP BY result", id)
-			if err == nil {
-				var a, b int
-				for rows.Next() {
-					var res string
-					var cnt int
-					rows.Scan(&res, &cnt)
-					if res == "A" {
-						a += cnt
-					}
-					if res == "B" {
-						b += cnt
-					}
-				}
-				rows.Close()
-				if status == "completed" {
-					if a > b {
-						teamScores[ta]++
-						projectedScores[ta]++
-					} else if b > a {
-						teamScores[tb]++
-						projectedScores[tb]++
-					} else if a == b {
-						teamScores[ta] += 0.5
-						teamScores[tb] += 0.5
-						projectedScores[ta] += 0.5
-						projectedScores[tb] += 0.5
-					}
-				} else if status == "running" {
-					if a > b {
-						projectedScores[ta]++
-					} else if b > a {
-						projectedScores[tb]++
-					} else if a == b {
-						projectedScores[ta] += 0.5
-						projectedScores[tb] += 0.5
-					}
-				}
-				m["score_a"] = a
-				m["score_b"] = b
-				if a > b {
-					m["score_text"] = fmt.Sprintf("%s %d Up", teamNames[ta], a-b)
-				} else if b > a {
-					m["score_text"] = fmt.Sprintf("%s %d Up", teamNames[tb], b-a)
-				} else {
-					m["score_text"] = "A/S"
+		standing, share := matchStanding(sm, status, conceded, holeResults, points, teamNames)
+		maps.Copy(m, standing)
+		for team, pts := range share {
+			projectedScores[team] += pts
+			if status == "completed" {
+				teamScores[team] += pts
+				if sessionScores[sessionID] == nil {
+					sessionScores[sessionID] = map[int]float64{}
 				}
+				sessionScores[sessionID][team] += pts
 			}
 		}
 		// Add match format
-		var format string
-		_ = DB.QueryRow("SELECT format FROM matches WHERE id=?", id).Scan(&format)
-		m["format"] = format
+		m["format"] = string(sm.Format)
+		m["format_name"] = FormatName(sm.Format)
 		matches = append(matches, m)
 	}
 	// Update team scores
@@ -618,11 +982,41 @@ func Dashboard(w http.ResponseWriter, r *http.Request) {
 		}
 		grouped[status] = append(grouped[status], m)
 	}
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	// 4. Nest matches under sessions with per-session points, unscheduled last
+	sessions := []map[string]interface{}{}
+	index := map[int]int{}
+	for _, s := range sessionList {
+		index[s.ID] = len(sessions)
+		sessions = append(sessions, map[string]interface{}{"id": s.ID, "name": s.Name, "date": s.Date, "format": s.Format, "format_name": FormatName(s.Format), "locked": s.Locked, "matches": []map[string]interface{}{}})
+	}
+	for _, m := range matches {
+		i, ok := index[m["session_id"].(int)]
+		if !ok {
+			index[0] = len(sessions)
+			sessions = append(sessions, map[string]interface{}{"id": 0, "name": "Unscheduled", "matches": []map[string]interface{}{}})
+			i = index[0]
+			index[m["session_id"].(int)] = i
+		}
+		sessions[i]["matches"] = append(sessions[i]["matches"].([]map[string]interface{}), m)
+	}
+	for _, s := range sessions {
+		scores := map[int]float64{}
+		for _, t := range teams {
+			scores[t["id"].(int)] = sessionScores[s["id"].(int)][t["id"].(int)]
+		}
+		s["scores"] = scores
+	}
+	resp := map[string]interface{}{
+		"event":           event,
 		"teams":           teams,
 		"matches":         grouped,
+		"sessions":        sessions,
 		"projectedScores": projectedScores,
-	})
+	}
+	if asOf != nil {
+		resp["as_of"] = asOf.UTC().Format(time.RFC3339)
+	}
+	json.NewEncoder(w).Encode(resp)
 }
 
 func HandleMainPage(w http.ResponseWriter, r *http.Request) {
@@ -639,6 +1033,10 @@ func HandleMainPage(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "static/admin.html")
 		return
 	}
+	if r.URL.Path == "/login" {
+		http.ServeFile(w, r, "static/login.html")
+		return
+	}
 	// Serve static assets (JS, CSS, etc.)
 	if len(r.URL.Path) > 8 && r.URL.Path[:8] == "/static/" {
 		http.ServeFile(w, r, "."+r.URL.Path)
@@ -652,8 +1050,45 @@ func HandleMainPage(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, "static/index.html")
 }
 
+// --- Set Match Points Handler ---
+func (srv *Server) SetMatchPoints(w http.ResponseWriter, r *http.Request) {
+	type req struct {
+		MatchID int      `json:"match_id"`
+		Points  *float64 `json:"points"` // null falls back to the session's points
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if body.Points != nil && *body.Points < 0 {
+		http.Error(w, "Invalid points", 400)
+		return
+	}
+	defer srv.lockMatch(r, body.MatchID)()
+	if err := srv.checkMatchWritable(body.MatchID); err != nil {
+		writeError(w, err)
+		return
+	}
+	err := srv.store.Tx(func(tx Store) error {
+		return audited(tx, r, body.MatchID, func() error {
+			m, err := tx.GetMatch(body.MatchID)
+			if err != nil {
+				return err
+			}
+			m.Points = body.Points
+			return tx.UpdateMatch(*m)
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // --- Per-hole Result Handlers ---
-func SaveHoleResults(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) SaveHoleResults(w http.ResponseWriter, r *http.Request) {
 	type req struct {
 		MatchID int      `json:"match_id"`
 		Holes   []string `json:"holes"` // 18 values: "A", "B", "AS", or ""
@@ -663,40 +1098,80 @@ func SaveHoleResults(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), 400)
 		return
 	}
-	// Remove old results
-	_, _ = DB.Exec("DELETE FROM hole_results WHERE match_id=?", body.MatchID)
-	for i, v := range body.Holes {
-		if v == "" {
-			continue
+	for _, v := range body.Holes {
+		if v != "" && v != "A" && v != "B" && v != "AS" {
+			http.Error(w, "Invalid result "+v, http.StatusBadRequest)
+			return
 		}
-		_, _ = DB.Exec("INSERT INTO hole_results (match_id, hole, result) VALUES (?, ?, ?)", body.MatchID, i+1, v)
+	}
+	defer srv.lockMatch(r, body.MatchID)()
+	if err := srv.checkMatchWritable(body.MatchID); err != nil {
+		writeError(w, err)
+		return
+	}
+	err := srv.store.Tx(func(tx Store) error {
+		if err := checkResultsOpen(tx, body.MatchID); err != nil {
+			return err
+		}
+		return audited(tx, r, body.MatchID, func() error {
+			// Keep the source of unchanged holes, anything else typed in is a manual override
+			existing, err := loadHoleResults(tx, body.MatchID)
+			if err != nil {
+				return err
+			}
+			for i := 0; i < 18; i++ {
+				v := ""
+				if i < len(body.Holes) {
+					v = body.Holes[i]
+				}
+				if v == existing[i] {
+					continue
+				}
+				if err := tx.SetHoleResult(body.MatchID, HoleResult{Hole: i + 1, Result: v, Source: SourceManual}); err != nil {
+					return err
+				}
+			}
+			// Cleared holes fall back to the result derived from strokes
+			return RecomputeHoleResults(tx, body.MatchID)
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func LoadHoleResults(w http.ResponseWriter, r *http.Request) {
-	matchID := r.URL.Query().Get("match_id")
-	rows, err := DB.Query("SELECT hole, result FROM hole_results WHERE match_id=?", matchID)
+func (srv *Server) LoadHoleResults(w http.ResponseWriter, r *http.Request) {
+	matchID, _ := strconv.Atoi(r.URL.Query().Get("match_id"))
+	results, err := srv.store.HoleResults(matchID)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
-	defer rows.Close()
 	holes := make([]string, 18)
-	for rows.Next() {
-		var hole int
-		var result string
-		rows.Scan(&hole, &result)
-		if hole >= 1 && hole <= 18 {
-			holes[hole-1] = result
+	sources := make([]string, 18)
+	for _, hr := range results {
+		if hr.Hole >= 1 && hr.Hole <= 18 {
+			holes[hr.Hole-1] = hr.Result
+			sources[hr.Hole-1] = hr.Source
 		}
 	}
+	// The version offline edits are based on, see SyncHoleEdits
+	events, err := srv.store.MatchEvents(matchID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"holes": holes})
+	json.NewEncoder(w).Encode(map[string]interface{}{"holes": holes, "sources": sources, "version": matchVersion(events)})
 }
 
 // --- Set Match Status Handler ---
-func SetMatchStatus(w http.ResponseWriter, r *http.Request) {
+
+// SetMatchStatus changes the status of a match. A match is completed only once
+// both sides signed its card, see SignCard.
+func (srv *Server) SetMatchStatus(w http.ResponseWriter, r *http.Request) {
 	type req struct {
 		MatchID int    `json:"match_id"`
 		Status  string `json:"status"`
@@ -706,9 +1181,84 @@ func SetMatchStatus(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), 400)
 		return
 	}
-	_, err := DB.Exec("UPDATE matches SET status=? WHERE id=?", body.Status, body.MatchID)
+	if body.Status != "prepared" && body.Status != "running" && body.Status != "completed" {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	defer srv.lockMatch(r, body.MatchID)()
+	if err := srv.checkMatchWritable(body.MatchID); err != nil {
+		writeError(w, err)
+		return
+	}
+	err := srv.store.Tx(func(tx Store) error {
+		return audited(tx, r, body.MatchID, func() error {
+			m, err := tx.GetMatch(body.MatchID)
+			if err != nil {
+				return err
+			}
+			if body.Status == "completed" && m.Status != "completed" {
+				if err := checkCardAttested(tx, body.MatchID); err != nil {
+					return err
+				}
+			}
+			m.Status = body.Status
+			// Reopening a match withdraws its concession
+			if m.Status != "completed" && m.Conceded != "" {
+				if err := checkConcessionWithdrawal(tx, r, body.MatchID, m.Conceded); err != nil {
+					return err
+				}
+				m.Conceded = ""
+			}
+			return tx.UpdateMatch(*m)
+		})
+	})
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Concede Match Handler ---
+
+// ConcedeMatch completes a match that one side gave up, the other side wins it
+// whatever the holes played say. It takes the conceding side's credential,
+// like signing its card: a concession is that side's sign-off on the match,
+// and the only way to complete a match without both signatures.
+func (srv *Server) ConcedeMatch(w http.ResponseWriter, r *http.Request) {
+	type req struct {
+		MatchID int    `json:"match_id"`
+		Side    string `json:"side"` // the side that concedes, A or B
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if body.Side != "A" && body.Side != "B" {
+		http.Error(w, "Invalid side", 400)
+		return
+	}
+	defer srv.lockMatch(r, body.MatchID)()
+	if err := srv.checkMatchWritable(body.MatchID); err != nil {
+		writeError(w, err)
+		return
+	}
+	err := srv.store.Tx(func(tx Store) error {
+		return audited(tx, r, body.MatchID, func() error {
+			m, err := tx.GetMatch(body.MatchID)
+			if err != nil {
+				return err
+			}
+			if m.Status == "completed" {
+				return conflict{fmt.Errorf("the match is completed already")}
+			}
+			m.Status, m.Conceded = "completed", body.Side
+			return tx.UpdateMatch(*m)
+		})
+	})
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,523 @@
+package backend
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testClient talks to a server on a MemoryStore as its admin, or with a
+// scorer token when token is set.
+type testClient struct {
+	t      *testing.T
+	url    string
+	client *http.Client
+	token  string
+}
+
+func newTestServer(t *testing.T) (*testClient, Store) {
+	t.Helper()
+	st := NewMemoryStore()
+	hash, err := hashPassword("adminpass1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.AddUser(&User{Username: "admin", Role: RoleAdmin, PasswordHash: hash}); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(NewServer(st).Handler())
+	t.Cleanup(ts.Close)
+	jar, _ := cookiejar.New(nil)
+	c := &testClient{t: t, url: ts.URL, client: &http.Client{Jar: jar}}
+	if status, body := c.post("/api/auth/login", `{"username":"admin","password":"adminpass1"}`); status != http.StatusOK {
+		t.Fatalf("login: %d %s", status, body)
+	}
+	return c, st
+}
+
+// as returns a client without the login that sends a scorer token.
+func (c *testClient) as(token string) *testClient {
+	return &testClient{t: c.t, url: c.url, client: &http.Client{}, token: token}
+}
+
+func (c *testClient) do(method, path, body string) (int, string) {
+	c.t.Helper()
+	req, err := http.NewRequest(method, c.url+path, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if c.token != "" {
+		req.Header.Set("X-Scorer-Token", c.token)
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(data)
+}
+
+func (c *testClient) post(path, body string) (int, string) {
+	c.t.Helper()
+	return c.do(http.MethodPost, path, body)
+}
+
+func (c *testClient) get(path string, v interface{}) {
+	c.t.Helper()
+	status, body := c.do(http.MethodGet, path, "")
+	if status != http.StatusOK {
+		c.t.Fatalf("GET %s: %d %s", path, status, body)
+	}
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		c.t.Fatalf("GET %s: %v", path, err)
+	}
+}
+
+// mustPost posts and fails the test unless the status is 2xx.
+func (c *testClient) mustPost(path, body string) string {
+	c.t.Helper()
+	status, res := c.post(path, body)
+	if status >= 300 {
+		c.t.Fatalf("POST %s: %d %s", path, status, res)
+	}
+	return res
+}
+
+// singlesMatch adds two teams of one player and a front nine singles match to
+// the event a new store starts with: match 1 with players 1 and 2.
+func singlesMatch(c *testClient) {
+	c.t.Helper()
+	c.mustPost("/api/team/add", `{"name":"EU","color":"#00f"}`)
+	c.mustPost("/api/team/add", `{"name":"US","color":"#f00"}`)
+	c.mustPost("/api/player/add", `{"name":"Alice","hcp":10,"team_id":1}`)
+	c.mustPost("/api/player/add", `{"name":"Bob","hcp":10,"team_id":2}`)
+	c.mustPost("/api/match/add", `{"team_a":1,"team_b":2,"format":"singles","holes":"front9","players_a":[1],"players_b":[2]}`)
+}
+
+// addCourse adds course 1: 18 holes of par 4, stroke index in hole order.
+func addCourse(c *testClient) {
+	c.t.Helper()
+	holes := make([]CourseHole, 18)
+	for i := range holes {
+		holes[i] = CourseHole{Hole: i + 1, Par: 4, StrokeIndex: i + 1}
+	}
+	course, _ := json.Marshal(Course{Name: "Links", Holes: holes})
+	c.mustPost("/api/course/add", string(course))
+}
+
+// holeResults returns the 18 hole results of a match.
+func (c *testClient) holeResults(matchID string) []string {
+	c.t.Helper()
+	var res struct {
+		Holes []string `json:"holes"`
+	}
+	c.get("/api/match/holescore?match_id="+matchID, &res)
+	return res.Holes
+}
+
+func (c *testClient) scorerToken(matchID, side string) string {
+	c.t.Helper()
+	var res struct {
+		Token string `json:"token"`
+	}
+	c.get("/api/match/token?match_id="+matchID+"&side="+side, &res)
+	return res.Token
+}
+
+func (c *testClient) matchStatus(id int) string {
+	c.t.Helper()
+	var res struct {
+		Matches []struct {
+			ID     int    `json:"id"`
+			Status string `json:"status"`
+		} `json:"matches"`
+	}
+	c.get("/api/match/list", &res)
+	for _, m := range res.Matches {
+		if m.ID == id {
+			return m.Status
+		}
+	}
+	c.t.Fatalf("match %d not listed", id)
+	return ""
+}
+
+func TestWritesArePostOnly(t *testing.T) {
+	c, _ := newTestServer(t)
+	singlesMatch(c)
+	for _, path := range []string{"/api/player/remove?id=1", "/api/team/remove?id=1", "/api/match/remove?id=1", "/api/user/remove?id=1", "/api/auth/logout"} {
+		if status, _ := c.do(http.MethodGet, path, ""); status != http.StatusMethodNotAllowed {
+			t.Errorf("GET %s: got %d, want 405", path, status)
+		}
+	}
+	var res struct {
+		Players []Player `json:"players"`
+	}
+	c.get("/api/player/list", &res)
+	if len(res.Players) != 2 {
+		t.Errorf("players after the GETs: got %d, want 2", len(res.Players))
+	}
+	if status, _ := c.do(http.MethodGet, "/api/auth/me", ""); status != http.StatusOK {
+		t.Errorf("me after the GET of logout: got %d, want 200", status)
+	}
+}
+
+// login returns a client with a login of its own.
+func (c *testClient) login(username, password string) *testClient {
+	c.t.Helper()
+	jar, _ := cookiejar.New(nil)
+	l := &testClient{t: c.t, url: c.url, client: &http.Client{Jar: jar}}
+	if status, body := l.post("/api/auth/login", `{"username":"`+username+`","password":"`+password+`"}`); status != http.StatusOK {
+		c.t.Fatalf("login of %s: %d %s", username, status, body)
+	}
+	return l
+}
+
+func TestPasswordChangeEndsLogins(t *testing.T) {
+	c, _ := newTestServer(t)
+	c.mustPost("/api/user/add", `{"username":"viewer","role":"viewer","password":"viewerpass1"}`)
+	phone, laptop := c.login("viewer", "viewerpass1"), c.login("viewer", "viewerpass1")
+	other := c.login("admin", "adminpass1")
+	me := func(client *testClient) int {
+		status, _ := client.do(http.MethodGet, "/api/auth/me", "")
+		return status
+	}
+
+	// Edits without a password keep the logins
+	c.mustPost("/api/user/edit", `{"id":2,"username":"viewer","role":"viewer"}`)
+	if got := me(phone); got != http.StatusOK {
+		t.Fatalf("me after an edit: got %d, want 200", got)
+	}
+	// A new password ends them
+	c.mustPost("/api/user/edit", `{"id":2,"username":"viewer","role":"viewer","password":"viewerpass2"}`)
+	for name, client := range map[string]*testClient{"phone": phone, "laptop": laptop} {
+		if got := me(client); got != http.StatusUnauthorized {
+			t.Errorf("me on the %s after the password change: got %d, want 401", name, got)
+		}
+	}
+	c.login("viewer", "viewerpass2")
+
+	// Changing their own, admins stay logged in where they did it
+	c.mustPost("/api/user/edit", `{"id":1,"username":"admin","role":"admin","password":"adminpass2"}`)
+	if got := me(c); got != http.StatusOK {
+		t.Errorf("me after changing my password: got %d, want 200", got)
+	}
+	if got := me(other); got != http.StatusUnauthorized {
+		t.Errorf("me on my other login: got %d, want 401", got)
+	}
+}
+
+func TestScorerTokens(t *testing.T) {
+	c, _ := newTestServer(t)
+	singlesMatch(c)
+	match, sideA := c.as(c.scorerToken("1", "")), c.as(c.scorerToken("1", "A"))
+	tests := []struct {
+		name   string
+		client *testClient
+		path   string
+		body   string
+		want   int
+	}{
+		{"no login", c.as(""), "/api/match/holescore", `{"match_id":1,"holes":["A"]}`, http.StatusUnauthorized},
+		{"wrong token", c.as("nope"), "/api/match/holescore", `{"match_id":1,"holes":["A"]}`, http.StatusUnauthorized},
+		{"match token scores", match, "/api/match/holescore", `{"match_id":1,"holes":["A"]}`, http.StatusNoContent},
+		{"match token can't enter a card", match, "/api/match/card", `{"match_id":1,"side":"A","holes":["A"]}`, http.StatusUnauthorized},
+		{"side token enters its card", sideA, "/api/match/card", `{"match_id":1,"side":"A","holes":["A"]}`, http.StatusOK},
+		{"side token can't enter the other card", sideA, "/api/match/card", `{"match_id":1,"side":"B","holes":["A"]}`, http.StatusUnauthorized},
+		{"invalid hole value", match, "/api/match/holescore", `{"match_id":1,"holes":["<b>"]}`, http.StatusBadRequest},
+		{"invalid status", c, "/api/match/status", `{"match_id":1,"status":"done"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := tt.client.post(tt.path, tt.body); status != tt.want {
+				t.Errorf("got %d %s, want %d", status, body, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignedCardCompletesMatch(t *testing.T) {
+	c, _ := newTestServer(t)
+	singlesMatch(c)
+	a, b := c.as(c.scorerToken("1", "A")), c.as(c.scorerToken("1", "B"))
+	holes := `["A","A","A","A","A"]`
+	a.mustPost("/api/match/card", `{"match_id":1,"side":"A","holes":`+holes+`}`)
+	if status, _ := a.post("/api/match/sign", `{"match_id":1,"side":"A","player_id":1}`); status != http.StatusConflict {
+		t.Errorf("signing before B entered the holes: got %d, want 409", status)
+	}
+	b.mustPost("/api/match/card", `{"match_id":1,"side":"B","holes":`+holes+`}`)
+	if status, _ := b.post("/api/match/sign", `{"match_id":1,"side":"B","player_id":1}`); status != http.StatusBadRequest {
+		t.Errorf("B signing with A's player: got %d, want 400", status)
+	}
+	a.mustPost("/api/match/sign", `{"match_id":1,"side":"A","player_id":1}`)
+	if got := c.matchStatus(1); got == "completed" {
+		t.Errorf("status after one signature: got %s", got)
+	}
+	b.mustPost("/api/match/sign", `{"match_id":1,"side":"B","player_id":2}`)
+	if got := c.matchStatus(1); got != "completed" {
+		t.Errorf("status after both signatures: got %s, want completed", got)
+	}
+	match := c.as(c.scorerToken("1", ""))
+	if status, _ := match.post("/api/match/holescore", `{"match_id":1,"holes":["B"]}`); status != http.StatusConflict {
+		t.Errorf("scoring a signed card: got %d, want 409", status)
+	}
+	if status, _ := c.post("/api/match/undo", `{"match_id":1}`); status != http.StatusConflict {
+		t.Errorf("undo on a signed card: got %d, want 409", status)
+	}
+}
+
+func TestRemoveTeamOfMatch(t *testing.T) {
+	c, st := newTestServer(t)
+	singlesMatch(c)
+	if status, _ := c.post("/api/team/remove?id=1", ""); status != http.StatusConflict {
+		t.Errorf("removing a team of a match: got %d, want 409", status)
+	}
+	c.mustPost("/api/match/remove?id=1", "")
+	c.mustPost("/api/team/remove?id=1", "")
+	if _, err := st.GetTeam(1); err != ErrNotFound {
+		t.Errorf("GetTeam after removing: got %v", err)
+	}
+}
+
+func TestArchivedEventKeepsHandicaps(t *testing.T) {
+	c, st := newTestServer(t)
+	singlesMatch(c)
+	c.mustPost("/api/event/archive", `{"event_id":1}`)
+	// Players are shared, the next event edits them
+	c.mustPost("/api/event/add", `{"name":"Next Cup"}`)
+	c.mustPost("/api/player/edit", `{"id":1,"name":"Alice","hcp":20}`)
+	entrants, err := st.MatchPlayers(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entrants) == 0 || entrants[0].HCP != 10 {
+		t.Errorf("roster of the archived match: got %+v, want Alice at 10", entrants)
+	}
+	if status, _ := c.post("/api/event/archive", `{"event_id":1,"archived":false}`); status != http.StatusBadRequest {
+		t.Errorf("unarchiving through archive: got %d, want 400", status)
+	}
+	c.mustPost("/api/event/unarchive", `{"event_id":1}`)
+	if e, _ := st.GetEvent(1); e == nil || e.Archived {
+		t.Errorf("event after unarchive: got %+v", e)
+	}
+}
+
+func TestTees(t *testing.T) {
+	c, _ := newTestServer(t)
+	addCourse(c)
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"unknown course", "/api/tee/add", `{"course_id":9,"name":"White"}`, http.StatusBadRequest},
+		{"short yardages", "/api/tee/add", `{"course_id":1,"name":"White","yards":[300,400]}`, http.StatusBadRequest},
+		{"no yardages", "/api/tee/add", `{"course_id":1,"name":"White"}`, http.StatusOK},
+		{"missing tee", "/api/tee/edit", `{"id":9,"course_id":1,"name":"Red"}`, http.StatusNotFound},
+		{"moved to another course", "/api/tee/edit", `{"id":1,"course_id":2,"name":"Red"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := c.post(tt.path, tt.body); status != tt.want {
+				t.Errorf("got %d %s, want %d", status, body, tt.want)
+			}
+		})
+	}
+}
+
+func TestScorerCannotWithdrawConcession(t *testing.T) {
+	c, _ := newTestServer(t)
+	singlesMatch(c)
+	match, sideA := c.as(c.scorerToken("1", "")), c.as(c.scorerToken("1", "A"))
+	match.mustPost("/api/match/status", `{"match_id":1,"status":"running"}`)
+	sideA.mustPost("/api/match/concede", `{"match_id":1,"side":"A"}`)
+
+	if status, body := match.post("/api/match/status", `{"match_id":1,"status":"running"}`); status != http.StatusConflict {
+		t.Errorf("scorer reopening a conceded match: got %d %s, want 409", status, body)
+	}
+	if status, body := match.post("/api/match/undo", `{"match_id":1}`); status != http.StatusConflict {
+		t.Errorf("scorer undoing the concession: got %d %s, want 409", status, body)
+	}
+	if got := c.matchStatus(1); got != "completed" {
+		t.Fatalf("status: got %s, want completed", got)
+	}
+	c.mustPost("/api/match/undo", `{"match_id":1}`)
+	if got := c.matchStatus(1); got != "running" {
+		t.Errorf("status after the admin undid the concession: got %s, want running", got)
+	}
+}
+
+func TestHandicapEditRescoresMatchesInPlay(t *testing.T) {
+	c, _ := newTestServer(t)
+	addCourse(c)
+	singlesMatch(c)
+	c.mustPost("/api/match/add", `{"team_a":1,"team_b":2,"format":"singles","holes":"18","course_id":1,"players_a":[1],"players_b":[2]}`)
+	c.mustPost("/api/match/add", `{"team_a":1,"team_b":2,"format":"singles","holes":"18","course_id":1,"players_a":[1],"players_b":[2]}`)
+	for _, id := range []string{"2", "3"} {
+		scorer := c.as(c.scorerToken(id, ""))
+		scorer.mustPost("/api/score/submit", `{"match_id":`+id+`,"player_id":1,"hole":1,"strokes":5}`)
+		scorer.mustPost("/api/score/submit", `{"match_id":`+id+`,"player_id":2,"hole":1,"strokes":4}`)
+		if got := c.holeResults(id)[0]; got != "B" {
+			t.Fatalf("match %s hole 1: got %q, want B", id, got)
+		}
+	}
+	c.as(c.scorerToken("3", "A")).mustPost("/api/match/concede", `{"match_id":3,"side":"A"}`)
+
+	// Two strokes more for Alice: she gets one on hole 1 and halves it
+	c.mustPost("/api/player/edit", `{"id":1,"name":"Alice","hcp":12}`)
+	if got := c.holeResults("2")[0]; got != "AS" {
+		t.Errorf("match in play, hole 1: got %q, want AS", got)
+	}
+	if got := c.holeResults("3")[0]; got != "B" {
+		t.Errorf("completed match, hole 1: got %q, want B as played", got)
+	}
+}
+
+func TestCourseEditRescoresMatchesInPlay(t *testing.T) {
+	c, _ := newTestServer(t)
+	addCourse(c)
+	c.mustPost("/api/tee/add", `{"course_id":1,"name":"White"}`)
+	singlesMatch(c)
+	c.mustPost("/api/player/edit", `{"id":1,"name":"Alice","hcp":11,"team_id":1}`)
+	c.mustPost("/api/match/add", `{"team_a":1,"team_b":2,"format":"singles","holes":"18","course_id":1,"tee_id":1,"players_a":[1],"players_b":[2]}`)
+	scorer := c.as(c.scorerToken("2", ""))
+	scorer.mustPost("/api/score/submit", `{"match_id":2,"player_id":1,"hole":2,"strokes":5}`)
+	scorer.mustPost("/api/score/submit", `{"match_id":2,"player_id":2,"hole":2,"strokes":4}`)
+	if got := c.holeResults("2")[1]; got != "B" {
+		t.Fatalf("hole 2: got %q, want B", got)
+	}
+
+	// Alice's stroke moves to hole 2, which becomes stroke index 1
+	holes := make([]CourseHole, 18)
+	for i := range holes {
+		holes[i] = CourseHole{Hole: i + 1, Par: 4, StrokeIndex: i + 1}
+	}
+	holes[0].StrokeIndex, holes[1].StrokeIndex = 2, 1
+	course, _ := json.Marshal(Course{ID: 1, Name: "Links", Holes: holes})
+	c.mustPost("/api/course/edit", string(course))
+	if got := c.holeResults("2")[1]; got != "AS" {
+		t.Errorf("hole 2 after the edit: got %q, want AS", got)
+	}
+
+	c.as(c.scorerToken("2", "B")).mustPost("/api/match/concede", `{"match_id":2,"side":"B"}`)
+	if status, body := c.post("/api/course/edit", string(course)); status != http.StatusConflict {
+		t.Errorf("editing the course of a completed match: got %d %s, want 409", status, body)
+	}
+	if status, body := c.post("/api/tee/edit", `{"id":1,"course_id":1,"name":"Red","slope":130,"course_rating":72.1}`); status != http.StatusConflict {
+		t.Errorf("editing the tee of a completed match: got %d %s, want 409", status, body)
+	}
+}
+
+// card returns the card of a match.
+func (c *testClient) card(matchID string) MatchCard {
+	c.t.Helper()
+	var card MatchCard
+	c.get("/api/match/card?match_id="+matchID, &card)
+	return card
+}
+
+func TestCardEntriesAreRecorded(t *testing.T) {
+	c, _ := newTestServer(t)
+	singlesMatch(c)
+	a, b := c.as(c.scorerToken("1", "A")), c.as(c.scorerToken("1", "B"))
+	a.mustPost("/api/match/card", `{"match_id":1,"side":"A","holes":["A"]}`)
+	b.mustPost("/api/match/card", `{"match_id":1,"side":"B","holes":["B"]}`)
+
+	var audit struct {
+		Entries []AuditEntry `json:"entries"`
+	}
+	c.get("/api/audit?match_id=1", &audit)
+	var got []string
+	for _, e := range audit.Entries {
+		got = append(got, e.Field+" "+e.Old+">"+e.New)
+	}
+	if want := []string{"side_a >A", "side_b >B"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("audit log: got %v, want %v", got, want)
+	}
+
+	// The strokes don't decide a hole the cards disagree on
+	scorer := c.as(c.scorerToken("1", ""))
+	scorer.mustPost("/api/score/submit", `{"match_id":1,"player_id":1,"hole":1,"strokes":4}`)
+	scorer.mustPost("/api/score/submit", `{"match_id":1,"player_id":2,"hole":1,"strokes":5}`)
+	if got := c.holeResults("1")[0]; got != "" {
+		t.Errorf("disputed hole 1 after the strokes: got %q, want none", got)
+	}
+
+	// Undoing the strokes and B's entry leaves A's, undoing that an empty card
+	for range 3 {
+		c.mustPost("/api/match/undo", `{"match_id":1}`)
+	}
+	if card := c.card("1"); card.Sides["A"][0] != "A" || card.Sides["B"][0] != "" {
+		t.Errorf("after undoing B's entry: got A %q and B %q, want A and none", card.Sides["A"][0], card.Sides["B"][0])
+	}
+	c.mustPost("/api/match/undo", `{"match_id":1}`)
+	if card := c.card("1"); card.Sides["A"][0] != "" {
+		t.Errorf("after undoing A's entry: got %q, want none", card.Sides["A"][0])
+	}
+}
+
+func TestEditMatchHolesVoidsSignatures(t *testing.T) {
+	c, _ := newTestServer(t)
+	singlesMatch(c)
+	a := c.as(c.scorerToken("1", "A"))
+	holes := `["A","A","A","A","A"]`
+	a.mustPost("/api/match/card", `{"match_id":1,"side":"A","holes":`+holes+`}`)
+	c.as(c.scorerToken("1", "B")).mustPost("/api/match/card", `{"match_id":1,"side":"B","holes":`+holes+`}`)
+	a.mustPost("/api/match/sign", `{"match_id":1,"side":"A","player_id":1}`)
+
+	edit := func(holes string) {
+		c.mustPost("/api/match/edit", `{"id":1,"team_a":1,"team_b":2,"format":"singles","holes":"`+holes+`","players_a":[1],"players_b":[2]}`)
+	}
+	edit("front9")
+	if got := len(c.card("1").Signatures); got != 1 {
+		t.Fatalf("signatures after an edit of the same holes: got %d, want 1", got)
+	}
+	edit("18")
+	if got := len(c.card("1").Signatures); got != 0 {
+		t.Errorf("signatures after the holes changed: got %d, want none", got)
+	}
+}
+
+func TestMatchScoresSessionPoints(t *testing.T) {
+	c, _ := newTestServer(t)
+	singlesMatch(c)
+	c.mustPost("/api/session/add", `{"name":"Friday fourballs","points":2}`)
+	c.mustPost("/api/match/add", `{"team_a":1,"team_b":2,"format":"singles","holes":"18","session_id":1,"players_a":[1],"players_b":[2]}`)
+	points := func() (float64, *float64) {
+		t.Helper()
+		var res struct {
+			Matches []struct {
+				ID        int      `json:"id"`
+				Points    float64  `json:"points"`
+				OwnPoints *float64 `json:"own_points"`
+			} `json:"matches"`
+		}
+		c.get("/api/match/list", &res)
+		for _, m := range res.Matches {
+			if m.ID == 2 {
+				return m.Points, m.OwnPoints
+			}
+		}
+		t.Fatal("match 2 not listed")
+		return 0, nil
+	}
+	if got, own := points(); got != 2 || own != nil {
+		t.Fatalf("new match: got %v points, own %v, want the session's 2", got, own)
+	}
+	// Saving the match as the admin page loads it keeps it on its session's
+	c.mustPost("/api/match/edit", `{"id":2,"team_a":1,"team_b":2,"format":"singles","holes":"front9","session_id":1,"points":null,"players_a":[1],"players_b":[2]}`)
+	c.mustPost("/api/session/edit", `{"id":1,"name":"Friday fourballs","points":3}`)
+	if got, own := points(); got != 3 || own != nil {
+		t.Errorf("after the edits: got %v points, own %v, want the session's 3", got, own)
+	}
+	c.mustPost("/api/match/edit", `{"id":2,"team_a":1,"team_b":2,"format":"singles","holes":"front9","session_id":1,"points":0.5,"players_a":[1],"players_b":[2]}`)
+	if got, own := points(); got != 0.5 || own == nil || *own != 0.5 {
+		t.Errorf("with points of its own: got %v points, own %v, want 0.5", got, own)
+	}
+}
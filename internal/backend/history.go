@@ -0,0 +1,240 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Types of match events.
+const (
+	MatchEventHoleSet       = "hole_set"       // Value is the result, Source where it came from
+	MatchEventHoleCleared   = "hole_cleared"   // the hole has no result anymore
+	MatchEventStrokesSet    = "strokes_set"    // Value is the strokes of PlayerID, "" clears them
+	MatchEventSideSet       = "side_set"       // Value is the result side Source entered, "" clears it
+	MatchEventConceded      = "match_conceded" // Value is the side that conceded, "" withdraws it
+	MatchEventStatusChanged = "status_changed" // Value is the new status
+	MatchEventUndo          = "undo"           // Value is the action undone
+)
+
+// MatchEvent is one entry of the history of a match. The hole results, the
+// cards of the sides, strokes, status and concession of a match are the
+// projection of its events; the events written by one request share an
+// Action. Action 0 is the state the match had before it had a history.
+type MatchEvent struct {
+	ID       int    `json:"id"`
+	MatchID  int    `json:"match_id"`
+	Action   int    `json:"action"`
+	Type     string `json:"type"`
+	Hole     int    `json:"hole,omitempty"`
+	PlayerID int    `json:"player_id,omitempty"`
+	Value    string `json:"value"`
+	Source   string `json:"source,omitempty"`
+	Actor    string `json:"actor"`
+	At       string `json:"at"` // RFC 3339, UTC
+}
+
+// projectEvents replays the history of a match, starting from a prepared
+// match without results. Points, rosters and signatures are not part of the
+// history.
+func projectEvents(events []MatchEvent) *matchSnapshot {
+	snap := newSnapshot("prepared")
+	for _, e := range events {
+		hole := e.Hole >= 1 && e.Hole <= 18
+		switch e.Type {
+		case MatchEventHoleSet:
+			if hole {
+				snap.holes[e.Hole-1] = HoleResult{Hole: e.Hole, Result: e.Value, Source: e.Source}
+			}
+		case MatchEventHoleCleared:
+			if hole {
+				snap.holes[e.Hole-1] = HoleResult{Hole: e.Hole}
+			}
+		case MatchEventStrokesSet:
+			key := [2]int{e.Hole, e.PlayerID}
+			if n, _ := strconv.Atoi(e.Value); n > 0 {
+				snap.strokes[key] = n
+			} else {
+				delete(snap.strokes, key)
+			}
+		case MatchEventSideSet:
+			if results, ok := snap.sides[e.Source]; ok && hole {
+				results[e.Hole-1] = e.Value
+			}
+		case MatchEventConceded:
+			snap.conceded = e.Value
+		case MatchEventStatusChanged:
+			snap.status = e.Value
+		}
+	}
+	return snap
+}
+
+// eventsAsOf returns the events that happened at or before t.
+func eventsAsOf(events []MatchEvent, t time.Time) []MatchEvent {
+	var past []MatchEvent
+	for _, e := range events {
+		if at, err := time.Parse(time.RFC3339, e.At); err == nil && !at.After(t) {
+			past = append(past, e)
+		}
+	}
+	return past
+}
+
+// matchVersion returns the version of a match from its history: its latest
+// action, 0 for a match without one.
+func matchVersion(events []MatchEvent) int {
+	version := 0
+	for _, e := range events {
+		version = max(version, e.Action)
+	}
+	return version
+}
+
+// lastAction returns the latest action of a history that can be undone: not
+// an undo itself, not undone already and not the imported state. 0 when there
+// is none.
+func lastAction(events []MatchEvent) int {
+	skip := map[int]bool{0: true}
+	for _, e := range events {
+		if e.Type == MatchEventUndo {
+			skip[e.Action] = true
+			if n, err := strconv.Atoi(e.Value); err == nil {
+				skip[n] = true
+			}
+		}
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		if !skip[events[i].Action] {
+			return events[i].Action
+		}
+	}
+	return 0
+}
+
+// apply writes the status, concession, hole results, cards and strokes of
+// the snapshot to a match where they differ, the cards as entered by actor.
+// Points are left as they are.
+func (s *matchSnapshot) apply(tx Store, matchID int, actor string) error {
+	current, err := snapshotMatch(tx, matchID)
+	if err != nil {
+		return err
+	}
+	if current.status != s.status || current.conceded != s.conceded {
+		m, err := tx.GetMatch(matchID)
+		if err != nil {
+			return err
+		}
+		m.Status, m.Conceded = s.status, s.conceded
+		if err := tx.UpdateMatch(*m); err != nil {
+			return err
+		}
+	}
+	for i, h := range s.holes {
+		if current.holes[i] != h {
+			if err := tx.SetHoleResult(matchID, h); err != nil {
+				return err
+			}
+		}
+	}
+	at := time.Now().UTC().Format(time.RFC3339)
+	for side, results := range s.sides {
+		for i, v := range results {
+			if current.sides[side][i] != v {
+				if err := tx.SetSideResult(matchID, SideResult{Side: side, Hole: i + 1, Result: v, Actor: actor, At: at}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for k, n := range current.strokes {
+		if _, ok := s.strokes[k]; !ok && n != 0 {
+			if err := tx.SetScore(Score{MatchID: matchID, Hole: k[0], PlayerID: k[1]}); err != nil {
+				return err
+			}
+		}
+	}
+	for k, n := range s.strokes {
+		if current.strokes[k] != n {
+			if err := tx.SetScore(Score{MatchID: matchID, Hole: k[0], PlayerID: k[1], Strokes: n}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// --- Match History Handlers ---
+
+// MatchHistory returns the history of a match, oldest first.
+func (srv *Server) MatchHistory(w http.ResponseWriter, r *http.Request) {
+	matchID, err := strconv.Atoi(r.URL.Query().Get("match_id"))
+	if err != nil {
+		http.Error(w, "Invalid match id", http.StatusBadRequest)
+		return
+	}
+	events, err := srv.store.MatchEvents(matchID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"events": events, "undoable": lastAction(events)})
+}
+
+// UndoMatchAction puts a match back in the state it had before its last
+// action, the cards of its sides included. The undo is an action of its own,
+// so the history keeps both. A signed card can't be undone.
+func (srv *Server) UndoMatchAction(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		MatchID int `json:"match_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer srv.lockMatch(r, body.MatchID)()
+	if err := srv.checkMatchWritable(body.MatchID); err != nil {
+		writeError(w, err)
+		return
+	}
+	var undone int
+	err := srv.store.Tx(func(tx Store) error {
+		events, err := tx.MatchEvents(body.MatchID)
+		if err != nil {
+			return err
+		}
+		if undone = lastAction(events); undone == 0 {
+			return conflict{errors.New("nothing to undo")}
+		}
+		// Signatures aren't part of the history, the sides change the
+		// results of a signed card themselves
+		sigs, err := tx.CardSignatures(body.MatchID)
+		if err != nil {
+			return err
+		}
+		if len(sigs) > 0 {
+			return conflict{errors.New("the card is signed, the sides have to change their results")}
+		}
+		first := slices.IndexFunc(events, func(e MatchEvent) bool { return e.Action == undone })
+		before := projectEvents(events[:first])
+		// Undoing a concession withdraws it
+		if conceded := projectEvents(events).conceded; conceded != "" && before.conceded != conceded {
+			if err := checkConcessionWithdrawal(tx, r, body.MatchID, conceded); err != nil {
+				return err
+			}
+		}
+		return recordAction(tx, r, body.MatchID, undone, func() error {
+			return before.apply(tx, body.MatchID, auditActor(r))
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"undone": undone})
+}
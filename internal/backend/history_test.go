@@ -0,0 +1,114 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestUndoTwice(t *testing.T) {
+	c, _ := newTestServer(t)
+	singlesMatch(c)
+	for _, holes := range []string{`["A"]`, `["A","B"]`, `["A","B","A"]`} {
+		c.mustPost("/api/match/holescore", `{"match_id":1,"holes":`+holes+`}`)
+	}
+	// Each undo takes back the latest action not undone yet, never an undo
+	for _, want := range []struct {
+		undone int
+		holes  []string
+	}{
+		{3, []string{"A", "B", ""}},
+		{2, []string{"A", "", ""}},
+		{1, []string{"", "", ""}},
+	} {
+		var res struct {
+			Undone int `json:"undone"`
+		}
+		if err := json.Unmarshal([]byte(c.mustPost("/api/match/undo", `{"match_id":1}`)), &res); err != nil {
+			t.Fatal(err)
+		}
+		if got := c.holeResults("1")[:3]; res.Undone != want.undone || !slices.Equal(got, want.holes) {
+			t.Errorf("undo: got action %d and holes %q, want action %d and %q", res.Undone, got, want.undone, want.holes)
+		}
+	}
+	if status, body := c.post("/api/match/undo", `{"match_id":1}`); status != http.StatusConflict {
+		t.Errorf("undo with nothing left: got %d %s, want 409", status, body)
+	}
+}
+
+func TestDashboardAsOf(t *testing.T) {
+	c, st := newTestServer(t)
+	singlesMatch(c)
+	// The history of the match, as it was played
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, e := range []MatchEvent{
+		{Type: MatchEventStatusChanged, Value: "running", At: "2026-09-25T10:00:00Z"},
+		{Type: MatchEventHoleSet, Hole: 1, Value: "A", Source: SourceManual, At: "2026-09-25T10:10:00Z"},
+		{Type: MatchEventHoleSet, Hole: 2, Value: "A", Source: SourceManual, At: "2026-09-25T10:20:00Z"},
+		{Type: MatchEventHoleSet, Hole: 3, Value: "B", Source: SourceManual, At: "2026-09-25T10:30:00Z"},
+		{Type: MatchEventHoleCleared, Hole: 2, At: "2026-09-25T10:40:00Z"},
+		{Type: MatchEventConceded, Value: "B", At: "2026-09-25T11:00:00Z"},
+		{Type: MatchEventStatusChanged, Value: "completed", At: "2026-09-25T11:00:00Z"},
+	} {
+		e.Actor = "admin"
+		must(st.AddMatchEvents(1, []MatchEvent{e}))
+	}
+	m, err := st.GetMatch(1)
+	must(err)
+	m.Status, m.Conceded = "completed", "B"
+	must(st.UpdateMatch(*m))
+	must(st.SetHoleResult(1, HoleResult{Hole: 1, Result: "A", Source: SourceManual}))
+	must(st.SetHoleResult(1, HoleResult{Hole: 3, Result: "B", Source: SourceManual}))
+
+	type dashboardMatch struct {
+		Status      string   `json:"status"`
+		HoleResults []string `json:"holeResults"`
+		ScoreText   string   `json:"score_text"`
+	}
+	dashboard := func(query string) (string, dashboardMatch) {
+		t.Helper()
+		var res struct {
+			AsOf    string                      `json:"as_of"`
+			Matches map[string][]dashboardMatch `json:"matches"`
+		}
+		c.get("/api/dashboard"+query, &res)
+		for _, ms := range res.Matches {
+			if len(ms) == 1 {
+				return res.AsOf, ms[0]
+			}
+		}
+		t.Fatalf("dashboard%s: got %+v, want the match", query, res.Matches)
+		return "", dashboardMatch{}
+	}
+
+	tests := []struct {
+		asOf   string
+		status string
+		holes  []string
+		text   string
+	}{
+		{"2026-09-25T09:00:00Z", "prepared", []string{"", "", ""}, ""},
+		{"2026-09-25T10:25:00Z", "running", []string{"A", "A", ""}, "EU 2 Up"},
+		{"2026-09-25T10:30:00Z", "running", []string{"A", "A", "B"}, "EU 1 Up"},
+		{"2026-09-25T10:45:00Z", "running", []string{"A", "", "B"}, "A/S"},
+		{"2026-09-25T12:00:00Z", "completed", []string{"A", "", "B"}, "EU wins, conceded"},
+	}
+	for _, tt := range tests {
+		asOf, got := dashboard("?as_of=" + tt.asOf)
+		if asOf != tt.asOf || got.Status != tt.status || !slices.Equal(got.HoleResults[:3], tt.holes) || got.ScoreText != tt.text {
+			t.Errorf("as of %s: got %s %+v, want %s %q %q", tt.asOf, asOf, got, tt.status, tt.holes, tt.text)
+		}
+	}
+	if asOf, got := dashboard(""); asOf != "" || got.Status != "completed" || got.ScoreText != "EU wins, conceded" {
+		t.Errorf("now: got %s %+v, want the completed match", asOf, got)
+	}
+	if status, _ := c.do(http.MethodGet, "/api/dashboard?as_of=yesterday", ""); status != http.StatusBadRequest {
+		t.Errorf("invalid as_of: got %d, want 400", status)
+	}
+}
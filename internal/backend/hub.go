@@ -0,0 +1,280 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Defaults of a Hub.
+const (
+	hubQueueSize    = 64               // messages waiting for a client before it is dropped
+	hubWriteTimeout = 10 * time.Second // for one message or ping
+	hubPingInterval = 30 * time.Second // server pings, so dead connections are noticed
+	hubReadTimeout  = 70 * time.Second // without any message or pong from the client
+	hubMaxRequest   = 4096             // bytes of a client message
+	hubHistorySize  = 1024             // messages kept for clients that reconnect
+)
+
+// wsConn is the part of a WebSocket connection the hub writes to, so the hub
+// runs without a network as well.
+type wsConn interface {
+	WriteMessage(messageType int, data []byte) error
+	SetWriteDeadline(t time.Time) error
+	Close() error
+}
+
+// Hub fans the live messages out to the connected clients. Every client has
+// a send queue and a goroutine writing it, so a slow client only delays
+// itself; clients whose queue fills up or whose writes time out are dropped.
+// The hub numbers the messages and keeps the last History of them, to replay
+// what reconnecting clients missed.
+type Hub struct {
+	QueueSize    int
+	WriteTimeout time.Duration
+	PingInterval time.Duration
+	History      int
+
+	mu      sync.Mutex
+	clients map[*hubClient]bool
+	seq     int
+	recent  []hubMessage
+}
+
+// hubMessage is a sent message, as kept for replays.
+type hubMessage struct {
+	seq    int
+	topics []string
+	data   []byte
+}
+
+// hubClient is a connection of a Hub. Its topics are nil until it
+// subscribes, it gets all messages until then.
+type hubClient struct {
+	hub    *Hub
+	conn   wsConn
+	name   string
+	send   chan []byte
+	done   chan struct{}
+	once   sync.Once
+	topics map[string]bool // guarded by hub.mu
+}
+
+// NewHub returns a Hub with the default queue size and timeouts.
+func NewHub() *Hub {
+	return &Hub{
+		QueueSize:    hubQueueSize,
+		WriteTimeout: hubWriteTimeout,
+		PingInterval: hubPingInterval,
+		History:      hubHistorySize,
+		clients:      map[*hubClient]bool{},
+	}
+}
+
+// Register adds a connection and starts its writer. name is used in the log.
+// A connection that can't send requests, like a stream of server-sent events,
+// comes with its subscription in sub.
+func (h *Hub) Register(conn wsConn, name string, sub *liveRequest) *hubClient {
+	c := &hubClient{hub: h, conn: conn, name: name, send: make(chan []byte, h.QueueSize), done: make(chan struct{})}
+	h.mu.Lock()
+	h.clients[c] = true
+	if sub != nil {
+		h.subscribe(c, *sub)
+	}
+	h.mu.Unlock()
+	go c.writer()
+	return c
+}
+
+// Len returns the number of connected clients.
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// Broadcast numbers the messages and queues them for the clients subscribed
+// to their topics. It never waits for a client.
+func (h *Hub) Broadcast(msgs []LiveMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, msg := range msgs {
+		msg.Seq = h.seq + 1
+		data, err := json.Marshal(msg)
+		if err != nil {
+			fmt.Println("WebSocket message error:", err)
+			continue
+		}
+		h.seq = msg.Seq
+		h.recent = append(h.recent, hubMessage{seq: msg.Seq, topics: msg.Topics, data: data})
+		if len(h.recent) > h.History {
+			h.recent = slices.Delete(h.recent, 0, len(h.recent)-h.History)
+		}
+		for c := range h.clients {
+			if c.wants(msg.Topics) {
+				h.queue(c, data)
+			}
+		}
+	}
+}
+
+// wants reports whether a client gets the messages of topics. h.mu is held.
+func (c *hubClient) wants(topics []string) bool {
+	return c.topics == nil || slices.ContainsFunc(topics, func(t string) bool { return c.topics[t] })
+}
+
+// replay queues the messages after seq since for a client, or a resync when
+// they are not all kept anymore or would overflow its queue. h.mu is held.
+func (h *Hub) replay(c *hubClient, since int) {
+	var missed [][]byte
+	gone := since > h.seq || (since < h.seq && (len(h.recent) == 0 || h.recent[0].seq > since+1))
+	for _, m := range h.recent {
+		if m.seq > since && c.wants(m.topics) {
+			missed = append(missed, m.data)
+		}
+	}
+	// Leave room for the reply to the subscription
+	if gone || len(missed) >= cap(c.send)-len(c.send) {
+		data, _ := json.Marshal(newLiveMessage(LiveResync, "", 0, map[string]interface{}{"seq": h.seq}))
+		h.queue(c, data)
+		return
+	}
+	for _, data := range missed {
+		h.queue(c, data)
+	}
+}
+
+// queue adds data to the send queue of a client, or drops the client when
+// the queue is full. h.mu is held.
+func (h *Hub) queue(c *hubClient, data []byte) {
+	select {
+	case c.send <- data:
+	default:
+		fmt.Printf("Live client %s is too slow, dropping it\n", c.name)
+		h.drop(c)
+	}
+}
+
+// drop removes a client and closes its connection, which ends its reader.
+// h.mu is held.
+func (h *Hub) drop(c *hubClient) {
+	delete(h.clients, c)
+	c.once.Do(func() {
+		close(c.done)
+		_ = c.conn.Close()
+	})
+}
+
+// Unregister removes a client, for example when its connection was closed.
+func (h *Hub) Unregister(c *hubClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(c)
+}
+
+// Handle answers a text message of a client: "ping", or a liveRequest that
+// changes its topics. The reply to a subscription comes after the replay of
+// the missed messages, its seq is the last one sent.
+func (h *Hub) Handle(c *hubClient, msg []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if string(msg) == "ping" {
+		h.queue(c, []byte("pong"))
+		return
+	}
+	var req liveRequest
+	if err := json.Unmarshal(msg, &req); err != nil || (req.Type != "subscribe" && req.Type != "unsubscribe") {
+		return
+	}
+	h.subscribe(c, req)
+}
+
+// subscribe changes the topics of a client, replays what it missed and
+// replies. A subscription without topics keeps them as they are. h.mu is held.
+func (h *Hub) subscribe(c *hubClient, req liveRequest) {
+	if c.topics == nil && len(req.Topics) > 0 {
+		c.topics = map[string]bool{}
+	}
+	for _, t := range req.Topics {
+		if validTopic(t) {
+			c.topics[t] = req.Type == "subscribe"
+		}
+	}
+	maps.DeleteFunc(c.topics, func(_ string, on bool) bool { return !on })
+	if req.Type == "subscribe" && req.Since != nil {
+		h.replay(c, *req.Since)
+	}
+	reply, _ := json.Marshal(newLiveMessage(LiveSubscribed, "", 0, map[string]interface{}{
+		"topics": slices.Sorted(maps.Keys(c.topics)), "seq": h.seq,
+	}))
+	h.queue(c, reply)
+}
+
+// writer writes the queue of a client and pings it, until the client is
+// dropped or a write fails.
+func (c *hubClient) writer() {
+	ping := time.NewTicker(c.hub.PingInterval)
+	defer ping.Stop()
+	write := func(messageType int, data []byte) bool {
+		_ = c.conn.SetWriteDeadline(time.Now().Add(c.hub.WriteTimeout))
+		if err := c.conn.WriteMessage(messageType, data); err != nil {
+			fmt.Printf("Live client %s write error: %v\n", c.name, err)
+			c.hub.Unregister(c)
+			return false
+		}
+		return true
+	}
+	for {
+		select {
+		case <-c.done:
+			return
+		case data := <-c.send:
+			if !write(websocket.TextMessage, data) {
+				return
+			}
+		case <-ping.C:
+			if !write(websocket.PingMessage, nil) {
+				return
+			}
+		}
+	}
+}
+
+// ServeHTTP upgrades a request to a WebSocket connection and reads the
+// client's messages until it disconnects or goes quiet.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		fmt.Println("WebSocket upgrade error:", err)
+		return
+	}
+	fmt.Printf("WebSocket client connected: %s\n", r.RemoteAddr)
+	c := h.Register(conn, r.RemoteAddr, nil)
+	defer func() {
+		h.Unregister(c)
+		fmt.Printf("WebSocket client disconnected: %s\n", r.RemoteAddr)
+	}()
+	conn.SetReadLimit(hubMaxRequest)
+	alive := func(string) error { return conn.SetReadDeadline(time.Now().Add(hubReadTimeout)) }
+	_ = alive("")
+	conn.SetPongHandler(alive)
+	for {
+		mt, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = alive("")
+		if mt == websocket.TextMessage {
+			h.Handle(c, msg)
+		}
+	}
+}
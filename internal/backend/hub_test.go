@@ -0,0 +1,249 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeConn is a wsConn that records the text messages written to it. A
+// stalled one blocks every write until its deadline passes or it is closed,
+// like a client that stopped reading.
+type fakeConn struct {
+	stalled bool
+	written chan []byte
+	closed  chan struct{}
+
+	mu        sync.Mutex
+	deadlines []time.Time
+	closes    int
+}
+
+func newFakeConn(stalled bool) *fakeConn {
+	return &fakeConn{stalled: stalled, written: make(chan []byte, 100), closed: make(chan struct{})}
+}
+
+func (c *fakeConn) WriteMessage(messageType int, data []byte) error {
+	if c.stalled {
+		var timeout <-chan time.Time
+		if d := c.deadline(); !d.IsZero() {
+			timeout = time.After(time.Until(d))
+		}
+		select {
+		case <-timeout:
+			return errors.New("i/o timeout")
+		case <-c.closed:
+			return errors.New("use of closed connection")
+		}
+	}
+	if messageType == websocket.TextMessage {
+		c.written <- data
+	}
+	return nil
+}
+
+func (c *fakeConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadlines = append(c.deadlines, t)
+	return nil
+}
+
+func (c *fakeConn) deadline() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.deadlines) == 0 {
+		return time.Time{}
+	}
+	return c.deadlines[len(c.deadlines)-1]
+}
+
+func (c *fakeConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closes == 0 {
+		close(c.closed)
+	}
+	c.closes++
+	return nil
+}
+
+// hubTestMessage is a LiveMessage as a client reads it.
+type hubTestMessage struct {
+	Seq     int            `json:"seq"`
+	Type    string         `json:"type"`
+	ID      int            `json:"id"`
+	Payload map[string]any `json:"payload"`
+}
+
+// next returns the next message written to c, failing the test when none
+// comes.
+func (c *fakeConn) next(t *testing.T) hubTestMessage {
+	t.Helper()
+	select {
+	case data := <-c.written:
+		var msg hubTestMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("message %s: %v", data, err)
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message written")
+		return hubTestMessage{}
+	}
+}
+
+// none fails the test when a message is written to c.
+func (c *fakeConn) none(t *testing.T) {
+	t.Helper()
+	select {
+	case data := <-c.written:
+		t.Fatalf("unexpected message %s", data)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// matchMessages returns n hole_changed messages of a match.
+func matchMessages(matchID, n int) []LiveMessage {
+	var msgs []LiveMessage
+	for range n {
+		msgs = append(msgs, newLiveMessage(LiveHoleChanged, "match", matchID, nil, matchTopic(matchID), TopicDashboard))
+	}
+	return msgs
+}
+
+// waitClients waits until the hub has n clients.
+func waitClients(t *testing.T, h *Hub, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); h.Len() != n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("clients: got %d, want %d", h.Len(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHubDropsSlowClient(t *testing.T) {
+	h := NewHub()
+	h.QueueSize = 2
+	slow, fast := newFakeConn(true), newFakeConn(false)
+	h.Register(slow, "slow", nil)
+	h.Register(fast, "fast", nil)
+
+	// The slow client's writer blocks on the first message, the next two
+	// fill its queue and the fourth drops it. The fast one gets them all.
+	for i := 1; i <= 4; i++ {
+		h.Broadcast(matchMessages(1, 1))
+		if msg := fast.next(t); msg.Seq != i {
+			t.Fatalf("fast client message %d: got seq %d", i, msg.Seq)
+		}
+	}
+	select {
+	case <-slow.closed:
+	case <-time.After(time.Second):
+		t.Fatal("the slow client was not closed")
+	}
+	waitClients(t, h, 1)
+}
+
+func TestHubWriteTimeout(t *testing.T) {
+	h := NewHub()
+	h.WriteTimeout = 20 * time.Millisecond
+	conn := newFakeConn(true)
+	h.Register(conn, "stalled", nil)
+
+	start := time.Now()
+	h.Broadcast(matchMessages(1, 1))
+	select {
+	case <-conn.closed:
+	case <-time.After(time.Second):
+		t.Fatal("the stalled client was not closed")
+	}
+	waitClients(t, h, 0)
+	d := conn.deadline()
+	if d.Before(start.Add(h.WriteTimeout)) || d.After(time.Now().Add(h.WriteTimeout)) {
+		t.Errorf("write deadline %v, want WriteTimeout after the write started at %v", d, start)
+	}
+}
+
+func TestHubTopics(t *testing.T) {
+	h := NewHub()
+	all, match1, dashboard := newFakeConn(false), newFakeConn(false), newFakeConn(false)
+	h.Register(all, "all", nil)
+	c := h.Register(match1, "match1", &liveRequest{Type: "subscribe", Topics: []string{matchTopic(1), "bogus"}})
+	h.Register(dashboard, "dashboard", &liveRequest{Type: "subscribe", Topics: []string{TopicDashboard}})
+	for _, conn := range []*fakeConn{match1, dashboard} {
+		if msg := conn.next(t); msg.Type != LiveSubscribed {
+			t.Fatalf("first message: got %s, want %s", msg.Type, LiveSubscribed)
+		}
+	}
+
+	h.Broadcast(append(matchMessages(1, 1), matchMessages(2, 1)...))
+	if got := []int{all.next(t).ID, all.next(t).ID}; !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("unsubscribed client: got matches %v, want 1 and 2", got)
+	}
+	if msg := match1.next(t); msg.ID != 1 {
+		t.Errorf("match:1 client: got match %d", msg.ID)
+	}
+	match1.none(t)
+	if got := []int{dashboard.next(t).ID, dashboard.next(t).ID}; !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("dashboard client: got matches %v, want 1 and 2", got)
+	}
+
+	// Unsubscribing the last topic stops the messages
+	h.Handle(c, []byte(`{"type":"unsubscribe","topics":["match:1"]}`))
+	if msg := match1.next(t); msg.Type != LiveSubscribed || msg.Payload["topics"] != nil {
+		t.Errorf("reply to unsubscribe: got %+v", msg)
+	}
+	h.Broadcast(matchMessages(1, 1))
+	match1.none(t)
+}
+
+func TestHubReplay(t *testing.T) {
+	tests := []struct {
+		name    string
+		since   int
+		history int
+		queue   int
+		want    []int // the seqs replayed, nil for a resync
+	}{
+		{"up to date", 5, 10, 64, []int{}},
+		{"missed some", 2, 10, 64, []int{3, 4, 5}},
+		{"missed all", 0, 10, 64, []int{1, 2, 3, 4, 5}},
+		{"missed more than kept", 1, 3, 64, nil},
+		{"missed more than the queue holds", 0, 10, 4, nil},
+		{"from a restarted server", 9, 10, 64, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub()
+			h.History, h.QueueSize = tt.history, tt.queue
+			h.Broadcast(matchMessages(1, 5))
+			conn := newFakeConn(false)
+			since := tt.since
+			h.Register(conn, "client", &liveRequest{Type: "subscribe", Topics: []string{matchTopic(1)}, Since: &since})
+
+			got := []int{}
+			msg := conn.next(t)
+			for ; msg.Type == LiveHoleChanged; msg = conn.next(t) {
+				got = append(got, msg.Seq)
+			}
+			if tt.want == nil {
+				if msg.Type != LiveResync || msg.Payload["seq"] != float64(5) {
+					t.Fatalf("got %+v after %v, want a resync to seq 5", msg, got)
+				}
+				msg = conn.next(t)
+			} else if !slices.Equal(got, tt.want) {
+				t.Errorf("replayed: got %v, want %v", got, tt.want)
+			}
+			if msg.Type != LiveSubscribed || msg.Payload["seq"] != float64(5) {
+				t.Errorf("reply: got %+v, want subscribed at seq 5", msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// LiveProtocolVersion is the version of the messages sent on /ws and
+// /api/events. Clients that don't know a version reload instead of applying
+// its messages.
+const LiveProtocolVersion = 1
+
+// Types of live messages.
+const (
+	LiveHoleChanged    = "hole_changed"    // match: the changed holes, all 18 results, the standing and version
+	LiveStrokesChanged = "strokes_changed" // match: the changed strokes, 0 for cleared ones, and the version
+	LiveStatusChanged  = "status_changed"  // match: status, concession, standing and version
+	LiveTotalsChanged  = "totals_changed"  // event: the points of the teams, overall, projected and per session
+	LiveCardChanged    = "card_changed"    // match: the card, with what each side entered and the signatures
+	LiveRefresh        = "refresh"         // anything else changed, reload
+	LiveSubscribed     = "subscribed"      // the reply to a subscription: the topics of the connection and the last seq
+	LiveResync         = "resync"          // the missed messages are gone: reload, then continue after payload.seq
+)
+
+// Topics clients subscribe to, besides match:<id> and event:<id>. A
+// connection that never subscribed gets every message.
+const (
+	TopicDashboard = "dashboard" // results and totals of all events, and refreshes of what the dashboard shows
+	TopicAdmin     = "admin"     // everything
+)
+
+func matchTopic(id int) string { return "match:" + strconv.Itoa(id) }
+func eventTopic(id int) string { return "event:" + strconv.Itoa(id) }
+
+// validTopic reports whether clients may subscribe to a topic.
+func validTopic(topic string) bool {
+	if topic == TopicDashboard || topic == TopicAdmin {
+		return true
+	}
+	kind, id, ok := strings.Cut(topic, ":")
+	if n, err := strconv.Atoi(id); !ok || err != nil || n <= 0 {
+		return false
+	}
+	return kind == "match" || kind == "event"
+}
+
+// LiveMessage is a JSON message of the live updates. Entity and ID name what
+// it is about, a match or an event; it is sent to the connections subscribed
+// to one of its Topics. The broadcast messages are numbered by Seq, in the
+// order they were sent.
+type LiveMessage struct {
+	V       int      `json:"v"`
+	Seq     int      `json:"seq,omitempty"`
+	Type    string   `json:"type"`
+	Entity  string   `json:"entity,omitempty"`
+	ID      int      `json:"id,omitempty"`
+	Payload any      `json:"payload,omitempty"`
+	Topics  []string `json:"-"`
+}
+
+func newLiveMessage(typ, entity string, id int, payload any, topics ...string) LiveMessage {
+	return LiveMessage{V: LiveProtocolVersion, Type: typ, Entity: entity, ID: id, Payload: payload, Topics: topics}
+}
+
+// liveRequest is a message of a client: subscribe or unsubscribe to topics.
+// A client that reconnects subscribes with the last seq it saw as Since, the
+// messages it missed are replayed.
+type liveRequest struct {
+	Type   string   `json:"type"`
+	Topics []string `json:"topics"`
+	Since  *int     `json:"since,omitempty"`
+}
+
+// liveChanges collects the history events a request wrote per match, and the
+// matches whose card changed; the live messages are made from them once it
+// succeeded, as rec tells.
+type liveChanges struct {
+	matches   []int
+	events    map[int][]MatchEvent
+	cards     []int
+	rec       *statusRecorder
+	published bool
+}
+
+type liveChangesKey struct{}
+
+// withLiveChanges returns the request with an empty liveChanges to collect into.
+func withLiveChanges(r *http.Request) (*http.Request, *liveChanges) {
+	c := &liveChanges{events: map[int][]MatchEvent{}}
+	return r.WithContext(context.WithValue(r.Context(), liveChangesKey{}, c)), c
+}
+
+// noteLiveChanges adds the events written for a match to the request's
+// changes. The match is noted even without events, its other fields changed.
+func noteLiveChanges(r *http.Request, matchID int, events []MatchEvent) {
+	c, _ := r.Context().Value(liveChangesKey{}).(*liveChanges)
+	if c == nil {
+		return
+	}
+	if _, ok := c.events[matchID]; !ok {
+		c.matches = append(c.matches, matchID)
+	}
+	c.events[matchID] = append(c.events[matchID], events...)
+}
+
+// noteCardChange adds a match whose card changed to the request's changes.
+func noteCardChange(r *http.Request, matchID int) {
+	c, _ := r.Context().Value(liveChangesKey{}).(*liveChanges)
+	if c != nil && !slices.Contains(c.cards, matchID) {
+		c.cards = append(c.cards, matchID)
+	}
+}
+
+// publishLive builds the live messages of a request that succeeded and
+// broadcasts them, once. It runs before the request unlocks its match, and
+// one request at a time, so the messages are numbered in the order of the
+// writes and the totals they carry are never older than the ones sent before.
+func (srv *Server) publishLive(r *http.Request) {
+	c, _ := r.Context().Value(liveChangesKey{}).(*liveChanges)
+	if c == nil || c.published || c.rec == nil || c.rec.status >= 400 {
+		return
+	}
+	c.published = true
+	srv.liveMu.Lock()
+	defer srv.liveMu.Unlock()
+	msgs := srv.liveMessages(r, c)
+	fmt.Printf("Broadcasting %d messages to %d live clients\n", len(msgs), srv.hub.Len())
+	srv.hub.Broadcast(msgs)
+}
+
+// statusRecorder remembers the status a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// liveMessages returns the messages for a write that succeeded. The writes of
+// scorers and of the sides' cards become deltas of their matches and the team
+// totals, any other write a refresh.
+func (srv *Server) liveMessages(r *http.Request, changes *liveChanges) []LiveMessage {
+	if !scoreRoutes[r.URL.Path] && !sideRoutes[r.URL.Path] {
+		return []LiveMessage{srv.refreshMessage(r, changes)}
+	}
+	var msgs []LiveMessage
+	var eventIDs []int
+	for _, id := range changes.matches {
+		if len(changes.events[id]) == 0 {
+			continue
+		}
+		matchMsgs, eventID, err := srv.matchLiveMessages(id, changes.events[id])
+		if err != nil {
+			return []LiveMessage{srv.refreshMessage(r, changes)}
+		}
+		msgs = append(msgs, matchMsgs...)
+		if !slices.Contains(eventIDs, eventID) {
+			eventIDs = append(eventIDs, eventID)
+		}
+	}
+	// The card only concerns the score pages of the match
+	for _, id := range changes.cards {
+		card, err := loadMatchCard(srv.store, id)
+		if err != nil {
+			return []LiveMessage{srv.refreshMessage(r, changes)}
+		}
+		msgs = append(msgs, newLiveMessage(LiveCardChanged, "match", id, card, matchTopic(id), TopicAdmin))
+	}
+	for _, eventID := range eventIDs {
+		scores, projected, sessions, err := srv.teamTotals(eventID)
+		if err != nil {
+			return []LiveMessage{srv.refreshMessage(r, changes)}
+		}
+		msgs = append(msgs, newLiveMessage(LiveTotalsChanged, "event", eventID, map[string]interface{}{
+			"scores": scores, "projected": projected, "sessions": sessions,
+		}, eventTopic(eventID), TopicDashboard, TopicAdmin))
+	}
+	return msgs
+}
+
+// refreshMessage is the refresh for a write: the users only concern the
+// admins, the edits of matches reach their score pages as well.
+func (srv *Server) refreshMessage(r *http.Request, changes *liveChanges) LiveMessage {
+	if strings.HasPrefix(r.URL.Path, "/api/user/") {
+		return newLiveMessage(LiveRefresh, "", 0, nil, TopicAdmin)
+	}
+	topics := []string{TopicDashboard, TopicAdmin}
+	for _, id := range changes.matches {
+		topics = append(topics, matchTopic(id))
+		if m, err := srv.store.GetMatch(id); err == nil && !slices.Contains(topics, eventTopic(m.EventID)) {
+			topics = append(topics, eventTopic(m.EventID))
+		}
+	}
+	return newLiveMessage(LiveRefresh, "", 0, nil, topics...)
+}
+
+// matchLiveMessages turns the events written for a match into its deltas, and
+// returns the event of the match.
+func (srv *Server) matchLiveMessages(matchID int, events []MatchEvent) ([]LiveMessage, int, error) {
+	m, err := srv.store.GetMatch(matchID)
+	if err != nil {
+		return nil, 0, err
+	}
+	sessions, _, err := srv.sessionsByID(m.EventID)
+	if err != nil {
+		return nil, 0, err
+	}
+	teams, err := srv.store.ListTeams(m.EventID)
+	if err != nil {
+		return nil, 0, err
+	}
+	teamNames := map[int]string{}
+	for _, t := range teams {
+		teamNames[t.ID] = t.Name
+	}
+	results, err := loadHoleResults(srv.store, matchID)
+	if err != nil {
+		return nil, 0, err
+	}
+	standing, _ := matchStanding(*m, m.Status, m.Conceded, results, matchPoints(*m, sessions), teamNames)
+
+	var holes []HoleResult
+	var scores []Score
+	status, version := false, matchVersion(events)
+	for _, e := range events {
+		switch e.Type {
+		case MatchEventHoleSet, MatchEventHoleCleared:
+			holes = append(holes, HoleResult{Hole: e.Hole, Result: e.Value, Source: e.Source})
+		case MatchEventStrokesSet:
+			n, _ := strconv.Atoi(e.Value)
+			scores = append(scores, Score{MatchID: matchID, PlayerID: e.PlayerID, Hole: e.Hole, Strokes: n})
+		case MatchEventStatusChanged, MatchEventConceded:
+			status = true
+		}
+	}
+	// The dashboard shows results, not strokes
+	topics := []string{matchTopic(matchID), eventTopic(m.EventID), TopicAdmin}
+	resultTopics := append(slices.Clone(topics), TopicDashboard)
+	var msgs []LiveMessage
+	if len(holes) > 0 {
+		msgs = append(msgs, newLiveMessage(LiveHoleChanged, "match", matchID, map[string]interface{}{
+			"holes": holes, "results": results, "standing": standing, "version": version,
+		}, resultTopics...))
+	}
+	if len(scores) > 0 {
+		msgs = append(msgs, newLiveMessage(LiveStrokesChanged, "match", matchID, map[string]interface{}{"scores": scores, "version": version}, topics...))
+	}
+	if status {
+		msgs = append(msgs, newLiveMessage(LiveStatusChanged, "match", matchID, map[string]interface{}{
+			"status": m.Status, "conceded": m.Conceded, "standing": standing, "version": version,
+		}, resultTopics...))
+	}
+	return msgs, m.EventID, nil
+}
+
+// teamTotals returns the points of the teams of an event from its completed
+// matches, the projected points including the running ones, and the points
+// per session, like the dashboard.
+func (srv *Server) teamTotals(eventID int) (map[int]float64, map[int]float64, map[int]map[int]float64, error) {
+	teams, err := srv.store.ListTeams(eventID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sessions, _, err := srv.sessionsByID(eventID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	matches, err := srv.store.ListMatches(eventID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	scores, projected, bySession := map[int]float64{}, map[int]float64{}, map[int]map[int]float64{}
+	for _, t := range teams {
+		scores[t.ID], projected[t.ID] = 0, 0
+	}
+	for _, m := range matches {
+		results, err := loadHoleResults(srv.store, m.ID)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		_, share := matchStanding(m, m.Status, m.Conceded, results, matchPoints(m, sessions), nil)
+		sessionID := 0
+		if m.SessionID != nil {
+			sessionID = *m.SessionID
+		}
+		for team, pts := range share {
+			projected[team] += pts
+			if m.Status == "completed" {
+				scores[team] += pts
+				if bySession[sessionID] == nil {
+					bySession[sessionID] = map[int]float64{}
+				}
+				bySession[sessionID][team] += pts
+			}
+		}
+	}
+	return scores, projected, bySession, nil
+}
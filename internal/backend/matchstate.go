@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MatchState is the match play standing derived from the hole results of a match.
+type MatchState struct {
+	WonA      int    `json:"won_a"`
+	WonB      int    `json:"won_b"`
+	Played    int    `json:"played"`
+	Remaining int    `json:"remaining"`
+	Lead      int    `json:"lead"`   // positive when side A leads
+	Closed    bool   `json:"closed"` // decided: lead exceeds holes remaining or all holes played
+	Dormie    bool   `json:"dormie"` // live and leading by exactly the holes remaining
+	Result    string `json:"result"` // "3&2", "2 Up", "Halved", or "" while live
+}
+
+// Leader returns "A", "B" or "" when the match is all square.
+func (s MatchState) Leader() string {
+	switch {
+	case s.Lead > 0:
+		return "A"
+	case s.Lead < 0:
+		return "B"
+	default:
+		return ""
+	}
+}
+
+// ComputeMatchState computes the standing of a match from its hole results
+// (index 0 is hole 1) for the holes setting (18, front9, back9).
+func ComputeMatchState(holes string, results []string) MatchState {
+	var s MatchState
+	start, end := holeRange(holes)
+	for i := start; i < end && i < len(results); i++ {
+		switch results[i] {
+		case "A":
+			s.WonA++
+		case "B":
+			s.WonB++
+		case "AS":
+		default:
+			continue
+		}
+		s.Played++
+	}
+	s.Remaining = end - start - s.Played
+	s.Lead = s.WonA - s.WonB
+	lead := s.Lead
+	if lead < 0 {
+		lead = -lead
+	}
+	switch {
+	case lead > s.Remaining:
+		s.Closed = true
+		if s.Remaining > 0 {
+			s.Result = fmt.Sprintf("%d&%d", lead, s.Remaining)
+		} else {
+			s.Result = fmt.Sprintf("%d Up", lead)
+		}
+	case s.Remaining == 0:
+		s.Closed = true
+		s.Result = "Halved"
+	case lead > 0 && lead == s.Remaining:
+		s.Dormie = true
+	}
+	return s
+}
+
+// loadHoleResults returns the 18 hole results of a match, "" for holes without one.
+func loadHoleResults(st Store, matchID int) ([]string, error) {
+	results := make([]string, 18)
+	rows, err := st.HoleResults(matchID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		if r.Hole >= 1 && r.Hole <= 18 {
+			results[r.Hole-1] = strings.TrimSpace(r.Result)
+		}
+	}
+	return results, nil
+}
+
+// CloseMatchIfDecided marks a match completed once its result can no longer
+// change and both sides signed its card.
+func CloseMatchIfDecided(st Store, matchID int) error {
+	m, err := st.GetMatch(matchID)
+	if err != nil {
+		return err
+	}
+	if m.Status == "completed" {
+		return nil
+	}
+	card, err := loadMatchCard(st, matchID)
+	if err != nil {
+		return err
+	}
+	if !card.Attested || !ComputeMatchState(m.Holes, card.Holes).Closed {
+		return nil
+	}
+	m.Status = "completed"
+	return st.UpdateMatch(*m)
+}
@@ -0,0 +1,127 @@
+package backend
+
+import "testing"
+
+// TestComputeMatchState walks through an 18 hole match that A wins 5&3.
+func TestComputeMatchState(t *testing.T) {
+	played := []string{"A", "A", "A", "A", "B", "AS", "AS", "AS", "AS", "AS", "AS", "AS", "AS", "A", "A"}
+	tests := []struct {
+		hole int // the standing after this hole
+		want MatchState
+	}{
+		{0, MatchState{Remaining: 18}},
+		{1, MatchState{WonA: 1, Played: 1, Remaining: 17, Lead: 1}},
+		{4, MatchState{WonA: 4, Played: 4, Remaining: 14, Lead: 4}},
+		{5, MatchState{WonA: 4, WonB: 1, Played: 5, Remaining: 13, Lead: 3}},
+		{13, MatchState{WonA: 4, WonB: 1, Played: 13, Remaining: 5, Lead: 3}},
+		{14, MatchState{WonA: 5, WonB: 1, Played: 14, Remaining: 4, Lead: 4, Dormie: true}},
+		{15, MatchState{WonA: 6, WonB: 1, Played: 15, Remaining: 3, Lead: 5, Closed: true, Result: "5&3"}},
+	}
+	for _, tt := range tests {
+		results := make([]string, 18)
+		copy(results, played[:tt.hole])
+		if got := ComputeMatchState("18", results); got != tt.want {
+			t.Errorf("after hole %d: got %+v, want %+v", tt.hole, got, tt.want)
+		}
+	}
+}
+
+func TestMatchResult(t *testing.T) {
+	// all returns the results of the holes in [from, to], the others empty.
+	all := func(from, to int, result string) []string {
+		results := make([]string, 18)
+		for i := from - 1; i < to; i++ {
+			results[i] = result
+		}
+		return results
+	}
+	with := func(results []string, hole int, result string) []string {
+		results[hole-1] = result
+		return results
+	}
+	tests := []struct {
+		name    string
+		holes   string
+		results []string
+		result  string
+		leader  string
+		dormie  bool
+	}{
+		{"won on the last hole", "18", with(all(1, 18, "AS"), 18, "B"), "1 Up", "B", false},
+		{"two up after 18", "18", with(with(all(1, 18, "AS"), 3, "A"), 7, "A"), "2 Up", "A", false},
+		{"halved", "18", with(with(all(1, 18, "AS"), 1, "A"), 2, "B"), "Halved", "", false},
+		{"all square, not finished", "18", all(1, 17, "AS"), "", "", false},
+		{"dormie for B", "18", with(all(1, 16, "AS"), 16, "B"), "", "B", false},
+		{"dormie on the last hole", "18", with(all(1, 17, "AS"), 17, "B"), "", "B", true},
+		{"front nine", "front9", all(1, 5, "A"), "5&4", "A", false},
+		{"back nine ignores the front", "back9", with(all(1, 9, "B"), 10, "A"), "", "A", false},
+		{"back nine", "back9", all(10, 17, "AS"), "", "", false},
+		{"back nine won", "back9", with(all(10, 16, "B"), 10, "AS"), "6&2", "B", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := ComputeMatchState(tt.holes, tt.results)
+			if s.Result != tt.result || s.Closed != (tt.result != "") {
+				t.Errorf("got result %q closed %v, want %q", s.Result, s.Closed, tt.result)
+			}
+			if s.Leader() != tt.leader {
+				t.Errorf("got leader %q, want %q", s.Leader(), tt.leader)
+			}
+			if s.Dormie != tt.dormie {
+				t.Errorf("got dormie %v, want %v", s.Dormie, tt.dormie)
+			}
+		})
+	}
+}
+
+// TestCloseMatchIfDecided completes a decided match only once both sides
+// signed its card.
+func TestCloseMatchIfDecided(t *testing.T) {
+	st := NewMemoryStore()
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	status := func(matchID int) string {
+		t.Helper()
+		m, err := st.GetMatch(matchID)
+		must(err)
+		return m.Status
+	}
+	sign := func(matchID int, side string) {
+		t.Helper()
+		must(st.AddCardSignature(&CardSignature{MatchID: matchID, Side: side, Signer: "Captain " + side}))
+	}
+	decided := Match{TeamA: 1, TeamB: 2, Format: Singles, Holes: "front9", Status: "running"}
+	live := decided
+	must(st.AddMatch(&decided))
+	must(st.AddMatch(&live))
+	for hole := 1; hole <= 5; hole++ {
+		must(st.SetHoleResult(decided.ID, HoleResult{Hole: hole, Result: "A", Source: SourceManual}))
+		must(st.SetHoleResult(live.ID, HoleResult{Hole: hole, Result: "AS", Source: SourceManual}))
+	}
+
+	must(CloseMatchIfDecided(st, decided.ID))
+	if got := status(decided.ID); got != "running" {
+		t.Fatalf("decided without signatures: got %s, want running", got)
+	}
+	sign(decided.ID, "A")
+	must(CloseMatchIfDecided(st, decided.ID))
+	if got := status(decided.ID); got != "running" {
+		t.Fatalf("signed by A only: got %s, want running", got)
+	}
+	sign(decided.ID, "B")
+	must(CloseMatchIfDecided(st, decided.ID))
+	if got := status(decided.ID); got != "completed" {
+		t.Fatalf("signed by both: got %s, want completed", got)
+	}
+
+	sign(live.ID, "A")
+	sign(live.ID, "B")
+	must(CloseMatchIfDecided(st, live.ID))
+	if got := status(live.ID); got != "running" {
+		t.Errorf("signed while live: got %s, want running", got)
+	}
+}
@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// Hole result sources. Results derived from strokes are owned by the scoring
+// engine; results typed in through SaveHoleResults are manual overrides and
+// results both sides' scorers entered alike are confirmed, the engine never
+// replaces either.
+const (
+	SourceStrokes   = "strokes"
+	SourceManual    = "manual"
+	SourceConfirmed = "confirmed"
+)
+
+// holeRange returns the zero-based range of holes played for a holes setting.
+func holeRange(holes string) (start, end int) {
+	switch holes {
+	case "front9":
+		return 0, 9
+	case "back9":
+		return 9, 18
+	default:
+		return 0, 18
+	}
+}
+
+// sideScore reduces the net strokes recorded for one side on a hole to the
+// score that counts for the match: the team ball, or the best ball of the
+// players who holed out. A partner who picked up has no score, the other's
+// counts. ok is false while nobody of the side has a score.
+func sideScore(strokes []int) (score int, ok bool) {
+	if len(strokes) == 0 {
+		return 0, false
+	}
+	return slices.Min(strokes), true
+}
+
+// stablefordPoints returns the Stableford points for a net score on a hole.
+func stablefordPoints(net, par int) int {
+	return max(0, 2+par-net)
+}
+
+// HoleWinner returns "A", "B" or "AS" for a hole, or "" while the hole is
+// incomplete. par is only used by Stableford formats.
+func HoleWinner(format MatchFormat, strokesA, strokesB []int, par int) string {
+	a, okA := sideScore(strokesA)
+	b, okB := sideScore(strokesB)
+	if !okA || !okB {
+		return ""
+	}
+	if formats[format].Stableford {
+		// More points wins, scores without points halve the hole
+		a, b = -stablefordPoints(a, par), -stablefordPoints(b, par)
+	}
+	switch {
+	case a < b:
+		return "A"
+	case b < a:
+		return "B"
+	default:
+		return "AS"
+	}
+}
+
+// RecomputeHoleResults derives the hole results of a match from the net
+// strokes of the players on its roster. Manual results are left untouched,
+// and so are the holes the sides' cards disagree on.
+func RecomputeHoleResults(st Store, matchID int) error {
+	m, err := st.GetMatch(matchID)
+	if err != nil {
+		return err
+	}
+	entrants, err := st.MatchPlayers(matchID)
+	if err != nil {
+		return err
+	}
+	sides := map[int]string{}
+	for _, e := range entrants {
+		sides[e.ID] = e.Side
+	}
+	received, err := LoadMatchStrokes(st, *m)
+	if err != nil {
+		return err
+	}
+	course, _, err := loadMatchCourse(st, *m)
+	if err != nil {
+		return err
+	}
+	var par [18]int
+	if course != nil && len(course.Holes) == 18 {
+		for i, h := range course.Holes {
+			par[i] = h.Par
+		}
+	}
+
+	var strokesA, strokesB [18][]int
+	scores, err := st.ListScores(matchID)
+	if err != nil {
+		return err
+	}
+	for _, sc := range scores {
+		if sc.Hole < 1 || sc.Hole > 18 {
+			continue
+		}
+		strokes := sc.Strokes
+		if alloc, ok := received[sc.PlayerID]; ok {
+			strokes -= alloc[sc.Hole-1]
+		}
+		switch sides[sc.PlayerID] {
+		case "A":
+			strokesA[sc.Hole-1] = append(strokesA[sc.Hole-1], strokes)
+		case "B":
+			strokesB[sc.Hole-1] = append(strokesB[sc.Hole-1], strokes)
+		}
+	}
+
+	existing, err := st.HoleResults(matchID)
+	if err != nil {
+		return err
+	}
+	keep := map[int]bool{}
+	for _, r := range existing {
+		keep[r.Hole] = r.Source != SourceStrokes
+	}
+	entries, err := st.SideResults(matchID)
+	if err != nil {
+		return err
+	}
+	entered := map[string]map[int]string{"A": {}, "B": {}}
+	for _, e := range entries {
+		if results, ok := entered[e.Side]; ok {
+			results[e.Hole] = e.Result
+		}
+	}
+	for hole, a := range entered["A"] {
+		if b := entered["B"][hole]; a != "" && b != "" && a != b {
+			keep[hole] = true
+		}
+	}
+	for i := 0; i < 18; i++ {
+		if keep[i+1] {
+			continue
+		}
+		result := HoleWinner(m.Format, strokesA[i], strokesB[i], par[i])
+		if err := st.SetHoleResult(matchID, HoleResult{Hole: i + 1, Result: result, Source: SourceStrokes}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --- List Scores Handler ---
+func (srv *Server) ListScores(w http.ResponseWriter, r *http.Request) {
+	matchID, _ := strconv.Atoi(r.URL.Query().Get("match_id"))
+	scores, err := srv.store.ListScores(matchID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"scores": scores})
+}
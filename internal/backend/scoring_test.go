@@ -0,0 +1,128 @@
+package backend
+
+import "testing"
+
+func TestHoleWinnerPickUp(t *testing.T) {
+	tests := []struct {
+		name               string
+		format             MatchFormat
+		strokesA, strokesB []int
+		want               string
+	}{
+		{"four-ball, A's partner picked up, A wins", FourBall, []int{3}, []int{4, 5}, "A"},
+		{"four-ball, A's partner picked up, B wins", FourBall, []int{5}, []int{4, 5}, "B"},
+		{"four-ball, both sides one ball", FourBall, []int{4}, []int{4}, "AS"},
+		{"four-ball, side without a ball", FourBall, []int{4, 5}, nil, ""},
+		{"better ball Stableford, partner picked up", StablefordMatch, []int{3}, []int{5, 6}, "A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HoleWinner(tt.format, tt.strokesA, tt.strokesB, 4); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHoleWinner(t *testing.T) {
+	tests := []struct {
+		format             MatchFormat
+		strokesA, strokesB []int
+		want               string
+	}{
+		{Singles, []int{4}, []int{5}, "A"},
+		{Singles, []int{5}, []int{4}, "B"},
+		{Singles, []int{4}, []int{4}, "AS"},
+		{Singles, []int{4}, nil, ""},
+		{Foursome, []int{4}, []int{5}, "A"},
+		{TexasScramble, []int{3}, []int{3}, "AS"},
+		{Greensomes, []int{6}, []int{5}, "B"},
+		{Chapman, []int{4}, []int{5}, "A"},
+		{FourBall, []int{5, 3}, []int{4, 4}, "A"},
+		{FourBall, []int{5, 4}, []int{4, 6}, "AS"},
+		{StablefordMatch, []int{3}, []int{4}, "A"},
+		{StablefordMatch, []int{5, 4}, []int{3, 6}, "B"},
+		// Without points on either side the hole is halved, where stroke
+		// play would give it to the lower score
+		{StablefordMatch, []int{6}, []int{7}, "AS"},
+		{Singles, []int{6}, []int{7}, "A"},
+	}
+	for _, tt := range tests {
+		if got := HoleWinner(tt.format, tt.strokesA, tt.strokesB, 4); got != tt.want {
+			t.Errorf("%s %v against %v: got %q, want %q", tt.format, tt.strokesA, tt.strokesB, got, tt.want)
+		}
+	}
+}
+
+func TestStablefordPoints(t *testing.T) {
+	tests := []struct{ net, par, want int }{
+		{1, 4, 5},
+		{2, 4, 4}, // albatross
+		{3, 4, 3},
+		{4, 4, 2},
+		{5, 4, 1},
+		{6, 4, 0}, // double bogey, the edge of max(0, 2+par-net)
+		{7, 4, 0},
+		{2, 3, 3},
+		{7, 5, 0},
+		{6, 5, 1},
+	}
+	for _, tt := range tests {
+		if got := stablefordPoints(tt.net, tt.par); got != tt.want {
+			t.Errorf("net %d on a par %d: got %d points, want %d", tt.net, tt.par, got, tt.want)
+		}
+	}
+}
+
+// TestRecomputeHoleResults scores a singles match on a course: the results
+// are decided on net strokes, a manual result is left as it is.
+func TestRecomputeHoleResults(t *testing.T) {
+	st := NewMemoryStore()
+	holes := make([]CourseHole, 18)
+	for i := range holes {
+		holes[i] = CourseHole{Hole: i + 1, Par: 4, StrokeIndex: i + 1}
+	}
+	course := Course{Name: "Links", Holes: holes}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(st.AddCourse(&course))
+	low, high := 5.0, 7.0
+	alice, bob := Player{Name: "Alice", HCP: &low}, Player{Name: "Bob", HCP: &high}
+	must(st.AddPlayer(&alice))
+	must(st.AddPlayer(&bob))
+	m := Match{TeamA: 1, TeamB: 2, Format: Singles, Holes: "18", CourseID: &course.ID}
+	must(st.AddMatch(&m))
+	must(st.SetMatchPlayers(m.ID, []MatchEntrant{{ID: alice.ID, Side: "A"}, {ID: bob.ID, Side: "B"}}))
+	// Bob receives two strokes, on stroke index 1 and 2
+	for _, sc := range []Score{
+		{PlayerID: alice.ID, Hole: 1, Strokes: 4}, {PlayerID: bob.ID, Hole: 1, Strokes: 5}, // net 4-4
+		{PlayerID: alice.ID, Hole: 2, Strokes: 4}, {PlayerID: bob.ID, Hole: 2, Strokes: 4}, // net 4-3
+		{PlayerID: alice.ID, Hole: 3, Strokes: 4}, {PlayerID: bob.ID, Hole: 3, Strokes: 5}, // gross
+		{PlayerID: alice.ID, Hole: 4, Strokes: 4},
+		{PlayerID: alice.ID, Hole: 5, Strokes: 6}, {PlayerID: bob.ID, Hole: 5, Strokes: 4},
+	} {
+		sc.MatchID = m.ID
+		must(st.SetScore(sc))
+	}
+	must(st.SetHoleResult(m.ID, HoleResult{Hole: 5, Result: "A", Source: SourceManual}))
+	must(RecomputeHoleResults(st, m.ID))
+
+	results, err := st.HoleResults(m.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[int]string{}
+	for _, r := range results {
+		got[r.Hole] = r.Result
+	}
+	want := map[int]string{1: "AS", 2: "B", 3: "A", 4: "", 5: "A"}
+	for hole, result := range want {
+		if got[hole] != result {
+			t.Errorf("hole %d: got %q, want %q", hole, got[hole], result)
+		}
+	}
+}
@@ -6,64 +6,45 @@ import (
 	"os"
 	"sync"
 
-	"github.com/gorilla/websocket"
 	"github.com/joho/godotenv"
 )
 
-func StartServer() {
-	mux := http.NewServeMux()
-	// --- WebSocket Hub ---
-	var wsUpgrader = websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool { return true },
-	}
-	var wsClients = make(map[*websocket.Conn]bool)
-	var wsLock sync.Mutex
-	broadcast := func() {
-		wsLock.Lock()
-		defer wsLock.Unlock()
-		fmt.Printf("Broadcasting update to %d WebSocket clients\n", len(wsClients))
-		for c := range wsClients {
-			_ = c.WriteMessage(websocket.TextMessage, []byte("update"))
-		}
-	}
-	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		conn, err := wsUpgrader.Upgrade(w, r, nil)
-		if err != nil {
-			fmt.Println("WebSocket upgrade error:", err)
-			return
-		}
-		fmt.Printf("WebSocket client connected: %s\n", r.RemoteAddr)
-		wsLock.Lock()
-		wsClients[conn] = true
-		wsLock.Unlock()
-		defer func() {
-			wsLock.Lock()
-			delete(wsClients, conn)
-			wsLock.Unlock()
-			conn.Close()
-			fmt.Printf("WebSocket client disconnected: %s\n", r.RemoteAddr)
-		}()
-		for {
-			mt, msg, err := conn.ReadMessage()
-			if err != nil {
-				break
-			}
-			if mt == websocket.TextMessage && string(msg) == "ping" {
-				_ = conn.WriteMessage(websocket.TextMessage, []byte("pong"))
-			}
-		}
-	})
+// Server serves the API and pages on top of a Store.
+type Server struct {
+	store Store
+	hub   *Hub
 
-	// Wrap mutating endpoints to broadcast updates
-	wrapAndBroadcast := func(h http.HandlerFunc) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
-			h(w, r)
-			if r.Method == http.MethodPost {
-				go broadcast()
-			}
-		}
+	mu         sync.Mutex
+	matchLocks map[int]*sync.Mutex
+	liveMu     sync.Mutex // one request at a time builds and sends its live messages
+}
+
+// NewServer returns a Server using the given store.
+func NewServer(store Store) *Server {
+	return &Server{store: store, hub: NewHub(), matchLocks: map[int]*sync.Mutex{}}
+}
+
+// lockMatch serializes the writes to one match, so two scorers saving at the
+// same time don't interleave. It returns the unlock, which sends the live
+// messages of the request first: those of a match go out in the order of its
+// writes, see publishLive.
+func (srv *Server) lockMatch(r *http.Request, matchID int) func() {
+	srv.mu.Lock()
+	l, ok := srv.matchLocks[matchID]
+	if !ok {
+		l = &sync.Mutex{}
+		srv.matchLocks[matchID] = l
 	}
+	srv.mu.Unlock()
+	l.Lock()
+	return func() {
+		srv.publishLive(r)
+		l.Unlock()
+	}
+}
 
+// StartServer serves the API on PORT (default 8080) until it fails.
+func StartServer(store Store) {
 	// Load .env file if present
 	_ = godotenv.Load()
 
@@ -71,80 +52,171 @@ func StartServer() {
 	if port == "" {
 		port = "8080"
 	}
+	if err := bootstrapAdmin(store); err != nil {
+		fmt.Printf("Failed to create the admin user: %v\n", err)
+	}
 	fmt.Printf("Starting backend server on port %s...\n", port)
 
+	addr := ":" + port
+	if err := http.ListenAndServe(addr, NewServer(store).Handler()); err != nil {
+		fmt.Printf("Server failed to start: %v\n", err)
+	}
+}
+
+// Handler returns the routes of the server, including the WebSocket hub,
+// behind the login and role checks of authenticate.
+func (srv *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	// --- WebSocket Hub ---
+	mux.Handle("/ws", srv.hub)
+	// The same messages as server-sent events
+	mux.HandleFunc("/api/events", srv.hub.ServeEvents)
+	// Writes only take POST: the login cookie is SameSite=Lax, so browsers
+	// don't send it with cross-site POSTs, but they do with links
+	postOnly := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			h(w, r)
+		}
+	}
+
+	// Wrap mutating endpoints to broadcast what a successful POST changed
+	wrapAndBroadcast := func(h http.HandlerFunc) http.HandlerFunc {
+		return postOnly(func(w http.ResponseWriter, r *http.Request) {
+			r, changes := withLiveChanges(r)
+			changes.rec = &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			h(changes.rec, r)
+			// Handlers that lock a match published already
+			srv.publishLive(r)
+		})
+	}
+
 	// Dashboard page (must be registered before /)
 	mux.HandleFunc("/dashboard", HandleMainPage)
 	mux.HandleFunc("/dashboard/", HandleMainPage)
 	// Main page (dashboard as homepage)
 	mux.HandleFunc("/", HandleMainPage)
+	// Event endpoints
+	mux.HandleFunc("/api/event/add", wrapAndBroadcast(srv.AddEvent))
+	mux.HandleFunc("/api/event/edit", wrapAndBroadcast(srv.EditEvent))
+	mux.HandleFunc("/api/event/archive", wrapAndBroadcast(srv.ArchiveEvent))
+	mux.HandleFunc("/api/event/unarchive", wrapAndBroadcast(srv.UnarchiveEvent))
+	mux.HandleFunc("/api/event/list", srv.ListEvents)
 	// Player endpoints
-	mux.HandleFunc("/api/player/add", wrapAndBroadcast(AddPlayer))
-	mux.HandleFunc("/api/player/edit", wrapAndBroadcast(EditPlayer))
-	mux.HandleFunc("/api/player/remove", wrapAndBroadcast(RemovePlayer))
-	mux.HandleFunc("/api/player/list", ListPlayers)
+	mux.HandleFunc("/api/player/add", wrapAndBroadcast(srv.AddPlayer))
+	mux.HandleFunc("/api/player/edit", wrapAndBroadcast(srv.EditPlayer))
+	mux.HandleFunc("/api/player/remove", wrapAndBroadcast(srv.RemovePlayer))
+	mux.HandleFunc("/api/player/list", srv.ListPlayers)
 	// Team endpoints
-	mux.HandleFunc("/api/team/add", wrapAndBroadcast(AddTeam))
-	mux.HandleFunc("/api/team/edit", wrapAndBroadcast(EditTeam))
-	mux.HandleFunc("/api/team/remove", wrapAndBroadcast(RemoveTeam))
-	mux.HandleFunc("/api/team/list", ListTeams)
-	mux.HandleFunc("/api/team/assign", AssignPlayersToTeam)
-	mux.HandleFunc("/api/team/players", ListPlayersByTeam)
+	mux.HandleFunc("/api/team/add", wrapAndBroadcast(srv.AddTeam))
+	mux.HandleFunc("/api/team/edit", wrapAndBroadcast(srv.EditTeam))
+	mux.HandleFunc("/api/team/remove", wrapAndBroadcast(srv.RemoveTeam))
+	mux.HandleFunc("/api/team/list", srv.ListTeams)
+	mux.HandleFunc("/api/team/assign", postOnly(srv.AssignPlayersToTeam))
+	mux.HandleFunc("/api/team/players", srv.ListPlayersByTeam)
+	// Course endpoints
+	mux.HandleFunc("/api/course/add", wrapAndBroadcast(srv.AddCourse))
+	mux.HandleFunc("/api/course/edit", wrapAndBroadcast(srv.EditCourse))
+	mux.HandleFunc("/api/course/remove", wrapAndBroadcast(srv.RemoveCourse))
+	mux.HandleFunc("/api/course/list", srv.ListCourses)
+	mux.HandleFunc("/api/tee/add", wrapAndBroadcast(srv.AddTee))
+	mux.HandleFunc("/api/tee/edit", wrapAndBroadcast(srv.EditTee))
+	mux.HandleFunc("/api/tee/remove", wrapAndBroadcast(srv.RemoveTee))
 	// Match endpoints
-	mux.HandleFunc("/api/match/add", wrapAndBroadcast(AddMatch))
-	mux.HandleFunc("/api/match/edit", wrapAndBroadcast(EditMatch))
-	mux.HandleFunc("/api/match/remove", wrapAndBroadcast(RemoveMatch))
-	mux.HandleFunc("/api/match/list", ListMatches)
+	mux.HandleFunc("/api/session/add", wrapAndBroadcast(srv.AddSession))
+	mux.HandleFunc("/api/session/edit", wrapAndBroadcast(srv.EditSession))
+	mux.HandleFunc("/api/session/remove", wrapAndBroadcast(srv.RemoveSession))
+	mux.HandleFunc("/api/session/lock", wrapAndBroadcast(srv.LockSession))
+	mux.HandleFunc("/api/session/list", srv.ListSessions)
+
+	mux.HandleFunc("/api/match/add", wrapAndBroadcast(srv.AddMatch))
+	mux.HandleFunc("/api/match/edit", wrapAndBroadcast(srv.EditMatch))
+	mux.HandleFunc("/api/match/remove", wrapAndBroadcast(srv.RemoveMatch))
+	mux.HandleFunc("/api/match/list", srv.ListMatches)
 	mux.HandleFunc("/api/matches", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			AddMatch(w, r)
+			srv.AddMatch(w, r)
 			return
 		}
 		// Handle other methods (GET, etc.) if needed
 	})
 	// Score endpoint
-	mux.HandleFunc("/api/score/submit", wrapAndBroadcast(SubmitScore))
+	mux.HandleFunc("/api/score/submit", wrapAndBroadcast(srv.SubmitScore))
+	mux.HandleFunc("/api/score/list", srv.ListScores)
 	// Dashboard
-	mux.HandleFunc("/api/dashboard", Dashboard)
+	mux.HandleFunc("/api/dashboard", srv.Dashboard)
 	// Match score endpoints
 	mux.HandleFunc("/api/match/score", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			SubmitMatchScore(w, r)
-			go broadcast()
+			wrapAndBroadcast(srv.SubmitMatchScore)(w, r)
 			return
 		}
 		if r.Method == http.MethodGet {
-			GetMatchScore(w, r)
+			srv.GetMatchScore(w, r)
 			return
 		}
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	})
+	// Match points endpoint
+	mux.HandleFunc("/api/match/points", wrapAndBroadcast(srv.SetMatchPoints))
 	// Hole score endpoints
 	mux.HandleFunc("/api/match/holescore", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			SaveHoleResults(w, r)
-			go broadcast()
+			wrapAndBroadcast(srv.SaveHoleResults)(w, r)
 			return
 		}
 		if r.Method == http.MethodGet {
-			LoadHoleResults(w, r)
+			srv.LoadHoleResults(w, r)
 			return
 		}
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	})
+	// Scorer tokens, printed as QR codes on the match cards (admins only)
+	mux.HandleFunc("/api/match/token", srv.MatchToken)
+	mux.HandleFunc("/api/match/qr", srv.MatchQRCode)
 	// Match status endpoint
 	mux.HandleFunc("/api/match/status", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			SetMatchStatus(w, r)
-			go broadcast()
+			wrapAndBroadcast(srv.SetMatchStatus)(w, r)
 			return
 		}
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	})
 
-	addr := ":" + port
-	if err := http.ListenAndServe(addr, mux); err != nil {
-		fmt.Printf("Server failed to start: %v\n", err)
-	}
+	// Match history: concessions, undo of the last action
+	mux.HandleFunc("/api/match/concede", wrapAndBroadcast(srv.ConcedeMatch))
+	mux.HandleFunc("/api/match/undo", wrapAndBroadcast(srv.UndoMatchAction))
+	// Hole edits queued by score pages while offline
+	mux.HandleFunc("/api/match/sync", wrapAndBroadcast(srv.SyncHoleEdits))
+	mux.HandleFunc("/api/match/history", srv.MatchHistory)
+	// Cards: the hole results each side entered, and the sign-offs
+	mux.HandleFunc("/api/match/card", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			wrapAndBroadcast(srv.SubmitSideResults)(w, r)
+			return
+		}
+		if r.Method == http.MethodGet {
+			srv.GetMatchCard(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+	mux.HandleFunc("/api/match/sign", wrapAndBroadcast(srv.SignCard))
+
+	// Audit log of the score and status changes
+	mux.HandleFunc("/api/audit", srv.ListAudit)
+	// Login and users
+	mux.HandleFunc("/api/auth/login", srv.Login)
+	mux.HandleFunc("/api/auth/logout", postOnly(srv.Logout))
+	mux.HandleFunc("/api/auth/me", srv.Me)
+	mux.HandleFunc("/api/user/list", srv.ListUsers)
+	mux.HandleFunc("/api/user/add", postOnly(srv.AddUser))
+	mux.HandleFunc("/api/user/edit", postOnly(srv.EditUser))
+	mux.HandleFunc("/api/user/remove", postOnly(srv.RemoveUser))
 
+	return srv.authenticate(mux)
 }
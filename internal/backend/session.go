@@ -0,0 +1,184 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Session is a named block of matches of an event, e.g. "Friday AM Foursomes".
+// Points is the default point value of its matches.
+type Session struct {
+	ID       int         `json:"id"`
+	EventID  int         `json:"event_id"`
+	Name     string      `json:"name"`
+	Date     string      `json:"date"`
+	Format   MatchFormat `json:"format"`
+	Position int         `json:"position"`
+	Points   *float64    `json:"points,omitempty"`
+	Locked   bool        `json:"locked"`
+}
+
+// ErrSessionLocked is returned for writes to a match of a locked session.
+var ErrSessionLocked = errors.New("session is locked")
+
+// checkSessionWritable returns ErrSessionLocked for locked sessions and
+// ErrEventArchived when the session belongs to an archived event.
+func (srv *Server) checkSessionWritable(sessionID int) error {
+	ss, err := srv.store.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if err := srv.checkEventWritable(ss.EventID); err != nil {
+		return err
+	}
+	if ss.Locked {
+		return ErrSessionLocked
+	}
+	return nil
+}
+
+// validateSession checks the format and point value of a session.
+func validateSession(s Session) error {
+	if s.Format != "" {
+		if _, ok := formats[s.Format]; !ok {
+			return errors.New("unknown match format " + string(s.Format))
+		}
+	}
+	if s.Points != nil && *s.Points < 0 {
+		return errors.New("invalid points")
+	}
+	return nil
+}
+
+// --- Session Handlers ---
+func (srv *Server) ListSessions(w http.ResponseWriter, r *http.Request) {
+	eventID, err := srv.eventFromRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sessions, err := srv.store.ListSessions(eventID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"sessions": sessions}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (srv *Server) AddSession(w http.ResponseWriter, r *http.Request) {
+	var s Session
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateSession(s); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.EventID == 0 {
+		eventID, err := srv.eventFromRequest(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		s.EventID = eventID
+	}
+	if err := srv.checkEventWritable(s.EventID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if s.Position == 0 {
+		// New sessions are played after the existing ones
+		sessions, err := srv.store.ListSessions(s.EventID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, o := range sessions {
+			s.Position = max(s.Position, o.Position)
+		}
+		s.Position++
+	}
+	if err := srv.store.AddSession(&s); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (srv *Server) EditSession(w http.ResponseWriter, r *http.Request) {
+	var s Session
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateSession(s); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	old, err := srv.store.GetSession(s.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := srv.checkEventWritable(old.EventID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := srv.store.UpdateSession(s); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.EventID = old.EventID
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// RemoveSession deletes a session, its matches stay in the event unscheduled.
+func (srv *Server) RemoveSession(w http.ResponseWriter, r *http.Request) {
+	idStr := r.URL.Query().Get("id")
+	id, _ := strconv.Atoi(idStr)
+	if err := srv.checkSessionWritable(id); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := srv.store.RemoveSession(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LockSession locks all matches of a session against score changes, or opens them again.
+func (srv *Server) LockSession(w http.ResponseWriter, r *http.Request) {
+	type req struct {
+		SessionID int  `json:"session_id"`
+		Locked    bool `json:"locked"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ss, err := srv.store.GetSession(body.SessionID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := srv.checkEventWritable(ss.EventID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := srv.store.SetSessionLocked(body.SessionID, body.Locked); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
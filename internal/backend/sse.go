@@ -0,0 +1,124 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// sseConn writes the messages of a hub client as server-sent events. The
+// event id is the seq of a broadcast message, or the seq the stream is at for
+// the subscription and resync, so EventSource resumes with Last-Event-ID
+// after reconnecting.
+type sseConn struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	mu     sync.Mutex
+	done   bool // the handler returned, w is gone
+	closed chan struct{}
+	once   sync.Once
+}
+
+var errSSEClosed = errors.New("event stream closed")
+
+func (c *sseConn) WriteMessage(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done {
+		return errSSEClosed
+	}
+	if messageType == websocket.PingMessage {
+		fmt.Fprint(c.w, ": ping\n\n")
+	} else {
+		var msg struct {
+			Seq     int    `json:"seq"`
+			Type    string `json:"type"`
+			Payload struct {
+				Seq int `json:"seq"`
+			} `json:"payload"`
+		}
+		if json.Unmarshal(data, &msg) == nil {
+			if msg.Type == LiveSubscribed || msg.Type == LiveResync {
+				fmt.Fprintf(c.w, "id: %d\n", msg.Payload.Seq)
+			} else if msg.Seq > 0 {
+				fmt.Fprintf(c.w, "id: %d\n", msg.Seq)
+			}
+		}
+		fmt.Fprintf(c.w, "data: %s\n\n", data)
+	}
+	return c.rc.Flush()
+}
+
+func (c *sseConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done {
+		return errSSEClosed
+	}
+	return c.rc.SetWriteDeadline(t)
+}
+
+func (c *sseConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+// finish is called when the handler returns, the writer may not use w after.
+func (c *sseConn) finish() {
+	c.mu.Lock()
+	c.done = true
+	c.mu.Unlock()
+}
+
+// ServeEvents streams the live messages as server-sent events, for clients
+// that can't use /ws. The topics come comma separated in ?topics=, all
+// messages without; Last-Event-ID replays the messages missed since.
+func (h *Hub) ServeEvents(w http.ResponseWriter, r *http.Request) {
+	sub := &liveRequest{Type: "subscribe"}
+	if topics := r.URL.Query().Get("topics"); topics != "" {
+		for _, t := range strings.Split(topics, ",") {
+			if !validTopic(t) {
+				http.Error(w, "Invalid topic "+t, http.StatusBadRequest)
+				return
+			}
+			sub.Topics = append(sub.Topics, t)
+		}
+	}
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		since, err := strconv.Atoi(id)
+		if err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		sub.Since = &since
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx would hold the events back
+	w.WriteHeader(http.StatusOK)
+	conn := &sseConn{w: w, rc: http.NewResponseController(w), closed: make(chan struct{})}
+	if err := conn.rc.Flush(); err != nil {
+		fmt.Println("Event stream error:", err)
+		return
+	}
+
+	name := r.RemoteAddr + " (events)"
+	fmt.Printf("Live client connected: %s\n", name)
+	c := h.Register(conn, name, sub)
+	defer func() {
+		h.Unregister(c)
+		conn.finish()
+		fmt.Printf("Live client disconnected: %s\n", name)
+	}()
+	select {
+	case <-r.Context().Done():
+	case <-conn.closed:
+	}
+}
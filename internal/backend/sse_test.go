@@ -0,0 +1,138 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent is one server-sent event: its id and the message in its data.
+type sseEvent struct {
+	id  string
+	msg hubTestMessage
+}
+
+// openEvents opens the event stream of a hub and returns its events as they
+// come. The stream is closed when the test ends.
+func openEvents(t *testing.T, h *Hub, query, lastEventID string) <-chan sseEvent {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(h.ServeEvents))
+	t.Cleanup(ts.Close)
+	req, err := http.NewRequest(http.MethodGet, ts.URL+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("event stream: got %d", res.StatusCode)
+	}
+	events := make(chan sseEvent, 100)
+	go func() {
+		defer close(events)
+		var e sseEvent
+		sc := bufio.NewScanner(res.Body)
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				e.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e.msg)
+			case line == "" && e.msg.Type != "":
+				events <- e
+				e = sseEvent{}
+			}
+		}
+	}()
+	return events
+}
+
+// nextEvent returns the next event of a stream, failing the test when none
+// comes.
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("event stream closed")
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+		return sseEvent{}
+	}
+}
+
+func TestEventsResume(t *testing.T) {
+	h := NewHub()
+	h.Broadcast(append(matchMessages(1, 2), matchMessages(2, 3)...))
+
+	// A client that saw up to 2 gets the messages of its topic since
+	events := openEvents(t, h, "?topics=match:2", "2")
+	for _, want := range []string{"3", "4", "5"} {
+		if e := nextEvent(t, events); e.id != want || e.msg.Type != LiveHoleChanged || e.msg.ID != 2 {
+			t.Fatalf("replay: got id %s %+v, want message %s of match 2", e.id, e.msg, want)
+		}
+	}
+	if e := nextEvent(t, events); e.id != "5" || e.msg.Type != LiveSubscribed {
+		t.Fatalf("after the replay: got id %s %+v, want subscribed at 5", e.id, e.msg)
+	}
+	waitClients(t, h, 1)
+	h.Broadcast(append(matchMessages(1, 1), matchMessages(2, 1)...))
+	if e := nextEvent(t, events); e.id != "7" || e.msg.ID != 2 {
+		t.Errorf("live: got id %s %+v, want message 7 of match 2", e.id, e.msg)
+	}
+}
+
+func TestEventsResync(t *testing.T) {
+	h := NewHub()
+	h.History = 3
+	h.Broadcast(matchMessages(1, 5))
+
+	// Messages 2 and 3 are gone, the client has to load the state again
+	events := openEvents(t, h, "", "1")
+	if e := nextEvent(t, events); e.id != "5" || e.msg.Type != LiveResync {
+		t.Fatalf("got id %s %+v, want a resync to 5", e.id, e.msg)
+	}
+	if e := nextEvent(t, events); e.id != "5" || e.msg.Type != LiveSubscribed {
+		t.Fatalf("after the resync: got id %s %+v, want subscribed at 5", e.id, e.msg)
+	}
+	waitClients(t, h, 1)
+	h.Broadcast(matchMessages(1, 1))
+	if e := nextEvent(t, events); e.id != "6" || e.msg.Type != LiveHoleChanged {
+		t.Errorf("live: got id %s %+v, want message 6", e.id, e.msg)
+	}
+}
+
+func TestEventsInvalidRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(NewHub().ServeEvents))
+	defer ts.Close()
+	for _, tt := range []struct{ query, lastEventID string }{
+		{"?topics=bogus", ""},
+		{"", "yesterday"},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+tt.query, nil)
+		if tt.lastEventID != "" {
+			req.Header.Set("Last-Event-ID", tt.lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("%q with Last-Event-ID %q: got %d, want 400", tt.query, tt.lastEventID, res.StatusCode)
+		}
+	}
+}
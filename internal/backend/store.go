@@ -0,0 +1,158 @@
+package backend
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by a Store when the requested row does not exist.
+var ErrNotFound = errors.New("not found")
+
+// HoleResult is the result of one hole of a match: "A", "B" or "AS", with the
+// source it came from (SourceStrokes or SourceManual).
+type HoleResult struct {
+	Hole   int    `json:"hole"`
+	Result string `json:"result"`
+	Source string `json:"source"`
+}
+
+// Store is the persistence layer of the server. Implementations return
+// ErrNotFound for missing rows; validation and archive/lock checks are done
+// by the handlers before they write.
+type Store interface {
+	// Tx runs fn atomically: the writes fn makes through tx are all kept, or
+	// all discarded when fn returns an error. Composite methods such as
+	// SetTeamPlayers are atomic on their own.
+	Tx(fn func(tx Store) error) error
+
+	// Events
+	ListEvents() ([]Event, error)
+	GetEvent(id int) (*Event, error)
+	// CurrentEventID returns the latest event that is not archived, or the
+	// latest event when all of them are archived.
+	CurrentEventID() (int, error)
+	AddEvent(e *Event) error
+	UpdateEvent(e Event) error
+	SetEventArchived(id int, archived bool) error
+
+	// Sessions, in playing order
+	ListSessions(eventID int) ([]Session, error)
+	GetSession(id int) (*Session, error)
+	AddSession(s *Session) error
+	UpdateSession(s Session) error
+	SetSessionLocked(id int, locked bool) error
+	// RemoveSession deletes a session, its matches stay in the event unscheduled.
+	RemoveSession(id int) error
+
+	// Players are shared across events, their team is the one in the given event
+	ListPlayers(eventID int) ([]Player, error)
+	AddPlayer(p *Player) error
+	UpdatePlayer(p Player) error
+	// RemovePlayer deletes a player with their team places, match places and
+	// strokes.
+	RemovePlayer(id int) error
+
+	// Teams
+	ListTeams(eventID int) ([]Team, error)
+	GetTeam(id int) (*Team, error)
+	AddTeam(t *Team) error
+	UpdateTeam(t Team) error
+	// RemoveTeam deletes a team with its player list, its captains keep their
+	// login without a team. Teams of matches are kept, see TeamInUse.
+	RemoveTeam(id int) error
+	TeamInUse(id int) (bool, error)
+	TeamPlayers(teamID int) ([]Player, error)
+	SetTeamPlayers(teamID int, playerIDs []int) error
+	AddTeamPlayer(teamID, playerID int) error
+	// SetPlayerTeam moves a player to a team of an event, nil removes them
+	// from all teams of the event.
+	SetPlayerTeam(eventID, playerID int, teamID *int) error
+
+	// Courses and tees
+	ListCourses() ([]Course, error)
+	GetCourse(id int) (*Course, error)
+	AddCourse(c *Course) error
+	UpdateCourse(c Course) error
+	RemoveCourse(id int) error
+	GetTee(id int) (*Tee, error)
+	AddTee(t *Tee) error
+	UpdateTee(t Tee) error
+	RemoveTee(id int) error
+	CourseInUse(id int) (bool, error)
+	TeeInUse(id int) (bool, error)
+
+	// Matches, ordered by start time
+	ListMatches(eventID int) ([]Match, error)
+	GetMatch(id int) (*Match, error)
+	AddMatch(m *Match) error
+	UpdateMatch(m Match) error
+	// RemoveMatch deletes a match with its roster, scores and hole results.
+	RemoveMatch(id int) error
+	// MatchPlayers returns the roster of a match, with the handicap each
+	// player had when SetMatchPlayers put them on it.
+	MatchPlayers(matchID int) ([]MatchEntrant, error)
+	SetMatchPlayers(matchID int, entrants []MatchEntrant) error
+	// PlayerMatches returns the matches a player is on the roster of.
+	PlayerMatches(playerID int) ([]Match, error)
+	// ScorerToken returns the token that allows writing a match's scores,
+	// side "", or entering and signing the card of side A or B.
+	ScorerToken(matchID int, side string) (string, error)
+	SetScorerToken(matchID int, side, token string) error
+
+	// Scores, one entry per player and hole
+	ListScores(matchID int) ([]Score, error)
+	// SetScore replaces the strokes of a player on a hole, zero clears them.
+	SetScore(s Score) error
+
+	// Hole results
+	HoleResults(matchID int) ([]HoleResult, error)
+	// SetHoleResult replaces the result of a hole, an empty result clears it.
+	SetHoleResult(matchID int, r HoleResult) error
+
+	// Users, ordered by name. Only UserByName returns the password hash.
+	ListUsers() ([]User, error)
+	GetUser(id int) (*User, error)
+	UserByName(username string) (*User, error)
+	AddUser(u *User) error
+	// UpdateUser saves the name, role and team, and the password hash when set.
+	UpdateUser(u User) error
+	// RemoveUser deletes a user with their logins and match assignments.
+	RemoveUser(id int) error
+	// UserMatches returns the matches a scorer is assigned to.
+	UserMatches(userID int) ([]int, error)
+	SetUserMatches(userID int, matchIDs []int) error
+
+	// Logins are keyed by the hash of their cookie token
+	AddLogin(tokenHash string, userID int, expires time.Time) error
+	// LoginUser returns the user of a login, ErrNotFound when it expired.
+	LoginUser(tokenHash string) (*User, error)
+	RemoveLogin(tokenHash string) error
+	// RemoveUserLogins ends the logins of a user but the one keyed by keep.
+	RemoveUserLogins(userID int, keep string) error
+
+	// Audit log, append-only
+	AddAuditEntry(e *AuditEntry) error
+	// AuditEntries returns the entries of a match, oldest first.
+	AuditEntries(matchID int) ([]AuditEntry, error)
+
+	// Match history, append-only. AddMatchEvents stores events as the next
+	// action of the match.
+	AddMatchEvents(matchID int, events []MatchEvent) error
+	// MatchEvents returns the events of a match, oldest first.
+	MatchEvents(matchID int) ([]MatchEvent, error)
+
+	// Offline sync: the outcome of every synced edit by its idempotency key.
+	// SyncOutcome returns ErrNotFound for keys not seen yet.
+	SyncOutcome(matchID int, key string) (string, error)
+	AddSyncOutcome(matchID int, key, outcome string) error
+
+	// Cards: the hole results each side's scorer entered, ordered by side
+	// and hole, and the sign-offs of the final cards, oldest first.
+	SideResults(matchID int) ([]SideResult, error)
+	// SetSideResult replaces a side's result of a hole, an empty result clears it.
+	SetSideResult(matchID int, r SideResult) error
+	CardSignatures(matchID int) ([]CardSignature, error)
+	AddCardSignature(sig *CardSignature) error
+	// ClearCardSignatures removes the signatures of a match's card.
+	ClearCardSignatures(matchID int) error
+}
@@ -0,0 +1,981 @@
+package backend
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store kept in memory, for tests and throwaway servers.
+// Everything returned is a copy, callers cannot change the stored rows.
+type MemoryStore struct {
+	mu   *sync.Mutex
+	inTx bool // mu is already held by Tx
+	*memTables
+}
+
+// memTables is the data of a MemoryStore.
+type memTables struct {
+	nextID      map[string]int // per table, like AUTOINCREMENT
+	events      map[int]Event
+	sessions    map[int]Session
+	players     map[int]Player
+	teams       map[int]Team
+	teamPlayers map[int][]int // team id -> player ids
+	courses     map[int]Course
+	tees        map[int]Tee
+	matches     map[int]Match
+	roster      map[int][]MatchEntrant // match id -> entrants, player fields are looked up on read
+	scores      map[int][]Score        // match id -> scores
+	holes       map[int]map[int]HoleResult
+	tokens      map[tokenKey]string // scorer tokens of the matches and their sides
+	users       map[int]User        // with password hashes
+	userMatches map[int][]int       // user id -> match ids they score
+	logins      map[string]memLogin
+	audit       []AuditEntry
+	history     []MatchEvent
+	synced      map[syncKey]string   // outcomes of synced edits
+	sideResults map[int][]SideResult // match id -> the results the sides entered
+	signatures  []CardSignature
+}
+
+// tokenKey identifies a scorer token of a MemoryStore: the match's card, side "",
+// or the card of one side.
+type tokenKey struct {
+	matchID int
+	side    string
+}
+
+// syncKey identifies a synced edit of a MemoryStore.
+type syncKey struct {
+	matchID int
+	key     string
+}
+
+// memLogin is a login of a MemoryStore, keyed by its token hash.
+type memLogin struct {
+	userID  int
+	expires time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty MemoryStore with one event, like a freshly
+// migrated database.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{mu: &sync.Mutex{}, memTables: &memTables{
+		nextID:      map[string]int{},
+		events:      map[int]Event{},
+		sessions:    map[int]Session{},
+		players:     map[int]Player{},
+		teams:       map[int]Team{},
+		teamPlayers: map[int][]int{},
+		courses:     map[int]Course{},
+		tees:        map[int]Tee{},
+		matches:     map[int]Match{},
+		roster:      map[int][]MatchEntrant{},
+		scores:      map[int][]Score{},
+		holes:       map[int]map[int]HoleResult{},
+		tokens:      map[tokenKey]string{},
+		users:       map[int]User{},
+		userMatches: map[int][]int{},
+		logins:      map[string]memLogin{},
+		synced:      map[syncKey]string{},
+		sideResults: map[int][]SideResult{},
+	}}
+	_ = s.AddEvent(&Event{Name: "Ryder Cup"})
+	return s
+}
+
+// lock takes the store lock and returns its unlock. Inside Tx the lock is held
+// for the whole transaction already.
+func (s *MemoryStore) lock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// Tx holds the store lock while fn runs and puts the tables back as they were
+// when fn fails.
+func (s *MemoryStore) Tx(fn func(tx Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+	defer s.lock()()
+	saved := s.memTables.clone()
+	if err := fn(&MemoryStore{mu: s.mu, inTx: true, memTables: s.memTables}); err != nil {
+		*s.memTables = *saved
+		return err
+	}
+	return nil
+}
+
+// clone copies the tables deep enough that changes to t don't show in the copy.
+func (t *memTables) clone() *memTables {
+	c := *t
+	c.nextID = maps.Clone(t.nextID)
+	c.events = maps.Clone(t.events)
+	c.sessions = maps.Clone(t.sessions)
+	c.players = maps.Clone(t.players)
+	c.teams = maps.Clone(t.teams)
+	c.teamPlayers = cloneLists(t.teamPlayers)
+	c.courses = maps.Clone(t.courses)
+	c.tees = maps.Clone(t.tees)
+	c.matches = maps.Clone(t.matches)
+	c.roster = cloneLists(t.roster)
+	c.scores = cloneLists(t.scores)
+	c.tokens = maps.Clone(t.tokens)
+	c.users = maps.Clone(t.users)
+	c.userMatches = cloneLists(t.userMatches)
+	c.logins = maps.Clone(t.logins)
+	c.audit = slices.Clone(t.audit)
+	c.history = slices.Clone(t.history)
+	c.synced = maps.Clone(t.synced)
+	c.sideResults = cloneLists(t.sideResults)
+	c.signatures = slices.Clone(t.signatures)
+	c.holes = map[int]map[int]HoleResult{}
+	for id, holes := range t.holes {
+		c.holes[id] = maps.Clone(holes)
+	}
+	return &c
+}
+
+func cloneLists[T any](m map[int][]T) map[int][]T {
+	c := make(map[int][]T, len(m))
+	for k, v := range m {
+		c[k] = slices.Clone(v)
+	}
+	return c
+}
+
+func (s *MemoryStore) id(table string) int {
+	s.nextID[table]++
+	return s.nextID[table]
+}
+
+// sortedValues returns the values of a map ordered by the given comparison.
+func sortedValues[T any](m map[int]T, less func(a, b T) int) []T {
+	values := make([]T, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	slices.SortFunc(values, less)
+	return values
+}
+
+// --- Events ---
+func (s *MemoryStore) ListEvents() ([]Event, error) {
+	defer s.lock()()
+	return sortedValues(s.events, func(a, b Event) int { return cmp.Compare(b.ID, a.ID) }), nil
+}
+
+func (s *MemoryStore) GetEvent(id int) (*Event, error) {
+	defer s.lock()()
+	e, ok := s.events[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &e, nil
+}
+
+func (s *MemoryStore) CurrentEventID() (int, error) {
+	defer s.lock()()
+	current, found := Event{}, false
+	for _, e := range s.events {
+		if !found || (current.Archived && !e.Archived) || (current.Archived == e.Archived && e.ID > current.ID) {
+			current, found = e, true
+		}
+	}
+	if !found {
+		return 0, ErrNotFound
+	}
+	return current.ID, nil
+}
+
+func (s *MemoryStore) AddEvent(e *Event) error {
+	defer s.lock()()
+	e.ID = s.id("events")
+	s.events[e.ID] = *e
+	return nil
+}
+
+func (s *MemoryStore) UpdateEvent(e Event) error {
+	defer s.lock()()
+	old, ok := s.events[e.ID]
+	if !ok {
+		return nil
+	}
+	old.Name, old.StartDate = e.Name, e.StartDate
+	s.events[e.ID] = old
+	return nil
+}
+
+func (s *MemoryStore) SetEventArchived(id int, archived bool) error {
+	defer s.lock()()
+	if e, ok := s.events[id]; ok {
+		e.Archived = archived
+		s.events[id] = e
+	}
+	return nil
+}
+
+// --- Sessions ---
+func (s *MemoryStore) ListSessions(eventID int) ([]Session, error) {
+	defer s.lock()()
+	sessions := []Session{}
+	for _, ss := range sortedValues(s.sessions, func(a, b Session) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), cmp.Compare(a.Date, b.Date), cmp.Compare(a.ID, b.ID))
+	}) {
+		if ss.EventID == eventID {
+			sessions = append(sessions, ss)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *MemoryStore) GetSession(id int) (*Session, error) {
+	defer s.lock()()
+	ss, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &ss, nil
+}
+
+func (s *MemoryStore) AddSession(ss *Session) error {
+	defer s.lock()()
+	ss.ID = s.id("sessions")
+	s.sessions[ss.ID] = *ss
+	return nil
+}
+
+func (s *MemoryStore) UpdateSession(ss Session) error {
+	defer s.lock()()
+	old, ok := s.sessions[ss.ID]
+	if !ok {
+		return nil
+	}
+	old.Name, old.Date, old.Format, old.Position, old.Points = ss.Name, ss.Date, ss.Format, ss.Position, ss.Points
+	s.sessions[ss.ID] = old
+	return nil
+}
+
+func (s *MemoryStore) SetSessionLocked(id int, locked bool) error {
+	defer s.lock()()
+	if ss, ok := s.sessions[id]; ok {
+		ss.Locked = locked
+		s.sessions[id] = ss
+	}
+	return nil
+}
+
+func (s *MemoryStore) RemoveSession(id int) error {
+	defer s.lock()()
+	for mid, m := range s.matches {
+		if m.SessionID != nil && *m.SessionID == id {
+			m.SessionID = nil
+			s.matches[mid] = m
+		}
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+// --- Players ---
+func (s *MemoryStore) ListPlayers(eventID int) ([]Player, error) {
+	defer s.lock()()
+	players := sortedValues(s.players, func(a, b Player) int {
+		// Players without a handicap sort first, as NULLs do in SQLite
+		ha, hb := -1e9, -1e9
+		if a.HCP != nil {
+			ha = *a.HCP
+		}
+		if b.HCP != nil {
+			hb = *b.HCP
+		}
+		return cmp.Or(cmp.Compare(ha, hb), cmp.Compare(a.Name, b.Name))
+	})
+	for i := range players {
+		for tid, pids := range s.teamPlayers {
+			if t := s.teams[tid]; t.EventID == eventID && slices.Contains(pids, players[i].ID) {
+				id := tid
+				players[i].TeamID = &id
+				players[i].TeamName = t.Name
+			}
+		}
+	}
+	return players, nil
+}
+
+func (s *MemoryStore) AddPlayer(p *Player) error {
+	defer s.lock()()
+	p.ID = s.id("players")
+	s.players[p.ID] = Player{ID: p.ID, Name: p.Name, Email: p.Email, HCP: p.HCP}
+	return nil
+}
+
+func (s *MemoryStore) UpdatePlayer(p Player) error {
+	defer s.lock()()
+	if _, ok := s.players[p.ID]; ok {
+		s.players[p.ID] = Player{ID: p.ID, Name: p.Name, Email: p.Email, HCP: p.HCP}
+	}
+	return nil
+}
+
+func (s *MemoryStore) RemovePlayer(id int) error {
+	defer s.lock()()
+	for tid, pids := range s.teamPlayers {
+		s.teamPlayers[tid] = slices.DeleteFunc(pids, func(pid int) bool { return pid == id })
+	}
+	for mid, entrants := range s.roster {
+		s.roster[mid] = slices.DeleteFunc(entrants, func(e MatchEntrant) bool { return e.ID == id })
+	}
+	for mid, scores := range s.scores {
+		s.scores[mid] = slices.DeleteFunc(scores, func(sc Score) bool { return sc.PlayerID == id })
+	}
+	delete(s.players, id)
+	return nil
+}
+
+// --- Teams ---
+func (s *MemoryStore) ListTeams(eventID int) ([]Team, error) {
+	defer s.lock()()
+	var teams []Team
+	for _, t := range sortedValues(s.teams, func(a, b Team) int { return cmp.Compare(a.ID, b.ID) }) {
+		if t.EventID == eventID {
+			teams = append(teams, t)
+		}
+	}
+	return teams, nil
+}
+
+func (s *MemoryStore) GetTeam(id int) (*Team, error) {
+	defer s.lock()()
+	t, ok := s.teams[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &t, nil
+}
+
+func (s *MemoryStore) AddTeam(t *Team) error {
+	defer s.lock()()
+	t.ID = s.id("teams")
+	s.teams[t.ID] = Team{ID: t.ID, EventID: t.EventID, Name: t.Name, Color: t.Color}
+	return nil
+}
+
+func (s *MemoryStore) UpdateTeam(t Team) error {
+	defer s.lock()()
+	if old, ok := s.teams[t.ID]; ok {
+		old.Name, old.Color = t.Name, t.Color
+		s.teams[t.ID] = old
+	}
+	return nil
+}
+
+func (s *MemoryStore) RemoveTeam(id int) error {
+	defer s.lock()()
+	for uid, u := range s.users {
+		if u.TeamID != nil && *u.TeamID == id {
+			u.TeamID = nil
+			s.users[uid] = u
+		}
+	}
+	delete(s.teamPlayers, id)
+	delete(s.teams, id)
+	return nil
+}
+
+func (s *MemoryStore) TeamInUse(id int) (bool, error) {
+	defer s.lock()()
+	for _, m := range s.matches {
+		if m.TeamA == id || m.TeamB == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryStore) TeamPlayers(teamID int) ([]Player, error) {
+	defer s.lock()()
+	var players []Player
+	for _, pid := range s.teamPlayers[teamID] {
+		if p, ok := s.players[pid]; ok {
+			players = append(players, p)
+		}
+	}
+	slices.SortFunc(players, func(a, b Player) int { return cmp.Compare(a.Name, b.Name) })
+	return players, nil
+}
+
+func (s *MemoryStore) SetTeamPlayers(teamID int, playerIDs []int) error {
+	defer s.lock()()
+	s.teamPlayers[teamID] = nil
+	for _, pid := range playerIDs {
+		s.addTeamPlayer(teamID, pid)
+	}
+	return nil
+}
+
+func (s *MemoryStore) AddTeamPlayer(teamID, playerID int) error {
+	defer s.lock()()
+	s.addTeamPlayer(teamID, playerID)
+	return nil
+}
+
+func (s *MemoryStore) addTeamPlayer(teamID, playerID int) {
+	if !slices.Contains(s.teamPlayers[teamID], playerID) {
+		s.teamPlayers[teamID] = append(s.teamPlayers[teamID], playerID)
+	}
+}
+
+func (s *MemoryStore) SetPlayerTeam(eventID, playerID int, teamID *int) error {
+	defer s.lock()()
+	for tid, pids := range s.teamPlayers {
+		if s.teams[tid].EventID == eventID {
+			s.teamPlayers[tid] = slices.DeleteFunc(pids, func(pid int) bool { return pid == playerID })
+		}
+	}
+	if teamID != nil {
+		s.addTeamPlayer(*teamID, playerID)
+	}
+	return nil
+}
+
+// --- Courses ---
+func (s *MemoryStore) ListCourses() ([]Course, error) {
+	defer s.lock()()
+	courses := []Course{}
+	for _, c := range sortedValues(s.courses, func(a, b Course) int { return cmp.Compare(a.Name, b.Name) }) {
+		courses = append(courses, *s.course(c.ID))
+	}
+	return courses, nil
+}
+
+// course returns a copy of a course with its tees, the lock must be held.
+func (s *MemoryStore) course(id int) *Course {
+	c, ok := s.courses[id]
+	if !ok {
+		return nil
+	}
+	c.Holes = slices.Clone(c.Holes)
+	c.Tees = []Tee{}
+	for _, t := range sortedValues(s.tees, func(a, b Tee) int { return cmp.Compare(a.ID, b.ID) }) {
+		if t.CourseID == id {
+			t.Yards = slices.Clone(t.Yards)
+			c.Tees = append(c.Tees, t)
+		}
+	}
+	return &c
+}
+
+func (s *MemoryStore) GetCourse(id int) (*Course, error) {
+	defer s.lock()()
+	c := s.course(id)
+	if c == nil {
+		return nil, ErrNotFound
+	}
+	return c, nil
+}
+
+func (s *MemoryStore) AddCourse(c *Course) error {
+	defer s.lock()()
+	c.ID = s.id("courses")
+	s.courses[c.ID] = Course{ID: c.ID, Name: c.Name, Holes: slices.Clone(c.Holes)}
+	return nil
+}
+
+func (s *MemoryStore) UpdateCourse(c Course) error {
+	defer s.lock()()
+	if _, ok := s.courses[c.ID]; ok {
+		s.courses[c.ID] = Course{ID: c.ID, Name: c.Name, Holes: slices.Clone(c.Holes)}
+	}
+	return nil
+}
+
+func (s *MemoryStore) RemoveCourse(id int) error {
+	defer s.lock()()
+	for tid, t := range s.tees {
+		if t.CourseID == id {
+			delete(s.tees, tid)
+		}
+	}
+	delete(s.courses, id)
+	return nil
+}
+
+func (s *MemoryStore) GetTee(id int) (*Tee, error) {
+	defer s.lock()()
+	t, ok := s.tees[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	t.Yards = slices.Clone(t.Yards)
+	return &t, nil
+}
+
+// teeYards keeps the positive yardages of the first 18 holes, like tee_holes.
+func teeYards(yards []int) []int {
+	var kept []int
+	for i, y := range yards {
+		if i >= 18 || y <= 0 {
+			continue
+		}
+		if kept == nil {
+			kept = make([]int, 18)
+		}
+		kept[i] = y
+	}
+	return kept
+}
+
+func (s *MemoryStore) AddTee(t *Tee) error {
+	defer s.lock()()
+	t.ID = s.id("tees")
+	stored := *t
+	stored.Yards = teeYards(t.Yards)
+	s.tees[t.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) UpdateTee(t Tee) error {
+	defer s.lock()()
+	old, ok := s.tees[t.ID]
+	if !ok {
+		return nil
+	}
+	old.Name, old.Color, old.CourseRating, old.Slope = t.Name, t.Color, t.CourseRating, t.Slope
+	old.Yards = teeYards(t.Yards)
+	s.tees[t.ID] = old
+	return nil
+}
+
+func (s *MemoryStore) RemoveTee(id int) error {
+	defer s.lock()()
+	delete(s.tees, id)
+	return nil
+}
+
+func (s *MemoryStore) CourseInUse(id int) (bool, error) {
+	defer s.lock()()
+	for _, m := range s.matches {
+		if m.CourseID != nil && *m.CourseID == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryStore) TeeInUse(id int) (bool, error) {
+	defer s.lock()()
+	for _, m := range s.matches {
+		if m.TeeID != nil && *m.TeeID == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// --- Matches ---
+func compareMatches(a, b Match) int {
+	return cmp.Or(cmp.Compare(a.StartTime, b.StartTime), cmp.Compare(a.ID, b.ID))
+}
+
+func (s *MemoryStore) ListMatches(eventID int) ([]Match, error) {
+	defer s.lock()()
+	matches := []Match{}
+	for _, m := range sortedValues(s.matches, compareMatches) {
+		if m.EventID == eventID {
+			matches = append(matches, m)
+		}
+	}
+	return matches, nil
+}
+
+func (s *MemoryStore) GetMatch(id int) (*Match, error) {
+	defer s.lock()()
+	m, ok := s.matches[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &m, nil
+}
+
+func (s *MemoryStore) AddMatch(m *Match) error {
+	defer s.lock()()
+	m.ID = s.id("matches")
+	s.matches[m.ID] = *m
+	return nil
+}
+
+func (s *MemoryStore) UpdateMatch(m Match) error {
+	defer s.lock()()
+	old, ok := s.matches[m.ID]
+	if !ok {
+		return nil
+	}
+	m.EventID = old.EventID
+	s.matches[m.ID] = m
+	return nil
+}
+
+func (s *MemoryStore) RemoveMatch(id int) error {
+	defer s.lock()()
+	delete(s.holes, id)
+	delete(s.sideResults, id)
+	for _, side := range []string{"", "A", "B"} {
+		delete(s.tokens, tokenKey{id, side})
+	}
+	for uid, ids := range s.userMatches {
+		s.userMatches[uid] = slices.DeleteFunc(ids, func(mid int) bool { return mid == id })
+	}
+	delete(s.scores, id)
+	delete(s.roster, id)
+	delete(s.matches, id)
+	return nil
+}
+
+func (s *MemoryStore) MatchPlayers(matchID int) ([]MatchEntrant, error) {
+	defer s.lock()()
+	var entrants []MatchEntrant
+	for _, side := range []string{"A", "B"} {
+		for _, e := range s.roster[matchID] {
+			p, ok := s.players[e.ID]
+			if e.Side != side || !ok {
+				continue
+			}
+			e.Name = p.Name
+			entrants = append(entrants, e)
+		}
+	}
+	return entrants, nil
+}
+
+func (s *MemoryStore) SetMatchPlayers(matchID int, entrants []MatchEntrant) error {
+	defer s.lock()()
+	roster := make([]MatchEntrant, 0, len(entrants))
+	for _, e := range entrants {
+		var hcp float64
+		if p := s.players[e.ID]; p.HCP != nil {
+			hcp = *p.HCP
+		}
+		roster = append(roster, MatchEntrant{ID: e.ID, Side: e.Side, HCP: hcp})
+	}
+	s.roster[matchID] = roster
+	return nil
+}
+
+func (s *MemoryStore) PlayerMatches(playerID int) ([]Match, error) {
+	defer s.lock()()
+	matches := []Match{}
+	for _, m := range sortedValues(s.matches, func(a, b Match) int { return cmp.Compare(a.ID, b.ID) }) {
+		if slices.ContainsFunc(s.roster[m.ID], func(e MatchEntrant) bool { return e.ID == playerID }) {
+			matches = append(matches, m)
+		}
+	}
+	return matches, nil
+}
+
+func (s *MemoryStore) ScorerToken(matchID int, side string) (string, error) {
+	defer s.lock()()
+	if _, ok := s.matches[matchID]; !ok {
+		return "", ErrNotFound
+	}
+	return s.tokens[tokenKey{matchID, side}], nil
+}
+
+func (s *MemoryStore) SetScorerToken(matchID int, side, token string) error {
+	defer s.lock()()
+	if _, ok := s.matches[matchID]; ok {
+		s.tokens[tokenKey{matchID, side}] = token
+	}
+	return nil
+}
+
+// --- Scores ---
+func (s *MemoryStore) ListScores(matchID int) ([]Score, error) {
+	defer s.lock()()
+	scores := slices.Clone(s.scores[matchID])
+	slices.SortFunc(scores, func(a, b Score) int {
+		return cmp.Or(cmp.Compare(a.Hole, b.Hole), cmp.Compare(a.PlayerID, b.PlayerID))
+	})
+	if scores == nil {
+		scores = []Score{}
+	}
+	return scores, nil
+}
+
+func (s *MemoryStore) SetScore(sc Score) error {
+	defer s.lock()()
+	scores := slices.DeleteFunc(s.scores[sc.MatchID], func(o Score) bool {
+		return o.PlayerID == sc.PlayerID && o.Hole == sc.Hole
+	})
+	if sc.Strokes > 0 {
+		sc.ID = s.id("scores")
+		scores = append(scores, sc)
+	}
+	s.scores[sc.MatchID] = scores
+	return nil
+}
+
+// --- Hole Results ---
+func (s *MemoryStore) HoleResults(matchID int) ([]HoleResult, error) {
+	defer s.lock()()
+	var results []HoleResult
+	for _, r := range sortedValues(s.holes[matchID], func(a, b HoleResult) int { return cmp.Compare(a.Hole, b.Hole) }) {
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func (s *MemoryStore) SetHoleResult(matchID int, r HoleResult) error {
+	defer s.lock()()
+	if r.Result == "" {
+		delete(s.holes[matchID], r.Hole)
+		return nil
+	}
+	if s.holes[matchID] == nil {
+		s.holes[matchID] = map[int]HoleResult{}
+	}
+	s.holes[matchID][r.Hole] = r
+	return nil
+}
+
+// --- Users ---
+func (s *MemoryStore) ListUsers() ([]User, error) {
+	defer s.lock()()
+	users := sortedValues(s.users, func(a, b User) int { return cmp.Compare(a.Username, b.Username) })
+	for i := range users {
+		users[i].PasswordHash = ""
+	}
+	return users, nil
+}
+
+func (s *MemoryStore) GetUser(id int) (*User, error) {
+	defer s.lock()()
+	return s.user(id)
+}
+
+// user returns a user without the password hash.
+func (s *MemoryStore) user(id int) (*User, error) {
+	u, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	u.PasswordHash = ""
+	return &u, nil
+}
+
+func (s *MemoryStore) UserByName(username string) (*User, error) {
+	defer s.lock()()
+	for _, u := range s.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) AddUser(u *User) error {
+	defer s.lock()()
+	u.ID = s.id("users")
+	s.users[u.ID] = User{ID: u.ID, Username: u.Username, Role: u.Role, TeamID: u.TeamID, PasswordHash: u.PasswordHash}
+	return nil
+}
+
+func (s *MemoryStore) UpdateUser(u User) error {
+	defer s.lock()()
+	old, ok := s.users[u.ID]
+	if !ok {
+		return nil
+	}
+	old.Username, old.Role, old.TeamID = u.Username, u.Role, u.TeamID
+	if u.PasswordHash != "" {
+		old.PasswordHash = u.PasswordHash
+	}
+	s.users[u.ID] = old
+	return nil
+}
+
+func (s *MemoryStore) RemoveUser(id int) error {
+	defer s.lock()()
+	for hash, l := range s.logins {
+		if l.userID == id {
+			delete(s.logins, hash)
+		}
+	}
+	delete(s.userMatches, id)
+	delete(s.users, id)
+	return nil
+}
+
+func (s *MemoryStore) UserMatches(userID int) ([]int, error) {
+	defer s.lock()()
+	ids := slices.Clone(s.userMatches[userID])
+	slices.Sort(ids)
+	if ids == nil {
+		ids = []int{}
+	}
+	return ids, nil
+}
+
+func (s *MemoryStore) SetUserMatches(userID int, matchIDs []int) error {
+	defer s.lock()()
+	var ids []int
+	for _, id := range matchIDs {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	s.userMatches[userID] = ids
+	return nil
+}
+
+func (s *MemoryStore) AddLogin(tokenHash string, userID int, expires time.Time) error {
+	defer s.lock()()
+	now := time.Now()
+	for hash, l := range s.logins {
+		if l.expires.Before(now) {
+			delete(s.logins, hash)
+		}
+	}
+	s.logins[tokenHash] = memLogin{userID: userID, expires: expires}
+	return nil
+}
+
+func (s *MemoryStore) LoginUser(tokenHash string) (*User, error) {
+	defer s.lock()()
+	l, ok := s.logins[tokenHash]
+	if !ok || l.expires.Before(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return s.user(l.userID)
+}
+
+func (s *MemoryStore) RemoveLogin(tokenHash string) error {
+	defer s.lock()()
+	delete(s.logins, tokenHash)
+	return nil
+}
+
+func (s *MemoryStore) RemoveUserLogins(userID int, keep string) error {
+	defer s.lock()()
+	for hash, l := range s.logins {
+		if l.userID == userID && hash != keep {
+			delete(s.logins, hash)
+		}
+	}
+	return nil
+}
+
+// --- Audit log ---
+func (s *MemoryStore) AddAuditEntry(e *AuditEntry) error {
+	defer s.lock()()
+	e.ID = s.id("audit_log")
+	s.audit = append(s.audit, *e)
+	return nil
+}
+
+func (s *MemoryStore) AuditEntries(matchID int) ([]AuditEntry, error) {
+	defer s.lock()()
+	entries := []AuditEntry{}
+	for _, e := range s.audit {
+		if e.MatchID == matchID {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// --- Match history ---
+func (s *MemoryStore) AddMatchEvents(matchID int, events []MatchEvent) error {
+	defer s.lock()()
+	action := 0
+	for _, e := range s.history {
+		if e.MatchID == matchID {
+			action = max(action, e.Action)
+		}
+	}
+	for i := range events {
+		events[i].ID, events[i].MatchID, events[i].Action = s.id("match_events"), matchID, action+1
+		s.history = append(s.history, events[i])
+	}
+	return nil
+}
+
+func (s *MemoryStore) MatchEvents(matchID int) ([]MatchEvent, error) {
+	defer s.lock()()
+	events := []MatchEvent{}
+	for _, e := range s.history {
+		if e.MatchID == matchID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// --- Offline sync ---
+func (s *MemoryStore) SyncOutcome(matchID int, key string) (string, error) {
+	defer s.lock()()
+	outcome, ok := s.synced[syncKey{matchID, key}]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return outcome, nil
+}
+
+func (s *MemoryStore) AddSyncOutcome(matchID int, key, outcome string) error {
+	defer s.lock()()
+	s.synced[syncKey{matchID, key}] = outcome
+	return nil
+}
+
+// --- Cards ---
+func (s *MemoryStore) SideResults(matchID int) ([]SideResult, error) {
+	defer s.lock()()
+	results := slices.Clone(s.sideResults[matchID])
+	slices.SortFunc(results, func(a, b SideResult) int { return cmp.Or(cmp.Compare(a.Side, b.Side), cmp.Compare(a.Hole, b.Hole)) })
+	return results, nil
+}
+
+func (s *MemoryStore) SetSideResult(matchID int, r SideResult) error {
+	defer s.lock()()
+	results := slices.DeleteFunc(s.sideResults[matchID], func(e SideResult) bool { return e.Side == r.Side && e.Hole == r.Hole })
+	if r.Result != "" {
+		results = append(results, r)
+	}
+	s.sideResults[matchID] = results
+	return nil
+}
+
+func (s *MemoryStore) CardSignatures(matchID int) ([]CardSignature, error) {
+	defer s.lock()()
+	sigs := []CardSignature{}
+	for _, sig := range s.signatures {
+		if sig.MatchID == matchID {
+			sig.Holes = slices.Clone(sig.Holes)
+			sigs = append(sigs, sig)
+		}
+	}
+	return sigs, nil
+}
+
+func (s *MemoryStore) AddCardSignature(sig *CardSignature) error {
+	defer s.lock()()
+	sig.ID = s.id("card_signatures")
+	stored := *sig
+	stored.Holes = slices.Clone(sig.Holes)
+	s.signatures = append(s.signatures, stored)
+	return nil
+}
+
+func (s *MemoryStore) ClearCardSignatures(matchID int) error {
+	defer s.lock()()
+	s.signatures = slices.DeleteFunc(s.signatures, func(sig CardSignature) bool { return sig.MatchID == matchID })
+	return nil
+}
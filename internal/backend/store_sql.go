@@ -0,0 +1,1132 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dialect holds what differs between the databases a SQLStore runs on.
+type dialect struct {
+	// numbered placeholders ($1, $2, ...) instead of ?
+	numbered bool
+	// returning reads new ids with RETURNING id instead of LastInsertId
+	returning bool
+	// rowOrder is the match_players column that keeps the insertion order
+	rowOrder string
+}
+
+var (
+	sqliteDialect   = dialect{rowOrder: "rowid"}
+	postgresDialect = dialect{numbered: true, returning: true, rowOrder: "seq"}
+)
+
+// SQLStore is the Store backed by a SQL database, SQLite or PostgreSQL. Queries
+// are written with ? placeholders and rebound for the dialect.
+type SQLStore struct {
+	db      *sql.DB
+	q       querier // db, or the transaction of a store made by atomic
+	inTx    bool
+	dialect dialect
+}
+
+// querier is a *sql.DB or a *sql.Tx.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+var _ Store = (*SQLStore)(nil)
+
+// NewSQLiteStore returns a Store on an open, migrated SQLite database.
+func NewSQLiteStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, q: db, dialect: sqliteDialect}
+}
+
+// NewPostgresStore returns a Store on an open, migrated PostgreSQL database.
+func NewPostgresStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, q: db, dialect: postgresDialect}
+}
+
+// rebind rewrites ? placeholders to $n when the dialect numbers them.
+func (s *SQLStore) rebind(query string) string {
+	if !s.dialect.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *SQLStore) exec(query string, args ...any) (sql.Result, error) {
+	return s.q.Exec(s.rebind(query), args...)
+}
+
+func (s *SQLStore) query(query string, args ...any) (*sql.Rows, error) {
+	return s.q.Query(s.rebind(query), args...)
+}
+
+func (s *SQLStore) queryRow(query string, args ...any) *sql.Row {
+	return s.q.QueryRow(s.rebind(query), args...)
+}
+
+// insert runs an INSERT into a table with an id column and returns the new id.
+func (s *SQLStore) insert(query string, args ...any) (int, error) {
+	if s.dialect.returning {
+		var id int
+		err := s.queryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	res, err := s.exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// atomic runs fn on a store bound to a transaction, committed when fn
+// succeeds. Inside a transaction fn simply joins it.
+func (s *SQLStore) atomic(fn func(s *SQLStore) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(&SQLStore{db: s.db, q: tx, inTx: true, dialect: s.dialect}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) Tx(fn func(tx Store) error) error {
+	return s.atomic(func(s *SQLStore) error { return fn(s) })
+}
+
+// notFound maps sql.ErrNoRows to ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// nullInt converts a nullable integer column to a pointer.
+func nullInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
+
+// nullFloat converts a nullable real column to a pointer.
+func nullFloat(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	f := v.Float64
+	return &f
+}
+
+// --- Events ---
+func (s *SQLStore) ListEvents() ([]Event, error) {
+	rows, err := s.query("SELECT id, name, COALESCE(start_date, ''), archived FROM events ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Name, &e.StartDate, &e.Archived); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (s *SQLStore) GetEvent(id int) (*Event, error) {
+	e := &Event{}
+	err := s.queryRow("SELECT id, name, COALESCE(start_date, ''), archived FROM events WHERE id=?", id).Scan(&e.ID, &e.Name, &e.StartDate, &e.Archived)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return e, nil
+}
+
+func (s *SQLStore) CurrentEventID() (int, error) {
+	var id int
+	err := s.queryRow("SELECT id FROM events ORDER BY archived ASC, id DESC LIMIT 1").Scan(&id)
+	return id, notFound(err)
+}
+
+func (s *SQLStore) AddEvent(e *Event) error {
+	id, err := s.insert("INSERT INTO events (name, start_date, archived) VALUES (?, ?, ?)", e.Name, e.StartDate, e.Archived)
+	if err != nil {
+		return err
+	}
+	e.ID = id
+	return nil
+}
+
+func (s *SQLStore) UpdateEvent(e Event) error {
+	_, err := s.exec("UPDATE events SET name=?, start_date=? WHERE id=?", e.Name, e.StartDate, e.ID)
+	return err
+}
+
+func (s *SQLStore) SetEventArchived(id int, archived bool) error {
+	_, err := s.exec("UPDATE events SET archived=? WHERE id=?", archived, id)
+	return err
+}
+
+// --- Sessions ---
+func (s *SQLStore) ListSessions(eventID int) ([]Session, error) {
+	rows, err := s.query(`SELECT id, event_id, name, COALESCE(date, ''), COALESCE(format, ''), position, points, locked
+		FROM sessions WHERE event_id=? ORDER BY position, date, id`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sessions := []Session{}
+	for rows.Next() {
+		ss, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *ss)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *SQLStore) GetSession(id int) (*Session, error) {
+	row := s.queryRow(`SELECT id, event_id, name, COALESCE(date, ''), COALESCE(format, ''), position, points, locked
+		FROM sessions WHERE id=?`, id)
+	ss, err := scanSession(row)
+	return ss, notFound(err)
+}
+
+func scanSession(row interface{ Scan(...any) error }) (*Session, error) {
+	var ss Session
+	var format string
+	var points sql.NullFloat64
+	if err := row.Scan(&ss.ID, &ss.EventID, &ss.Name, &ss.Date, &format, &ss.Position, &points, &ss.Locked); err != nil {
+		return nil, err
+	}
+	ss.Format = MatchFormat(format)
+	ss.Points = nullFloat(points)
+	return &ss, nil
+}
+
+func (s *SQLStore) AddSession(ss *Session) error {
+	id, err := s.insert("INSERT INTO sessions (event_id, name, date, format, position, points, locked) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		ss.EventID, ss.Name, ss.Date, ss.Format, ss.Position, ss.Points, ss.Locked)
+	if err != nil {
+		return err
+	}
+	ss.ID = id
+	return nil
+}
+
+func (s *SQLStore) UpdateSession(ss Session) error {
+	_, err := s.exec("UPDATE sessions SET name=?, date=?, format=?, position=?, points=? WHERE id=?", ss.Name, ss.Date, ss.Format, ss.Position, ss.Points, ss.ID)
+	return err
+}
+
+func (s *SQLStore) SetSessionLocked(id int, locked bool) error {
+	_, err := s.exec("UPDATE sessions SET locked=? WHERE id=?", locked, id)
+	return err
+}
+
+func (s *SQLStore) RemoveSession(id int) error {
+	return s.atomic(func(s *SQLStore) error {
+		if _, err := s.exec("UPDATE matches SET session_id=NULL WHERE session_id=?", id); err != nil {
+			return err
+		}
+		_, err := s.exec("DELETE FROM sessions WHERE id=?", id)
+		return err
+	})
+}
+
+// --- Players ---
+func (s *SQLStore) ListPlayers(eventID int) ([]Player, error) {
+	rows, err := s.query(`SELECT p.id, p.name, p.email, p.hcp, t.id, t.name
+		FROM players p
+		LEFT JOIN (SELECT tp.player_id, t.id, t.name FROM team_players tp JOIN teams t ON tp.team_id = t.id WHERE t.event_id = ?) t ON p.id = t.player_id
+		ORDER BY p.hcp ASC NULLS FIRST, p.name ASC`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var players []Player
+	for rows.Next() {
+		var p Player
+		var hcp sql.NullFloat64
+		var teamID sql.NullInt64
+		var teamName sql.NullString
+		if err := rows.Scan(&p.ID, &p.Name, &p.Email, &hcp, &teamID, &teamName); err != nil {
+			return nil, err
+		}
+		p.HCP = nullFloat(hcp)
+		p.TeamID = nullInt(teamID)
+		p.TeamName = teamName.String
+		players = append(players, p)
+	}
+	return players, rows.Err()
+}
+
+func (s *SQLStore) AddPlayer(p *Player) error {
+	id, err := s.insert("INSERT INTO players (name, email, hcp) VALUES (?, ?, ?)", p.Name, p.Email, p.HCP)
+	if err != nil {
+		return err
+	}
+	p.ID = id
+	return nil
+}
+
+func (s *SQLStore) UpdatePlayer(p Player) error {
+	_, err := s.exec("UPDATE players SET name=?, email=?, hcp=? WHERE id=?", p.Name, p.Email, p.HCP, p.ID)
+	return err
+}
+
+func (s *SQLStore) RemovePlayer(id int) error {
+	return s.atomic(func(s *SQLStore) error {
+		for _, stmt := range []string{
+			"DELETE FROM scores WHERE player_id=?",
+			"DELETE FROM match_players WHERE player_id=?",
+			"DELETE FROM team_players WHERE player_id=?",
+			"DELETE FROM players WHERE id=?",
+		} {
+			if _, err := s.exec(stmt, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// --- Teams ---
+func (s *SQLStore) ListTeams(eventID int) ([]Team, error) {
+	rows, err := s.query("SELECT id, event_id, name, color FROM teams WHERE event_id=? ORDER BY id", eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var teams []Team
+	for rows.Next() {
+		var t Team
+		if err := rows.Scan(&t.ID, &t.EventID, &t.Name, &t.Color); err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+	return teams, rows.Err()
+}
+
+func (s *SQLStore) GetTeam(id int) (*Team, error) {
+	t := &Team{}
+	err := s.queryRow("SELECT id, event_id, name, color FROM teams WHERE id=?", id).Scan(&t.ID, &t.EventID, &t.Name, &t.Color)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return t, nil
+}
+
+func (s *SQLStore) AddTeam(t *Team) error {
+	id, err := s.insert("INSERT INTO teams (event_id, name, color) VALUES (?, ?, ?)", t.EventID, t.Name, t.Color)
+	if err != nil {
+		return err
+	}
+	t.ID = id
+	return nil
+}
+
+func (s *SQLStore) UpdateTeam(t Team) error {
+	_, err := s.exec("UPDATE teams SET name=?, color=? WHERE id=?", t.Name, t.Color, t.ID)
+	return err
+}
+
+func (s *SQLStore) RemoveTeam(id int) error {
+	return s.atomic(func(s *SQLStore) error {
+		for _, stmt := range []string{
+			"DELETE FROM team_players WHERE team_id=?",
+			"UPDATE users SET team_id=NULL WHERE team_id=?",
+			"DELETE FROM teams WHERE id=?",
+		} {
+			if _, err := s.exec(stmt, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) TeamInUse(id int) (bool, error) {
+	var used int
+	err := s.queryRow("SELECT COUNT(*) FROM matches WHERE team_a_id=? OR team_b_id=?", id, id).Scan(&used)
+	return used > 0, err
+}
+
+func (s *SQLStore) TeamPlayers(teamID int) ([]Player, error) {
+	rows, err := s.query("SELECT p.id, p.name, p.email, p.hcp FROM players p JOIN team_players tp ON p.id=tp.player_id WHERE tp.team_id=? ORDER BY p.name", teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var players []Player
+	for rows.Next() {
+		var p Player
+		var hcp sql.NullFloat64
+		if err := rows.Scan(&p.ID, &p.Name, &p.Email, &hcp); err != nil {
+			return nil, err
+		}
+		p.HCP = nullFloat(hcp)
+		players = append(players, p)
+	}
+	return players, rows.Err()
+}
+
+func (s *SQLStore) SetTeamPlayers(teamID int, playerIDs []int) error {
+	return s.atomic(func(s *SQLStore) error {
+		if _, err := s.exec("DELETE FROM team_players WHERE team_id=?", teamID); err != nil {
+			return err
+		}
+		for _, pid := range playerIDs {
+			if err := s.AddTeamPlayer(teamID, pid); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) AddTeamPlayer(teamID, playerID int) error {
+	_, err := s.exec("INSERT INTO team_players (team_id, player_id) VALUES (?, ?) ON CONFLICT DO NOTHING", teamID, playerID)
+	return err
+}
+
+func (s *SQLStore) SetPlayerTeam(eventID, playerID int, teamID *int) error {
+	return s.atomic(func(s *SQLStore) error {
+		if _, err := s.exec("DELETE FROM team_players WHERE player_id=? AND team_id IN (SELECT id FROM teams WHERE event_id=?)", playerID, eventID); err != nil {
+			return err
+		}
+		if teamID == nil {
+			return nil
+		}
+		return s.AddTeamPlayer(*teamID, playerID)
+	})
+}
+
+// --- Courses ---
+func (s *SQLStore) ListCourses() ([]Course, error) {
+	rows, err := s.query("SELECT id FROM courses ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	courses := []Course{}
+	for _, id := range ids {
+		c, err := s.GetCourse(id)
+		if err != nil {
+			return nil, err
+		}
+		courses = append(courses, *c)
+	}
+	return courses, nil
+}
+
+func (s *SQLStore) GetCourse(id int) (*Course, error) {
+	c := &Course{ID: id, Holes: []CourseHole{}, Tees: []Tee{}}
+	if err := s.queryRow("SELECT name FROM courses WHERE id=?", id).Scan(&c.Name); err != nil {
+		return nil, notFound(err)
+	}
+	rows, err := s.query("SELECT hole, par, stroke_index FROM course_holes WHERE course_id=? ORDER BY hole", id)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var h CourseHole
+		if err := rows.Scan(&h.Hole, &h.Par, &h.StrokeIndex); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.Holes = append(c.Holes, h)
+	}
+	rows.Close()
+	rows, err = s.query("SELECT id FROM tees WHERE course_id=? ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	var teeIDs []int
+	for rows.Next() {
+		var tid int
+		if err := rows.Scan(&tid); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		teeIDs = append(teeIDs, tid)
+	}
+	rows.Close()
+	for _, tid := range teeIDs {
+		t, err := s.GetTee(tid)
+		if err != nil {
+			return nil, err
+		}
+		c.Tees = append(c.Tees, *t)
+	}
+	return c, nil
+}
+
+func (s *SQLStore) saveCourseHoles(courseID int, holes []CourseHole) error {
+	if _, err := s.exec("DELETE FROM course_holes WHERE course_id=?", courseID); err != nil {
+		return err
+	}
+	for _, h := range holes {
+		if _, err := s.exec("INSERT INTO course_holes (course_id, hole, par, stroke_index) VALUES (?, ?, ?, ?)", courseID, h.Hole, h.Par, h.StrokeIndex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) AddCourse(c *Course) error {
+	return s.atomic(func(s *SQLStore) error {
+		id, err := s.insert("INSERT INTO courses (name) VALUES (?)", c.Name)
+		if err != nil {
+			return err
+		}
+		c.ID = id
+		return s.saveCourseHoles(c.ID, c.Holes)
+	})
+}
+
+func (s *SQLStore) UpdateCourse(c Course) error {
+	return s.atomic(func(s *SQLStore) error {
+		if _, err := s.exec("UPDATE courses SET name=? WHERE id=?", c.Name, c.ID); err != nil {
+			return err
+		}
+		return s.saveCourseHoles(c.ID, c.Holes)
+	})
+}
+
+func (s *SQLStore) RemoveCourse(id int) error {
+	return s.atomic(func(s *SQLStore) error {
+		for _, stmt := range []string{
+			"DELETE FROM tee_holes WHERE tee_id IN (SELECT id FROM tees WHERE course_id=?)",
+			"DELETE FROM tees WHERE course_id=?",
+			"DELETE FROM course_holes WHERE course_id=?",
+			"DELETE FROM courses WHERE id=?",
+		} {
+			if _, err := s.exec(stmt, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) GetTee(id int) (*Tee, error) {
+	t := &Tee{ID: id}
+	var rating sql.NullFloat64
+	var slope sql.NullInt64
+	var color sql.NullString
+	err := s.queryRow("SELECT course_id, name, color, course_rating, slope FROM tees WHERE id=?", id).Scan(&t.CourseID, &t.Name, &color, &rating, &slope)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	t.Color = color.String
+	t.CourseRating = nullFloat(rating)
+	t.Slope = nullInt(slope)
+	rows, err := s.query("SELECT hole, yards FROM tee_holes WHERE tee_id=? ORDER BY hole", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hole, yards int
+		if err := rows.Scan(&hole, &yards); err != nil {
+			return nil, err
+		}
+		if t.Yards == nil {
+			t.Yards = make([]int, 18)
+		}
+		if hole >= 1 && hole <= 18 {
+			t.Yards[hole-1] = yards
+		}
+	}
+	return t, rows.Err()
+}
+
+func (s *SQLStore) saveTeeYards(teeID int, yards []int) error {
+	if _, err := s.exec("DELETE FROM tee_holes WHERE tee_id=?", teeID); err != nil {
+		return err
+	}
+	for i, y := range yards {
+		if i >= 18 || y <= 0 {
+			continue
+		}
+		if _, err := s.exec("INSERT INTO tee_holes (tee_id, hole, yards) VALUES (?, ?, ?)", teeID, i+1, y); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) AddTee(t *Tee) error {
+	return s.atomic(func(s *SQLStore) error {
+		id, err := s.insert("INSERT INTO tees (course_id, name, color, course_rating, slope) VALUES (?, ?, ?, ?, ?)", t.CourseID, t.Name, t.Color, t.CourseRating, t.Slope)
+		if err != nil {
+			return err
+		}
+		t.ID = id
+		return s.saveTeeYards(t.ID, t.Yards)
+	})
+}
+
+func (s *SQLStore) UpdateTee(t Tee) error {
+	return s.atomic(func(s *SQLStore) error {
+		if _, err := s.exec("UPDATE tees SET name=?, color=?, course_rating=?, slope=? WHERE id=?", t.Name, t.Color, t.CourseRating, t.Slope, t.ID); err != nil {
+			return err
+		}
+		return s.saveTeeYards(t.ID, t.Yards)
+	})
+}
+
+func (s *SQLStore) RemoveTee(id int) error {
+	return s.atomic(func(s *SQLStore) error {
+		if _, err := s.exec("DELETE FROM tee_holes WHERE tee_id=?", id); err != nil {
+			return err
+		}
+		_, err := s.exec("DELETE FROM tees WHERE id=?", id)
+		return err
+	})
+}
+
+func (s *SQLStore) CourseInUse(id int) (bool, error) {
+	var used int
+	err := s.queryRow("SELECT COUNT(*) FROM matches WHERE course_id=?", id).Scan(&used)
+	return used > 0, err
+}
+
+func (s *SQLStore) TeeInUse(id int) (bool, error) {
+	var used int
+	err := s.queryRow("SELECT COUNT(*) FROM matches WHERE tee_id=?", id).Scan(&used)
+	return used > 0, err
+}
+
+// --- Matches ---
+const matchColumns = `id, event_id, session_id, team_a_id, team_b_id, format, status, COALESCE(holes, ''), COALESCE(start_time, ''), course_id, tee_id, points, COALESCE(conceded, '')`
+
+func scanMatch(row interface{ Scan(...any) error }) (*Match, error) {
+	var m Match
+	var format string
+	var sessionID, courseID, teeID sql.NullInt64
+	var points sql.NullFloat64
+	if err := row.Scan(&m.ID, &m.EventID, &sessionID, &m.TeamA, &m.TeamB, &format, &m.Status, &m.Holes, &m.StartTime, &courseID, &teeID, &points, &m.Conceded); err != nil {
+		return nil, err
+	}
+	m.Format = MatchFormat(format)
+	m.SessionID = nullInt(sessionID)
+	m.CourseID = nullInt(courseID)
+	m.TeeID = nullInt(teeID)
+	m.Points = nullFloat(points)
+	return &m, nil
+}
+
+func (s *SQLStore) queryMatches(query string, args ...any) ([]Match, error) {
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	matches := []Match{}
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, *m)
+	}
+	return matches, rows.Err()
+}
+
+func (s *SQLStore) ListMatches(eventID int) ([]Match, error) {
+	return s.queryMatches("SELECT "+matchColumns+" FROM matches WHERE event_id=? ORDER BY start_time, id", eventID)
+}
+
+func (s *SQLStore) GetMatch(id int) (*Match, error) {
+	m, err := scanMatch(s.queryRow("SELECT "+matchColumns+" FROM matches WHERE id=?", id))
+	return m, notFound(err)
+}
+
+func (s *SQLStore) AddMatch(m *Match) error {
+	id, err := s.insert("INSERT INTO matches (event_id, session_id, team_a_id, team_b_id, format, status, holes, start_time, course_id, tee_id, points, conceded) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		m.EventID, m.SessionID, m.TeamA, m.TeamB, m.Format, m.Status, m.Holes, m.StartTime, m.CourseID, m.TeeID, m.Points, m.Conceded)
+	if err != nil {
+		return err
+	}
+	m.ID = id
+	return nil
+}
+
+func (s *SQLStore) UpdateMatch(m Match) error {
+	_, err := s.exec("UPDATE matches SET session_id=?, team_a_id=?, team_b_id=?, format=?, status=?, holes=?, start_time=?, course_id=?, tee_id=?, points=?, conceded=? WHERE id=?",
+		m.SessionID, m.TeamA, m.TeamB, m.Format, m.Status, m.Holes, m.StartTime, m.CourseID, m.TeeID, m.Points, m.Conceded, m.ID)
+	return err
+}
+
+func (s *SQLStore) RemoveMatch(id int) error {
+	return s.atomic(func(s *SQLStore) error {
+		for _, stmt := range []string{
+			"DELETE FROM hole_results WHERE match_id=?",
+			"DELETE FROM side_hole_results WHERE match_id=?",
+			"DELETE FROM match_scorers WHERE match_id=?",
+			"DELETE FROM scores WHERE match_id=?",
+			"DELETE FROM match_players WHERE match_id=?",
+			"DELETE FROM matches WHERE id=?",
+		} {
+			if _, err := s.exec(stmt, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) MatchPlayers(matchID int) ([]MatchEntrant, error) {
+	rows, err := s.query(`SELECT p.id, p.name, mp.team_side, mp.hcp FROM match_players mp JOIN players p ON mp.player_id=p.id
+		WHERE mp.match_id=? ORDER BY mp.team_side, mp.`+s.dialect.rowOrder, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entrants []MatchEntrant
+	for rows.Next() {
+		var e MatchEntrant
+		var hcp sql.NullFloat64
+		if err := rows.Scan(&e.ID, &e.Name, &e.Side, &hcp); err != nil {
+			return nil, err
+		}
+		e.HCP = hcp.Float64
+		entrants = append(entrants, e)
+	}
+	return entrants, rows.Err()
+}
+
+func (s *SQLStore) SetMatchPlayers(matchID int, entrants []MatchEntrant) error {
+	return s.atomic(func(s *SQLStore) error {
+		if _, err := s.exec("DELETE FROM match_players WHERE match_id=?", matchID); err != nil {
+			return err
+		}
+		for _, e := range entrants {
+			if _, err := s.exec("INSERT INTO match_players (match_id, player_id, team_side, hcp) VALUES (?, ?, ?, (SELECT hcp FROM players WHERE id=?))",
+				matchID, e.ID, e.Side, e.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) PlayerMatches(playerID int) ([]Match, error) {
+	return s.queryMatches("SELECT "+matchColumns+" FROM matches WHERE id IN (SELECT match_id FROM match_players WHERE player_id=?) ORDER BY id", playerID)
+}
+
+// tokenColumns are the columns of the scorer tokens of a match by side, ""
+// for the match's card.
+var tokenColumns = map[string]string{"": "scorer_token", "A": "scorer_token_a", "B": "scorer_token_b"}
+
+func (s *SQLStore) ScorerToken(matchID int, side string) (string, error) {
+	column, ok := tokenColumns[side]
+	if !ok {
+		return "", fmt.Errorf("invalid side %q", side)
+	}
+	var token sql.NullString
+	err := s.queryRow("SELECT "+column+" FROM matches WHERE id=?", matchID).Scan(&token)
+	return token.String, notFound(err)
+}
+
+func (s *SQLStore) SetScorerToken(matchID int, side, token string) error {
+	column, ok := tokenColumns[side]
+	if !ok {
+		return fmt.Errorf("invalid side %q", side)
+	}
+	_, err := s.exec("UPDATE matches SET "+column+"=? WHERE id=?", token, matchID)
+	return err
+}
+
+// --- Scores ---
+func (s *SQLStore) ListScores(matchID int) ([]Score, error) {
+	rows, err := s.query("SELECT id, match_id, player_id, hole, strokes FROM scores WHERE match_id=? ORDER BY hole, player_id", matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	scores := []Score{}
+	for rows.Next() {
+		var sc Score
+		if err := rows.Scan(&sc.ID, &sc.MatchID, &sc.PlayerID, &sc.Hole, &sc.Strokes); err != nil {
+			return nil, err
+		}
+		scores = append(scores, sc)
+	}
+	return scores, rows.Err()
+}
+
+func (s *SQLStore) SetScore(sc Score) error {
+	return s.atomic(func(s *SQLStore) error {
+		if _, err := s.exec("DELETE FROM scores WHERE match_id=? AND player_id=? AND hole=?", sc.MatchID, sc.PlayerID, sc.Hole); err != nil {
+			return err
+		}
+		if sc.Strokes <= 0 {
+			return nil
+		}
+		_, err := s.exec("INSERT INTO scores (match_id, player_id, hole, strokes) VALUES (?, ?, ?, ?)", sc.MatchID, sc.PlayerID, sc.Hole, sc.Strokes)
+		return err
+	})
+}
+
+// --- Hole Results ---
+func (s *SQLStore) HoleResults(matchID int) ([]HoleResult, error) {
+	rows, err := s.query("SELECT hole, COALESCE(result, ''), COALESCE(source, '') FROM hole_results WHERE match_id=? ORDER BY hole", matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []HoleResult
+	for rows.Next() {
+		var r HoleResult
+		if err := rows.Scan(&r.Hole, &r.Result, &r.Source); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+func (s *SQLStore) SetHoleResult(matchID int, r HoleResult) error {
+	if r.Result == "" {
+		_, err := s.exec("DELETE FROM hole_results WHERE match_id=? AND hole=?", matchID, r.Hole)
+		return err
+	}
+	_, err := s.exec(`INSERT INTO hole_results (match_id, hole, result, source) VALUES (?, ?, ?, ?)
+		ON CONFLICT(match_id, hole) DO UPDATE SET result=excluded.result, source=excluded.source`, matchID, r.Hole, r.Result, r.Source)
+	return err
+}
+
+// --- Users ---
+func (s *SQLStore) ListUsers() ([]User, error) {
+	rows, err := s.query("SELECT id, username, role, team_id FROM users ORDER BY username")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := []User{}
+	for rows.Next() {
+		var u User
+		var teamID sql.NullInt64
+		if err := rows.Scan(&u.ID, &u.Username, &u.Role, &teamID); err != nil {
+			return nil, err
+		}
+		u.TeamID = nullInt(teamID)
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s *SQLStore) GetUser(id int) (*User, error) {
+	u := &User{}
+	var teamID sql.NullInt64
+	err := s.queryRow("SELECT id, username, role, team_id FROM users WHERE id=?", id).Scan(&u.ID, &u.Username, &u.Role, &teamID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	u.TeamID = nullInt(teamID)
+	return u, nil
+}
+
+func (s *SQLStore) UserByName(username string) (*User, error) {
+	u := &User{}
+	var teamID sql.NullInt64
+	err := s.queryRow("SELECT id, username, role, team_id, password_hash FROM users WHERE username=?", username).
+		Scan(&u.ID, &u.Username, &u.Role, &teamID, &u.PasswordHash)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	u.TeamID = nullInt(teamID)
+	return u, nil
+}
+
+func (s *SQLStore) AddUser(u *User) error {
+	id, err := s.insert("INSERT INTO users (username, password_hash, role, team_id) VALUES (?, ?, ?, ?)", u.Username, u.PasswordHash, u.Role, u.TeamID)
+	if err != nil {
+		return err
+	}
+	u.ID = id
+	return nil
+}
+
+func (s *SQLStore) UpdateUser(u User) error {
+	if u.PasswordHash != "" {
+		_, err := s.exec("UPDATE users SET username=?, role=?, team_id=?, password_hash=? WHERE id=?", u.Username, u.Role, u.TeamID, u.PasswordHash, u.ID)
+		return err
+	}
+	_, err := s.exec("UPDATE users SET username=?, role=?, team_id=? WHERE id=?", u.Username, u.Role, u.TeamID, u.ID)
+	return err
+}
+
+func (s *SQLStore) RemoveUser(id int) error {
+	return s.atomic(func(s *SQLStore) error {
+		for _, stmt := range []string{
+			"DELETE FROM match_scorers WHERE user_id=?",
+			"DELETE FROM auth_sessions WHERE user_id=?",
+			"DELETE FROM users WHERE id=?",
+		} {
+			if _, err := s.exec(stmt, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) UserMatches(userID int) ([]int, error) {
+	rows, err := s.query("SELECT match_id FROM match_scorers WHERE user_id=? ORDER BY match_id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *SQLStore) SetUserMatches(userID int, matchIDs []int) error {
+	return s.atomic(func(s *SQLStore) error {
+		if _, err := s.exec("DELETE FROM match_scorers WHERE user_id=?", userID); err != nil {
+			return err
+		}
+		for _, id := range matchIDs {
+			if _, err := s.exec("INSERT INTO match_scorers (match_id, user_id) VALUES (?, ?) ON CONFLICT DO NOTHING", id, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// loginTime formats login expiry times so they compare as strings.
+func loginTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (s *SQLStore) AddLogin(tokenHash string, userID int, expires time.Time) error {
+	return s.atomic(func(s *SQLStore) error {
+		// Logins that were never logged out of expire here
+		if _, err := s.exec("DELETE FROM auth_sessions WHERE expires_at<?", loginTime(time.Now())); err != nil {
+			return err
+		}
+		_, err := s.exec("INSERT INTO auth_sessions (token_hash, user_id, expires_at) VALUES (?, ?, ?)", tokenHash, userID, loginTime(expires))
+		return err
+	})
+}
+
+func (s *SQLStore) LoginUser(tokenHash string) (*User, error) {
+	var userID int
+	err := s.queryRow("SELECT user_id FROM auth_sessions WHERE token_hash=? AND expires_at>=?", tokenHash, loginTime(time.Now())).Scan(&userID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return s.GetUser(userID)
+}
+
+func (s *SQLStore) RemoveLogin(tokenHash string) error {
+	_, err := s.exec("DELETE FROM auth_sessions WHERE token_hash=?", tokenHash)
+	return err
+}
+
+func (s *SQLStore) RemoveUserLogins(userID int, keep string) error {
+	_, err := s.exec("DELETE FROM auth_sessions WHERE user_id=? AND token_hash<>?", userID, keep)
+	return err
+}
+
+// --- Audit log ---
+func (s *SQLStore) AddAuditEntry(e *AuditEntry) error {
+	id, err := s.insert("INSERT INTO audit_log (match_id, actor, created_at, field, hole, player_id, old_value, new_value) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		e.MatchID, e.Actor, e.At, e.Field, sql.NullInt64{Int64: int64(e.Hole), Valid: e.Hole != 0},
+		sql.NullInt64{Int64: int64(e.PlayerID), Valid: e.PlayerID != 0}, e.Old, e.New)
+	if err != nil {
+		return err
+	}
+	e.ID = id
+	return nil
+}
+
+func (s *SQLStore) AuditEntries(matchID int) ([]AuditEntry, error) {
+	rows, err := s.query("SELECT id, match_id, actor, created_at, field, hole, player_id, old_value, new_value FROM audit_log WHERE match_id=? ORDER BY id", matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var hole, playerID sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.MatchID, &e.Actor, &e.At, &e.Field, &hole, &playerID, &e.Old, &e.New); err != nil {
+			return nil, err
+		}
+		e.Hole, e.PlayerID = int(hole.Int64), int(playerID.Int64)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// --- Match history ---
+func (s *SQLStore) AddMatchEvents(matchID int, events []MatchEvent) error {
+	return s.atomic(func(s *SQLStore) error {
+		var action int
+		if err := s.queryRow("SELECT COALESCE(MAX(action), 0) FROM match_events WHERE match_id=?", matchID).Scan(&action); err != nil {
+			return err
+		}
+		for i := range events {
+			e := &events[i]
+			e.MatchID, e.Action = matchID, action+1
+			id, err := s.insert("INSERT INTO match_events (match_id, action, type, hole, player_id, value, source, actor, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+				e.MatchID, e.Action, e.Type, sql.NullInt64{Int64: int64(e.Hole), Valid: e.Hole != 0},
+				sql.NullInt64{Int64: int64(e.PlayerID), Valid: e.PlayerID != 0}, e.Value, e.Source, e.Actor, e.At)
+			if err != nil {
+				return err
+			}
+			e.ID = id
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) MatchEvents(matchID int) ([]MatchEvent, error) {
+	rows, err := s.query("SELECT id, match_id, action, type, hole, player_id, value, source, actor, created_at FROM match_events WHERE match_id=? ORDER BY id", matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []MatchEvent{}
+	for rows.Next() {
+		var e MatchEvent
+		var hole, playerID sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.MatchID, &e.Action, &e.Type, &hole, &playerID, &e.Value, &e.Source, &e.Actor, &e.At); err != nil {
+			return nil, err
+		}
+		e.Hole, e.PlayerID = int(hole.Int64), int(playerID.Int64)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// --- Offline sync ---
+func (s *SQLStore) SyncOutcome(matchID int, key string) (string, error) {
+	var outcome string
+	err := s.queryRow("SELECT outcome FROM sync_edits WHERE match_id=? AND edit_key=?", matchID, key).Scan(&outcome)
+	return outcome, notFound(err)
+}
+
+func (s *SQLStore) AddSyncOutcome(matchID int, key, outcome string) error {
+	_, err := s.exec("INSERT INTO sync_edits (match_id, edit_key, outcome, created_at) VALUES (?, ?, ?, ?)",
+		matchID, key, outcome, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// --- Cards ---
+func (s *SQLStore) SideResults(matchID int) ([]SideResult, error) {
+	rows, err := s.query("SELECT side, hole, result, actor, updated_at FROM side_hole_results WHERE match_id=? ORDER BY side, hole", matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []SideResult
+	for rows.Next() {
+		var r SideResult
+		if err := rows.Scan(&r.Side, &r.Hole, &r.Result, &r.Actor, &r.At); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+func (s *SQLStore) SetSideResult(matchID int, r SideResult) error {
+	if r.Result == "" {
+		_, err := s.exec("DELETE FROM side_hole_results WHERE match_id=? AND side=? AND hole=?", matchID, r.Side, r.Hole)
+		return err
+	}
+	_, err := s.exec(`INSERT INTO side_hole_results (match_id, side, hole, result, actor, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(match_id, side, hole) DO UPDATE SET result=excluded.result, actor=excluded.actor, updated_at=excluded.updated_at`,
+		matchID, r.Side, r.Hole, r.Result, r.Actor, r.At)
+	return err
+}
+
+func (s *SQLStore) CardSignatures(matchID int) ([]CardSignature, error) {
+	rows, err := s.query("SELECT id, match_id, side, player_id, signer, actor, holes, result, signed_at FROM card_signatures WHERE match_id=? ORDER BY id", matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sigs := []CardSignature{}
+	for rows.Next() {
+		var sig CardSignature
+		var playerID sql.NullInt64
+		var holes string
+		if err := rows.Scan(&sig.ID, &sig.MatchID, &sig.Side, &playerID, &sig.Signer, &sig.Actor, &holes, &sig.Result, &sig.SignedAt); err != nil {
+			return nil, err
+		}
+		sig.PlayerID, sig.Holes = int(playerID.Int64), strings.Split(holes, ",")
+		sigs = append(sigs, sig)
+	}
+	return sigs, rows.Err()
+}
+
+func (s *SQLStore) AddCardSignature(sig *CardSignature) error {
+	id, err := s.insert("INSERT INTO card_signatures (match_id, side, player_id, signer, actor, holes, result, signed_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		sig.MatchID, sig.Side, sql.NullInt64{Int64: int64(sig.PlayerID), Valid: sig.PlayerID != 0}, sig.Signer, sig.Actor,
+		strings.Join(sig.Holes, ","), sig.Result, sig.SignedAt)
+	if err != nil {
+		return err
+	}
+	sig.ID = id
+	return nil
+}
+
+func (s *SQLStore) ClearCardSignatures(matchID int) error {
+	_, err := s.exec("DELETE FROM card_signatures WHERE match_id=?", matchID)
+	return err
+}
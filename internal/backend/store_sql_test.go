@@ -0,0 +1,195 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query    string
+		postgres string
+	}{
+		{"SELECT id FROM teams", "SELECT id FROM teams"},
+		{"SELECT id FROM teams WHERE id=?", "SELECT id FROM teams WHERE id=$1"},
+		{"INSERT INTO scores (match_id, player_id, hole, strokes) VALUES (?, ?, ?, ?)", "INSERT INTO scores (match_id, player_id, hole, strokes) VALUES ($1, $2, $3, $4)"},
+		{"UPDATE players SET name=?, hcp=? WHERE id=?", "UPDATE players SET name=$1, hcp=$2 WHERE id=$3"},
+	}
+	sqlite, postgres := NewSQLiteStore(nil), NewPostgresStore(nil)
+	for _, tt := range tests {
+		if got := sqlite.rebind(tt.query); got != tt.query {
+			t.Errorf("SQLite %q: got %q, want it unchanged", tt.query, got)
+		}
+		if got := postgres.rebind(tt.query); got != tt.postgres {
+			t.Errorf("PostgreSQL %q: got %q, want %q", tt.query, got, tt.postgres)
+		}
+	}
+}
+
+// recordingDB is a database/sql driver that records the statements it gets
+// and answers them with no rows. A query with RETURNING id returns id 1, and
+// LastInsertId fails as it does with lib/pq.
+type recordingDB struct {
+	mu      sync.Mutex
+	queries []recordedQuery
+}
+
+type recordedQuery struct {
+	query string
+	args  int
+}
+
+func (db *recordingDB) record(query string, args []driver.Value) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queries = append(db.queries, recordedQuery{query, len(args)})
+}
+
+func (db *recordingDB) Connect(context.Context) (driver.Conn, error) { return recordingConn{db}, nil }
+func (db *recordingDB) Driver() driver.Driver                        { return db }
+func (db *recordingDB) Open(string) (driver.Conn, error)             { return recordingConn{db}, nil }
+
+type recordingConn struct{ db *recordingDB }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{c.db, query}, nil
+}
+func (c recordingConn) Close() error              { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) { return recordingTx{}, nil }
+
+type recordingTx struct{}
+
+func (recordingTx) Commit() error   { return nil }
+func (recordingTx) Rollback() error { return nil }
+
+type recordingStmt struct {
+	db    *recordingDB
+	query string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.record(s.query, args)
+	return recordingResult{}, nil
+}
+
+func (s recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.record(s.query, args)
+	rows := &recordingRows{}
+	if strings.HasSuffix(s.query, "RETURNING id") {
+		rows.ids = 1
+	}
+	return rows, nil
+}
+
+type recordingResult struct{}
+
+func (recordingResult) LastInsertId() (int64, error) {
+	return 0, errors.New("LastInsertId is not supported by this driver")
+}
+func (recordingResult) RowsAffected() (int64, error) { return 1, nil }
+
+type recordingRows struct{ ids int }
+
+func (r *recordingRows) Columns() []string { return []string{"id"} }
+func (r *recordingRows) Close() error      { return nil }
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if r.ids == 0 {
+		return io.EOF
+	}
+	r.ids--
+	dest[0] = int64(1)
+	return nil
+}
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+// TestPostgresStatements runs every Store method of a PostgreSQL store on
+// recordingDB: the statements it sends have to number their placeholders
+// $1 to $n for their n arguments, and new ids have to come from RETURNING.
+// That the statements run on PostgreSQL is up to the conformance tests with
+// RYDER_TEST_POSTGRES.
+func TestPostgresStatements(t *testing.T) {
+	db := &recordingDB{}
+	st := NewPostgresStore(sql.OpenDB(db))
+	store := reflect.ValueOf(Store(st))
+	methods := reflect.TypeOf((*Store)(nil)).Elem()
+	for i := range methods.NumMethod() {
+		name := methods.Method(i).Name
+		if name == "Tx" {
+			continue
+		}
+		fn := store.MethodByName(name)
+		var args []reflect.Value
+		for j := range fn.Type().NumIn() {
+			in := fn.Type().In(j)
+			if in.Kind() == reflect.Pointer {
+				args = append(args, reflect.New(in.Elem()))
+			} else {
+				args = append(args, reflect.Zero(in))
+			}
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s: %v", name, r)
+				}
+			}()
+			fn.Call(args)
+		}()
+	}
+	err := st.Tx(func(tx Store) error {
+		return tx.SetHoleResult(1, HoleResult{Hole: 1, Result: "A", Source: SourceManual})
+	})
+	if err != nil {
+		t.Errorf("Tx: %v", err)
+	}
+
+	if len(db.queries) < methods.NumMethod() {
+		t.Errorf("got %d statements for %d methods", len(db.queries), methods.NumMethod())
+	}
+	for _, q := range db.queries {
+		if strings.Contains(q.query, "?") {
+			t.Errorf("%s: ? placeholder left", q.query)
+		}
+		n := 0
+		for _, m := range placeholder.FindAllStringSubmatch(q.query, -1) {
+			i, _ := strconv.Atoi(m[1])
+			n = max(n, i)
+		}
+		if n != q.args {
+			t.Errorf("%s: placeholders up to $%d for %d arguments", q.query, n, q.args)
+		}
+	}
+
+	// Adding rows reads their ids back with RETURNING
+	ids := map[string]func() (int, error){
+		"event":  func() (int, error) { e := Event{Name: "Cup"}; err := st.AddEvent(&e); return e.ID, err },
+		"player": func() (int, error) { p := Player{Name: "Alice"}; err := st.AddPlayer(&p); return p.ID, err },
+		"team":   func() (int, error) { tm := Team{Name: "EU"}; err := st.AddTeam(&tm); return tm.ID, err },
+		"match":  func() (int, error) { m := Match{Format: Singles}; err := st.AddMatch(&m); return m.ID, err },
+		"user":   func() (int, error) { u := User{Username: "admin"}; err := st.AddUser(&u); return u.ID, err },
+		"audit entry": func() (int, error) {
+			e := AuditEntry{MatchID: 1, Field: "status"}
+			err := st.AddAuditEntry(&e)
+			return e.ID, err
+		},
+	}
+	for what, add := range ids {
+		if id, err := add(); err != nil || id != 1 {
+			t.Errorf("adding a %s: got id %d (%v), want 1", what, id, err)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/tests/copilot/ryder/internal/migrate"
+)
+
+// testStores returns the stores the conformance tests run against: the
+// memory store, SQLite in a temporary file, and PostgreSQL when
+// RYDER_TEST_POSTGRES holds a DSN. Each call opens empty ones.
+func testStores(t *testing.T) map[string]func(t *testing.T) Store {
+	return map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"sqlite": func(t *testing.T) Store {
+			db := migratedDB(t, "sqlite3", filepath.Join(t.TempDir(), "ryder.db")+"?_txlock=immediate")
+			return NewSQLiteStore(db)
+		},
+		"postgres": func(t *testing.T) Store {
+			dsn := os.Getenv("RYDER_TEST_POSTGRES")
+			if dsn == "" {
+				t.Skip("RYDER_TEST_POSTGRES is not set")
+			}
+			return NewPostgresStore(migratedDB(t, "postgres", postgresSchema(t, dsn)))
+		},
+	}
+}
+
+// postgresSchema creates a schema of its own for a test and returns the DSN
+// that uses it. The schema is dropped when the test ends.
+func postgresSchema(t *testing.T, dsn string) string {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	schema := fmt.Sprintf("ryder_test_%d", time.Now().UnixNano())
+	if _, err := db.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db, err := sql.Open("postgres", dsn)
+		if err == nil {
+			db.Exec("DROP SCHEMA " + schema + " CASCADE")
+			db.Close()
+		}
+	})
+	switch {
+	case !strings.Contains(dsn, "://"):
+		return dsn + " search_path=" + schema
+	case strings.Contains(dsn, "?"):
+		return dsn + "&search_path=" + schema
+	default:
+		return dsn + "?search_path=" + schema
+	}
+}
+
+func migratedDB(t *testing.T, driver, dsn string) *sql.DB {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m, err := migrate.New(db, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// storeFixture is an event with two teams of one player each and a singles
+// match between them.
+type storeFixture struct {
+	event        Event
+	teamA, teamB Team
+	alice, bob   Player
+	match        Match
+	captain      User
+}
+
+func newStoreFixture(t *testing.T, st Store) *storeFixture {
+	t.Helper()
+	f := &storeFixture{}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.event = Event{Name: "Cup", StartDate: "2026-09-25"}
+	must(st.AddEvent(&f.event))
+	f.teamA = Team{EventID: f.event.ID, Name: "EU", Color: "#00f"}
+	must(st.AddTeam(&f.teamA))
+	f.teamB = Team{EventID: f.event.ID, Name: "US", Color: "#f00"}
+	must(st.AddTeam(&f.teamB))
+	hcp := 10.0
+	f.alice = Player{Name: "Alice", HCP: &hcp}
+	must(st.AddPlayer(&f.alice))
+	f.bob = Player{Name: "Bob", HCP: &hcp}
+	must(st.AddPlayer(&f.bob))
+	must(st.AddTeamPlayer(f.teamA.ID, f.alice.ID))
+	must(st.AddTeamPlayer(f.teamB.ID, f.bob.ID))
+	f.match = Match{EventID: f.event.ID, TeamA: f.teamA.ID, TeamB: f.teamB.ID, Format: Singles, Status: "running", Holes: "18"}
+	must(st.AddMatch(&f.match))
+	must(st.SetMatchPlayers(f.match.ID, []MatchEntrant{{ID: f.alice.ID, Side: "A"}, {ID: f.bob.ID, Side: "B"}}))
+	must(st.SetScore(Score{MatchID: f.match.ID, PlayerID: f.alice.ID, Hole: 1, Strokes: 4}))
+	must(st.SetScore(Score{MatchID: f.match.ID, PlayerID: f.bob.ID, Hole: 1, Strokes: 5}))
+	f.captain = User{Username: "captain", Role: "captain", TeamID: &f.teamA.ID, PasswordHash: "x"}
+	must(st.AddUser(&f.captain))
+	return f
+}
+
+func TestStoreConformance(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, st Store, f *storeFixture)
+	}{
+		{"missing rows are ErrNotFound", func(t *testing.T, st Store, f *storeFixture) {
+			if _, err := st.GetMatch(999); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetMatch: got %v", err)
+			}
+			if _, err := st.GetTeam(999); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetTeam: got %v", err)
+			}
+			if _, err := st.SyncOutcome(f.match.ID, "unknown"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("SyncOutcome: got %v", err)
+			}
+		}},
+		{"Tx discards the writes of a failed fn", func(t *testing.T, st Store, f *storeFixture) {
+			failed := errors.New("failed")
+			err := st.Tx(func(tx Store) error {
+				if err := tx.SetHoleResult(f.match.ID, HoleResult{Hole: 1, Result: "A", Source: SourceManual}); err != nil {
+					return err
+				}
+				return failed
+			})
+			if !errors.Is(err, failed) {
+				t.Fatalf("Tx: got %v", err)
+			}
+			if results, _ := st.HoleResults(f.match.ID); len(results) != 0 {
+				t.Errorf("HoleResults: got %v, want none", results)
+			}
+		}},
+		{"SetHoleResult replaces and clears", func(t *testing.T, st Store, f *storeFixture) {
+			st.SetHoleResult(f.match.ID, HoleResult{Hole: 2, Result: "A", Source: SourceManual})
+			st.SetHoleResult(f.match.ID, HoleResult{Hole: 2, Result: "B", Source: SourceManual})
+			st.SetHoleResult(f.match.ID, HoleResult{Hole: 3, Result: "AS", Source: SourceManual})
+			st.SetHoleResult(f.match.ID, HoleResult{Hole: 3, Result: "", Source: SourceManual})
+			results, err := st.HoleResults(f.match.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 || results[0].Hole != 2 || results[0].Result != "B" {
+				t.Errorf("HoleResults: got %v", results)
+			}
+		}},
+		{"scorer tokens per side", func(t *testing.T, st Store, f *storeFixture) {
+			for _, side := range []string{"", "A", "B"} {
+				if err := st.SetScorerToken(f.match.ID, side, "token"+side); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, side := range []string{"", "A", "B"} {
+				if token, err := st.ScorerToken(f.match.ID, side); err != nil || token != "token"+side {
+					t.Errorf("ScorerToken(%q): got %q, %v", side, token, err)
+				}
+			}
+		}},
+		{"MatchPlayers keeps the handicap of SetMatchPlayers", func(t *testing.T, st Store, f *storeFixture) {
+			hcp := 20.0
+			alice := f.alice
+			alice.HCP = &hcp
+			if err := st.UpdatePlayer(alice); err != nil {
+				t.Fatal(err)
+			}
+			entrants, err := st.MatchPlayers(f.match.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entrants) != 2 || entrants[0].ID != f.alice.ID || entrants[0].HCP != 10 {
+				t.Errorf("MatchPlayers: got %+v", entrants)
+			}
+		}},
+		{"ClearCardSignatures", func(t *testing.T, st Store, f *storeFixture) {
+			sig := CardSignature{MatchID: f.match.ID, Side: "A", PlayerID: f.alice.ID, Signer: "Alice", Actor: "token", Holes: make([]string, 18), Result: "Halved", SignedAt: "2026-09-25T12:00:00Z"}
+			if err := st.AddCardSignature(&sig); err != nil {
+				t.Fatal(err)
+			}
+			if sigs, _ := st.CardSignatures(f.match.ID); len(sigs) != 1 {
+				t.Fatalf("CardSignatures: got %v", sigs)
+			}
+			if err := st.ClearCardSignatures(f.match.ID); err != nil {
+				t.Fatal(err)
+			}
+			if sigs, _ := st.CardSignatures(f.match.ID); len(sigs) != 0 {
+				t.Errorf("CardSignatures after clearing: got %v", sigs)
+			}
+		}},
+		{"RemovePlayer deletes their places and strokes", func(t *testing.T, st Store, f *storeFixture) {
+			if err := st.RemovePlayer(f.alice.ID); err != nil {
+				t.Fatal(err)
+			}
+			if players, _ := st.TeamPlayers(f.teamA.ID); len(players) != 0 {
+				t.Errorf("TeamPlayers: got %v", players)
+			}
+			entrants, _ := st.MatchPlayers(f.match.ID)
+			if len(entrants) != 1 || entrants[0].ID != f.bob.ID {
+				t.Errorf("MatchPlayers: got %+v", entrants)
+			}
+			scores, _ := st.ListScores(f.match.ID)
+			if len(scores) != 1 || scores[0].PlayerID != f.bob.ID {
+				t.Errorf("ListScores: got %+v", scores)
+			}
+			if matches, _ := st.PlayerMatches(f.alice.ID); len(matches) != 0 {
+				t.Errorf("PlayerMatches: got %v", matches)
+			}
+			// A new player that gets a reused id has no matches of the old one
+			carol := Player{Name: "Carol"}
+			if err := st.AddPlayer(&carol); err != nil {
+				t.Fatal(err)
+			}
+			if matches, _ := st.PlayerMatches(carol.ID); len(matches) != 0 {
+				t.Errorf("PlayerMatches of a new player: got %v", matches)
+			}
+		}},
+		{"TeamInUse", func(t *testing.T, st Store, f *storeFixture) {
+			for _, id := range []int{f.teamA.ID, f.teamB.ID} {
+				if used, err := st.TeamInUse(id); err != nil || !used {
+					t.Errorf("TeamInUse(%d): got %v, %v", id, used, err)
+				}
+			}
+			if err := st.RemoveMatch(f.match.ID); err != nil {
+				t.Fatal(err)
+			}
+			if used, err := st.TeamInUse(f.teamA.ID); err != nil || used {
+				t.Errorf("TeamInUse after RemoveMatch: got %v, %v", used, err)
+			}
+		}},
+		{"RemoveTeam deletes its player list and frees its captains", func(t *testing.T, st Store, f *storeFixture) {
+			if err := st.RemoveMatch(f.match.ID); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.RemoveTeam(f.teamA.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := st.GetTeam(f.teamA.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetTeam: got %v", err)
+			}
+			if players, _ := st.TeamPlayers(f.teamA.ID); len(players) != 0 {
+				t.Errorf("TeamPlayers: got %v", players)
+			}
+			players, _ := st.ListPlayers(f.event.ID)
+			for _, p := range players {
+				if p.ID == f.alice.ID && p.TeamID != nil {
+					t.Errorf("ListPlayers: Alice is still on team %d", *p.TeamID)
+				}
+			}
+			u, err := st.GetUser(f.captain.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.TeamID != nil {
+				t.Errorf("captain team: got %d, want none", *u.TeamID)
+			}
+		}},
+		{"RemoveMatch deletes its roster, results and tokens", func(t *testing.T, st Store, f *storeFixture) {
+			st.SetHoleResult(f.match.ID, HoleResult{Hole: 1, Result: "A", Source: SourceStrokes})
+			st.SetScorerToken(f.match.ID, "A", "token")
+			if err := st.RemoveMatch(f.match.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := st.GetMatch(f.match.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetMatch: got %v", err)
+			}
+			if matches, _ := st.PlayerMatches(f.alice.ID); len(matches) != 0 {
+				t.Errorf("PlayerMatches: got %v", matches)
+			}
+			if scores, _ := st.ListScores(f.match.ID); len(scores) != 0 {
+				t.Errorf("ListScores: got %v", scores)
+			}
+			if results, _ := st.HoleResults(f.match.ID); len(results) != 0 {
+				t.Errorf("HoleResults: got %v", results)
+			}
+		}},
+		{"RemoveUserLogins keeps one login and the other users'", func(t *testing.T, st Store, f *storeFixture) {
+			expires := time.Now().Add(time.Hour)
+			for _, hash := range []string{"phone", "laptop", "current"} {
+				if err := st.AddLogin(hash, f.captain.ID, expires); err != nil {
+					t.Fatal(err)
+				}
+			}
+			viewer := User{Username: "viewer", Role: RoleViewer, PasswordHash: "x"}
+			if err := st.AddUser(&viewer); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.AddLogin("other", viewer.ID, expires); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.RemoveUserLogins(f.captain.ID, "current"); err != nil {
+				t.Fatal(err)
+			}
+			for hash, want := range map[string]bool{"phone": false, "laptop": false, "current": true, "other": true} {
+				if _, err := st.LoginUser(hash); (err == nil) != want {
+					t.Errorf("LoginUser(%s): got %v, want a login %v", hash, err, want)
+				}
+			}
+		}},
+		{"RemoveUser deletes their logins and matches", func(t *testing.T, st Store, f *storeFixture) {
+			if err := st.AddLogin("hash", f.captain.ID, time.Now().Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.SetUserMatches(f.captain.ID, []int{f.match.ID}); err != nil {
+				t.Fatal(err)
+			}
+			if ids, _ := st.UserMatches(f.captain.ID); !slices.Equal(ids, []int{f.match.ID}) {
+				t.Errorf("UserMatches: got %v", ids)
+			}
+			if err := st.RemoveUser(f.captain.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := st.LoginUser("hash"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("LoginUser: got %v", err)
+			}
+			if ids, _ := st.UserMatches(f.captain.ID); len(ids) != 0 {
+				t.Errorf("UserMatches: got %v", ids)
+			}
+		}},
+	}
+	for name, open := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					st := open(t)
+					tt.run(t, st, newStoreFixture(t, st))
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// maxSyncEdits bounds the edits of one sync request.
+const maxSyncEdits = 500
+
+// SyncEdit is a hole edit a score page queued, for example while it was
+// offline: the result of a hole, or with PlayerID the strokes of a player on
+// it. Key is made by the page and identifies the edit across retries,
+// BaseVersion is the version of the match the edit was made on.
+type SyncEdit struct {
+	Key         string `json:"key"`
+	BaseVersion int    `json:"base_version"`
+	Hole        int    `json:"hole"`
+	Result      string `json:"result,omitempty"` // "A", "B", "AS", or "" to clear it
+	PlayerID    int    `json:"player_id,omitempty"`
+	Strokes     int    `json:"strokes,omitempty"` // 0 clears them
+}
+
+// Outcomes of a SyncEdit.
+const (
+	SyncApplied    = "applied"  // the match has the edit, also when it had it already
+	SyncConflicted = "conflict" // someone else changed the same thing since the base version
+	SyncRejected   = "rejected" // the edit is invalid
+)
+
+// SyncResult is the outcome of a SyncEdit. A retry of an edit gets the
+// outcome of its first try, with Duplicate set.
+type SyncResult struct {
+	Key       string        `json:"key"`
+	Status    string        `json:"status"`
+	Version   int           `json:"version"` // of the match once the edit was handled
+	Duplicate bool          `json:"duplicate,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Conflict  *SyncConflict `json:"conflict,omitempty"`
+}
+
+// SyncConflict is the change an edit conflicts with: the hole result or
+// strokes are Theirs now, the edit wanted Yours.
+type SyncConflict struct {
+	Field    string `json:"field"` // hole or strokes
+	Hole     int    `json:"hole"`
+	PlayerID int    `json:"player_id,omitempty"`
+	Yours    string `json:"yours"`
+	Theirs   string `json:"theirs"`
+	Version  int    `json:"version"` // the action that made the change
+	Actor    string `json:"actor"`
+	At       string `json:"at"`
+}
+
+// validate returns why an edit can't be applied, nil when it can.
+func (e SyncEdit) validate() error {
+	switch {
+	case e.Key == "":
+		return errors.New("missing key")
+	case e.Hole < 1 || e.Hole > 18:
+		return errors.New("invalid hole")
+	case e.PlayerID == 0 && e.Result != "" && e.Result != "A" && e.Result != "B" && e.Result != "AS":
+		return errors.New("invalid result")
+	case e.PlayerID != 0 && e.Strokes < 0:
+		return errors.New("invalid strokes")
+	}
+	return nil
+}
+
+// values returns what the edit wants and what the match has now.
+func (e SyncEdit) values(snap *matchSnapshot) (yours, theirs string) {
+	if e.PlayerID == 0 {
+		return e.Result, snap.holes[e.Hole-1].Result
+	}
+	strokes := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+	return strokes(e.Strokes), strokes(snap.strokes[[2]int{e.Hole, e.PlayerID}])
+}
+
+// concurrentChange returns the latest event after the base version of an
+// edit that changed what the edit changes, nil when there is none. The
+// actions in ours were made by the same sync and don't count.
+func (e SyncEdit) concurrentChange(events []MatchEvent, ours map[int]bool) *MatchEvent {
+	for i := len(events) - 1; i >= 0 && events[i].Action > e.BaseVersion; i-- {
+		ev := events[i]
+		if ours[ev.Action] || ev.Hole != e.Hole {
+			continue
+		}
+		if e.PlayerID == 0 && (ev.Type == MatchEventHoleSet || ev.Type == MatchEventHoleCleared) ||
+			e.PlayerID != 0 && ev.Type == MatchEventStrokesSet && ev.PlayerID == e.PlayerID {
+			return &ev
+		}
+	}
+	return nil
+}
+
+// apply makes the change of an edit to a match, like SaveHoleResults and
+// SubmitScore do.
+func (e SyncEdit) apply(tx Store, matchID int) error {
+	var err error
+	if e.PlayerID == 0 {
+		err = tx.SetHoleResult(matchID, HoleResult{Hole: e.Hole, Result: e.Result, Source: SourceManual})
+	} else {
+		err = tx.SetScore(Score{MatchID: matchID, PlayerID: e.PlayerID, Hole: e.Hole, Strokes: e.Strokes})
+	}
+	if err != nil {
+		return err
+	}
+	return RecomputeHoleResults(tx, matchID)
+}
+
+// syncEdit handles one edit of a sync in tx. ours are the actions the sync
+// made so far, the action of the edit is added.
+func syncEdit(tx Store, r *http.Request, matchID int, e SyncEdit, ours map[int]bool) (SyncResult, error) {
+	res := SyncResult{Key: e.Key}
+	events, err := tx.MatchEvents(matchID)
+	if err != nil {
+		return res, err
+	}
+	res.Version = matchVersion(events)
+	if err := e.validate(); err != nil {
+		res.Status, res.Error = SyncRejected, err.Error()
+		return res, nil
+	}
+	if e.BaseVersion < 0 || e.BaseVersion > res.Version {
+		res.Status, res.Error = SyncRejected, "unknown base version"
+		return res, nil
+	}
+	var closed conflict
+	if err := checkResultsOpen(tx, matchID); errors.As(err, &closed) {
+		res.Status, res.Error = SyncRejected, closed.Error()
+		return res, nil
+	} else if err != nil {
+		return res, err
+	}
+	snap, err := snapshotMatch(tx, matchID)
+	if err != nil {
+		return res, err
+	}
+	yours, theirs := e.values(snap)
+	if yours == theirs {
+		res.Status = SyncApplied
+		return res, nil
+	}
+	if ev := e.concurrentChange(events, ours); ev != nil {
+		res.Status = SyncConflicted
+		res.Conflict = &SyncConflict{Field: "hole", Hole: e.Hole, PlayerID: e.PlayerID, Yours: yours, Theirs: theirs,
+			Version: ev.Action, Actor: ev.Actor, At: ev.At}
+		if e.PlayerID != 0 {
+			res.Conflict.Field = "strokes"
+		}
+		return res, nil
+	}
+	if err := audited(tx, r, matchID, func() error { return e.apply(tx, matchID) }); err != nil {
+		return res, err
+	}
+	if events, err = tx.MatchEvents(matchID); err != nil {
+		return res, err
+	}
+	res.Status, res.Version = SyncApplied, matchVersion(events)
+	ours[res.Version] = true
+	return res, nil
+}
+
+// --- Offline Sync Handlers ---
+
+// SyncHoleEdits applies the hole edits a score page queued, in order. Every
+// edit is applied once, retries get the outcome of the first try; edits of
+// holes someone else changed since their base version come back as
+// conflicts instead of overwriting the change.
+func (srv *Server) SyncHoleEdits(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		MatchID int        `json:"match_id"`
+		Edits   []SyncEdit `json:"edits"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body.Edits) > maxSyncEdits {
+		http.Error(w, "Too many edits", http.StatusBadRequest)
+		return
+	}
+	defer srv.lockMatch(r, body.MatchID)()
+	if err := srv.checkMatchWritable(body.MatchID); err != nil {
+		writeError(w, err)
+		return
+	}
+	results := []SyncResult{}
+	version := 0
+	err := srv.store.Tx(func(tx Store) error {
+		ours := map[int]bool{}
+		for _, e := range body.Edits {
+			outcome, err := tx.SyncOutcome(body.MatchID, e.Key)
+			if err == nil {
+				var res SyncResult
+				if err := json.Unmarshal([]byte(outcome), &res); err != nil {
+					return err
+				}
+				res.Duplicate = true
+				results = append(results, res)
+				continue
+			}
+			if !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			res, err := syncEdit(tx, r, body.MatchID, e, ours)
+			if err != nil {
+				return err
+			}
+			results = append(results, res)
+			// Edits without a key can't be retried
+			if e.Key == "" {
+				continue
+			}
+			data, err := json.Marshal(res)
+			if err != nil {
+				return err
+			}
+			if err := tx.AddSyncOutcome(body.MatchID, e.Key, string(data)); err != nil {
+				return err
+			}
+		}
+		events, err := tx.MatchEvents(body.MatchID)
+		version = matchVersion(events)
+		return err
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"version": version, "results": results})
+}
@@ -0,0 +1,124 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// syncResponse is the reply to a sync.
+type syncResponse struct {
+	Version int          `json:"version"`
+	Results []SyncResult `json:"results"`
+}
+
+// sync posts the edits of match 1 and returns the reply.
+func (c *testClient) sync(edits ...string) syncResponse {
+	c.t.Helper()
+	var res syncResponse
+	body := c.mustPost("/api/match/sync", `{"match_id":1,"edits":[`+strings.Join(edits, ",")+`]}`)
+	if err := json.Unmarshal([]byte(body), &res); err != nil {
+		c.t.Fatalf("sync: %v", err)
+	}
+	if len(res.Results) != len(edits) {
+		c.t.Fatalf("sync: got %d results for %d edits", len(res.Results), len(edits))
+	}
+	return res
+}
+
+func TestSyncReplay(t *testing.T) {
+	c, _ := newTestServer(t)
+	singlesMatch(c)
+	scorer := c.as(c.scorerToken("1", ""))
+
+	first := scorer.sync(`{"key":"k1","base_version":0,"hole":1,"result":"A"}`)
+	if res := first.Results[0]; res.Status != SyncApplied || res.Duplicate || res.Version != 1 {
+		t.Fatalf("first try: got %+v, want applied at version 1", res)
+	}
+	// A retry, even one that changed on the way, gets the first outcome
+	for _, result := range []string{"A", "B"} {
+		retry := scorer.sync(`{"key":"k1","base_version":0,"hole":1,"result":"` + result + `"}`)
+		if res := retry.Results[0]; res.Status != SyncApplied || !res.Duplicate || res.Version != 1 || retry.Version != 1 {
+			t.Errorf("retry with %s: got %+v at version %d, want the first outcome", result, res, retry.Version)
+		}
+	}
+	if got := c.holeResults("1")[0]; got != "A" {
+		t.Errorf("hole 1: got %q, want A", got)
+	}
+	var audit struct {
+		Entries []AuditEntry `json:"entries"`
+	}
+	c.get("/api/audit?match_id=1", &audit)
+	if len(audit.Entries) != 1 {
+		t.Errorf("audit log: got %+v, want the one change", audit.Entries)
+	}
+}
+
+func TestSyncConflict(t *testing.T) {
+	c, _ := newTestServer(t)
+	singlesMatch(c)
+	scorer := c.as(c.scorerToken("1", ""))
+	// Another page sets hole 1 while this one is offline on version 0
+	c.mustPost("/api/match/holescore", `{"match_id":1,"holes":["B"]}`)
+
+	res := scorer.sync(
+		`{"key":"k1","base_version":0,"hole":1,"result":"A"}`,
+		`{"key":"k2","base_version":0,"hole":2,"result":"A"}`,
+		`{"key":"k3","base_version":0,"hole":1,"result":"B"}`,
+	)
+	conflicted := res.Results[0]
+	if conflicted.Status != SyncConflicted || conflicted.Conflict == nil {
+		t.Fatalf("edit of a hole changed since: got %+v, want a conflict", conflicted)
+	}
+	want := SyncConflict{Field: "hole", Hole: 1, Yours: "A", Theirs: "B", Version: 1, Actor: "admin", At: conflicted.Conflict.At}
+	if got := *conflicted.Conflict; got != want || got.At == "" {
+		t.Errorf("conflict: got %+v, want %+v", got, want)
+	}
+	if got := res.Results[1].Status; got != SyncApplied {
+		t.Errorf("edit of another hole: got %s, want applied", got)
+	}
+	if got := res.Results[2].Status; got != SyncApplied {
+		t.Errorf("edit to the result the hole has: got %s, want applied", got)
+	}
+	if got := c.holeResults("1")[:2]; got[0] != "B" || got[1] != "A" {
+		t.Errorf("holes: got %v, want B and A", got)
+	}
+
+	// The conflict is stored, its retry gets it again
+	retry := scorer.sync(`{"key":"k1","base_version":0,"hole":1,"result":"A"}`)
+	if got := retry.Results[0]; got.Status != SyncConflicted || !got.Duplicate || got.Conflict == nil || got.Conflict.Theirs != "B" {
+		t.Errorf("retry of the conflict: got %+v, want the stored conflict", got)
+	}
+	// A new edit made on the current version wins
+	rebased := scorer.sync(fmt.Sprintf(`{"key":"k4","base_version":%d,"hole":1,"result":"A"}`, retry.Version))
+	if got := rebased.Results[0].Status; got != SyncApplied {
+		t.Errorf("edit on the current version: got %s, want applied", got)
+	}
+	if got := c.holeResults("1")[0]; got != "A" {
+		t.Errorf("hole 1: got %q, want A", got)
+	}
+}
+
+func TestSyncLimit(t *testing.T) {
+	c, _ := newTestServer(t)
+	singlesMatch(c)
+	scorer := c.as(c.scorerToken("1", ""))
+	edits := func(n int) string {
+		var list []string
+		for i := range n {
+			list = append(list, fmt.Sprintf(`{"key":"k%d","base_version":0,"hole":%d,"result":"AS"}`, i, i%9+1))
+		}
+		return `{"match_id":1,"edits":[` + strings.Join(list, ",") + `]}`
+	}
+	if status, body := scorer.post("/api/match/sync", edits(maxSyncEdits+1)); status != http.StatusBadRequest {
+		t.Errorf("%d edits: got %d %s, want 400", maxSyncEdits+1, status, body)
+	}
+	if got := c.holeResults("1")[0]; got != "" {
+		t.Errorf("hole 1 after a rejected sync: got %q, want none", got)
+	}
+	if status, body := scorer.post("/api/match/sync", edits(maxSyncEdits)); status != http.StatusOK {
+		t.Errorf("%d edits: got %d %s, want 200", maxSyncEdits, status, body)
+	}
+}
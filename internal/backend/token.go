@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// ErrScorerToken is returned for score writes without the match's token, and
+// for card writes without the token of the side.
+var ErrScorerToken = errors.New("missing or invalid scorer token")
+
+// newToken returns a random hex token, for scorecards and logins.
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// scorerTokenFromRequest reads the token from the X-Scorer-Token header, or the
+// token query parameter for links.
+func scorerTokenFromRequest(r *http.Request) string {
+	if t := r.Header.Get("X-Scorer-Token"); t != "" {
+		return t
+	}
+	return r.URL.Query().Get("token")
+}
+
+// checkScorerToken returns ErrScorerToken unless the request carries the
+// scorer token of the match, side "", or the token of one side of its card.
+func (srv *Server) checkScorerToken(r *http.Request, matchID int, side string) error {
+	token, err := srv.store.ScorerToken(matchID, side)
+	if err != nil {
+		return err
+	}
+	given := scorerTokenFromRequest(r)
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(given)) != 1 {
+		return ErrScorerToken
+	}
+	return nil
+}
+
+// scorerURL is the score page link printed on a match's card, or on the card
+// of one side.
+func scorerURL(r *http.Request, matchID int, side, token string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	url := fmt.Sprintf("%s://%s/static/score.html?match=%d&token=%s", scheme, r.Host, matchID, token)
+	if side != "" {
+		url += "&side=" + side
+	}
+	return url
+}
+
+// validTokenSide reports whether side names a scorer token: "" for the
+// match's card, A or B for the card of one side.
+func validTokenSide(side string) bool {
+	return side == "" || side == "A" || side == "B"
+}
+
+// --- Scorer Token Handlers ---
+
+// MatchToken returns the scorer token of a match with its score page link on
+// GET, and replaces it with a new one on POST, invalidating printed cards.
+// With a side, A or B, it is the token of that side's card.
+func (srv *Server) MatchToken(w http.ResponseWriter, r *http.Request) {
+	var matchID int
+	var side string
+	switch r.Method {
+	case http.MethodGet:
+		matchID, _ = strconv.Atoi(r.URL.Query().Get("match_id"))
+		side = r.URL.Query().Get("side")
+	case http.MethodPost:
+		var body struct {
+			MatchID int    `json:"match_id"`
+			Side    string `json:"side"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		matchID, side = body.MatchID, body.Side
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !validTokenSide(side) {
+		http.Error(w, "Invalid side", http.StatusBadRequest)
+		return
+	}
+	token, err := srv.store.ScorerToken(matchID, side)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if r.Method == http.MethodPost || token == "" {
+		token = newToken()
+		if err := srv.store.SetScorerToken(matchID, side, token); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"match_id": matchID, "side": side, "token": token, "url": scorerURL(r, matchID, side, token)})
+}
+
+// MatchQRCode serves the score page link of a match, or of one side's card,
+// as a PNG QR code.
+func (srv *Server) MatchQRCode(w http.ResponseWriter, r *http.Request) {
+	matchID, _ := strconv.Atoi(r.URL.Query().Get("match_id"))
+	side := r.URL.Query().Get("side")
+	if !validTokenSide(side) {
+		http.Error(w, "Invalid side", http.StatusBadRequest)
+		return
+	}
+	token, err := srv.store.ScorerToken(matchID, side)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if token == "" {
+		http.Error(w, "Match has no scorer token", http.StatusNotFound)
+		return
+	}
+	png, err := qrcode.Encode(scorerURL(r, matchID, side, token), qrcode.Medium, 256)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(png)
+}
@@ -0,0 +1,237 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Roles of the users.
+const (
+	RoleAdmin   = "admin"   // manages everything
+	RoleCaptain = "captain" // manages the roster and lineups of their team
+	RoleScorer  = "scorer"  // enters the scores of their assigned matches
+	RoleViewer  = "viewer"  // reads only
+)
+
+var roles = map[string]bool{RoleAdmin: true, RoleCaptain: true, RoleScorer: true, RoleViewer: true}
+
+// minPasswordLength is the shortest password AddUser and EditUser accept.
+const minPasswordLength = 8
+
+// User is a login of the admin pages. TeamID is the team a captain manages,
+// MatchIDs the matches a scorer may enter scores for.
+type User struct {
+	ID           int    `json:"id"`
+	Username     string `json:"username"`
+	Role         string `json:"role"`
+	TeamID       *int   `json:"team_id,omitempty"`
+	MatchIDs     []int  `json:"match_ids,omitempty"`
+	PasswordHash string `json:"-"`
+}
+
+// hashPassword returns the bcrypt hash of a password.
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// userRequest is the body of AddUser and EditUser, the password of an existing
+// user only changes when it is given.
+type userRequest struct {
+	User
+	Password string `json:"password"`
+}
+
+// validateUser checks a user's name, role, password and the team or matches
+// of the role, and drops the ones the role doesn't use.
+func (srv *Server) validateUser(body *userRequest) error {
+	body.Username = strings.TrimSpace(body.Username)
+	if body.Username == "" {
+		return badRequest{errors.New("username is required")}
+	}
+	if !roles[body.Role] {
+		return badRequest{errors.New("unknown role " + body.Role)}
+	}
+	if (body.ID == 0 || body.Password != "") && len(body.Password) < minPasswordLength {
+		return badRequest{errors.New("password must have at least " + strconv.Itoa(minPasswordLength) + " characters")}
+	}
+	if other, err := srv.store.UserByName(body.Username); err == nil && other.ID != body.ID {
+		return conflict{errors.New("username " + body.Username + " is taken")}
+	} else if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if body.Role != RoleCaptain {
+		body.TeamID = nil
+	} else if body.TeamID == nil {
+		return badRequest{errors.New("captains need a team")}
+	} else if _, err := srv.store.GetTeam(*body.TeamID); errors.Is(err, ErrNotFound) {
+		return badRequest{errors.New("unknown team")}
+	} else if err != nil {
+		return err
+	}
+	if body.Role != RoleScorer {
+		body.MatchIDs = nil
+	}
+	for _, id := range body.MatchIDs {
+		if _, err := srv.store.GetMatch(id); errors.Is(err, ErrNotFound) {
+			return badRequest{errors.New("unknown match " + strconv.Itoa(id))}
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkKeepsAdmin returns a conflict when removing or demoting the user would
+// leave nobody to manage the users.
+func (srv *Server) checkKeepsAdmin(userID int) error {
+	users, err := srv.store.ListUsers()
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		if u.Role == RoleAdmin && u.ID != userID {
+			return nil
+		}
+	}
+	return conflict{errors.New("the last admin can't be removed")}
+}
+
+// --- User Handlers ---
+func (srv *Server) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := srv.store.ListUsers()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i, u := range users {
+		if u.Role != RoleScorer {
+			continue
+		}
+		if users[i].MatchIDs, err = srv.store.UserMatches(u.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"users": users}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (srv *Server) AddUser(w http.ResponseWriter, r *http.Request) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body.ID = 0
+	if err := srv.validateUser(&body); err != nil {
+		writeError(w, err)
+		return
+	}
+	u := body.User
+	var err error
+	if u.PasswordHash, err = hashPassword(body.Password); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = srv.store.Tx(func(tx Store) error {
+		if err := tx.AddUser(&u); err != nil {
+			return err
+		}
+		return tx.SetUserMatches(u.ID, u.MatchIDs)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	u.PasswordHash = ""
+	if err := json.NewEncoder(w).Encode(u); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// EditUser changes a user's name, role, team or matches, and their password
+// when one is given. A new password ends the user's other logins, the one of
+// the request stays when users change their own.
+func (srv *Server) EditUser(w http.ResponseWriter, r *http.Request) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	old, err := srv.store.GetUser(body.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := srv.validateUser(&body); err != nil {
+		writeError(w, err)
+		return
+	}
+	if old.Role == RoleAdmin && body.Role != RoleAdmin {
+		if err := srv.checkKeepsAdmin(old.ID); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	u := body.User
+	u.PasswordHash = ""
+	if body.Password != "" {
+		if u.PasswordHash, err = hashPassword(body.Password); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	err = srv.store.Tx(func(tx Store) error {
+		if err := tx.UpdateUser(u); err != nil {
+			return err
+		}
+		if u.PasswordHash != "" {
+			var keep string
+			if me := currentUser(r); me != nil && me.ID == u.ID {
+				if c, err := r.Cookie(loginCookie); err == nil {
+					keep = hashToken(c.Value)
+				}
+			}
+			if err := tx.RemoveUserLogins(u.ID, keep); err != nil {
+				return err
+			}
+		}
+		return tx.SetUserMatches(u.ID, u.MatchIDs)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	u.PasswordHash = ""
+	if err := json.NewEncoder(w).Encode(u); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (srv *Server) RemoveUser(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	u, err := srv.store.GetUser(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if u.Role == RoleAdmin {
+		if err := srv.checkKeepsAdmin(id); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if err := srv.store.RemoveUser(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
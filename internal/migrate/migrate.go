@@ -0,0 +1,329 @@
+// Package migrate applies the versioned schema migrations embedded in the
+// binary and records them in the schema_migrations table.
+package migrate
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tests/copilot/ryder/migrations"
+)
+
+// legacyVersion is the schema autoMigrate created before migrations were
+// versioned; such SQLite databases are adopted at this version.
+const legacyVersion = 8
+
+// Migration is one migration file, NNN_name.sql.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status is a migration together with its state in the database.
+type Status struct {
+	Migration
+	AppliedAt string // empty while pending
+	Changed   bool   // the file differs from the one that was applied
+}
+
+// Migrator applies migrations to one database.
+type Migrator struct {
+	db         *sql.DB
+	postgres   bool
+	migrations []Migration
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.sql$`)
+
+// Load reads the migration files of a directory, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	var list []Migration
+	seen := map[int]string{}
+	for _, e := range entries {
+		parts := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || parts == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(parts[1])
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", other, e.Name())
+		}
+		seen[version] = e.Name()
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		m, err := parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		m.Version = version
+		m.Name = parts[2]
+		sum := sha256.Sum256(data)
+		m.Checksum = hex.EncodeToString(sum[:])
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// parse splits a file into its -- +migrate Up and -- +migrate Down sections.
+func parse(data string) (Migration, error) {
+	var m Migration
+	var up, down strings.Builder
+	var section *strings.Builder
+	sc := bufio.NewScanner(strings.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		switch strings.TrimSpace(line) {
+		case "-- +migrate Up":
+			section = &up
+			continue
+		case "-- +migrate Down":
+			section = &down
+			continue
+		}
+		if section == nil {
+			if strings.TrimSpace(line) != "" && !strings.HasPrefix(strings.TrimSpace(line), "--") {
+				return m, fmt.Errorf("statement before -- +migrate Up")
+			}
+			continue
+		}
+		section.WriteString(line + "\n")
+	}
+	m.Up = strings.TrimSpace(up.String())
+	m.Down = strings.TrimSpace(down.String())
+	if m.Up == "" {
+		return m, fmt.Errorf("missing -- +migrate Up section")
+	}
+	return m, nil
+}
+
+// New returns a Migrator for the embedded migrations of the driver, "sqlite3"
+// or "postgres", and creates schema_migrations when needed.
+func New(db *sql.DB, driver string) (*Migrator, error) {
+	var fsys fs.FS = migrations.SQLite
+	if driver == "postgres" {
+		fsys = migrations.Postgres
+	}
+	list, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	m := &Migrator{db: db, postgres: driver == "postgres", migrations: list}
+	if err := m.init(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// arg returns the n-th placeholder (from 1) of the driver.
+func (m *Migrator) arg(n int) string {
+	if m.postgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+func (m *Migrator) init() error {
+	legacy := false
+	if !m.postgres {
+		var tables int
+		err := m.db.QueryRow(`SELECT
+			(SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='players') -
+			(SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='schema_migrations')`).Scan(&tables)
+		if err != nil {
+			return err
+		}
+		legacy = tables > 0
+	}
+	_, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+	if legacy {
+		return m.adoptLegacy()
+	}
+	return nil
+}
+
+// adoptLegacy records the migrations up to legacyVersion on a database that
+// autoMigrate created. Databases last run by an older binary may miss some of
+// the later columns, so every statement is replayed first and the errors of
+// the ones already applied are ignored, as autoMigrate did.
+func (m *Migrator) adoptLegacy() error {
+	for _, mig := range m.migrations {
+		if mig.Version > legacyVersion {
+			break
+		}
+		for _, stmt := range strings.Split(mig.Up, ";") {
+			if strings.TrimSpace(stmt) != "" {
+				_, _ = m.db.Exec(stmt)
+			}
+		}
+		if err := m.record(m.db, mig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execer is a *sql.DB or *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// record marks a migration as applied.
+func (m *Migrator) record(ex execer, mig Migration) error {
+	_, err := ex.Exec(fmt.Sprintf("INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (%s, %s, %s, %s)",
+		m.arg(1), m.arg(2), m.arg(3), m.arg(4)), mig.Version, mig.Name, mig.Checksum, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// Status lists every known migration with whether and when it was applied.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var list []Status
+	for _, mig := range m.migrations {
+		st := Status{Migration: mig}
+		if a, ok := applied[mig.Version]; ok {
+			st.AppliedAt = a.AppliedAt
+			st.Changed = a.Checksum != mig.Checksum
+		}
+		list = append(list, st)
+	}
+	return list, nil
+}
+
+// applied returns the rows of schema_migrations by version.
+func (m *Migrator) applied() (map[int]Status, error) {
+	rows, err := m.db.Query("SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int]Status{}
+	for rows.Next() {
+		var st Status
+		if err := rows.Scan(&st.Version, &st.Name, &st.Checksum, &st.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[st.Version] = st
+	}
+	return applied, rows.Err()
+}
+
+// verify fails when an applied migration was changed since or is unknown to
+// this binary, and returns the applied versions otherwise.
+func (m *Migrator) verify() (map[int]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	known := map[int]Migration{}
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+	for v, a := range applied {
+		mig, ok := known[v]
+		if !ok {
+			return nil, fmt.Errorf("migration %03d_%s is applied but unknown to this binary", v, a.Name)
+		}
+		if mig.Checksum != a.Checksum {
+			return nil, fmt.Errorf("migration %03d_%s was changed after it was applied", v, mig.Name)
+		}
+	}
+	return applied, nil
+}
+
+// Up applies all pending migrations in order, each in its own transaction,
+// and returns the ones it applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.verify()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		err := m.inTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(mig.Up); err != nil {
+				return err
+			}
+			return m.record(tx, mig)
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %03d_%s: %w", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down rolls back the last n applied migrations and returns them.
+func (m *Migrator) Down(n int) ([]Migration, error) {
+	applied, err := m.verify()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < n; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == "" {
+			return done, fmt.Errorf("migration %03d_%s has no down section", mig.Version, mig.Name)
+		}
+		err := m.inTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(mig.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec("DELETE FROM schema_migrations WHERE version="+m.arg(1), mig.Version)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %03d_%s: %w", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+func (m *Migrator) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
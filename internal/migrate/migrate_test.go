@@ -0,0 +1,241 @@
+package migrate
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/tests/copilot/ryder/migrations"
+)
+
+// openSQLite opens an empty SQLite database in a temporary file.
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "ryder.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newMigrator(t *testing.T, db *sql.DB) *Migrator {
+	t.Helper()
+	m, err := New(db, "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// columns returns the columns of a table, nil when there is no such table.
+func columns(t *testing.T, db *sql.DB, table string) []string {
+	t.Helper()
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// applied returns the applied versions, in order.
+func applied(t *testing.T, m *Migrator) []int {
+	t.Helper()
+	list, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int
+	for _, st := range list {
+		if st.AppliedAt != "" {
+			versions = append(versions, st.Version)
+		}
+	}
+	return versions
+}
+
+func TestUpDown(t *testing.T) {
+	all, err := Load(migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := openSQLite(t)
+	m := newMigrator(t, db)
+	done, err := m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != len(all) {
+		t.Fatalf("up: applied %d migrations, want %d", len(done), len(all))
+	}
+	if done, err := m.Up(); err != nil || len(done) != 0 {
+		t.Fatalf("second up: applied %d (%v), want none", len(done), err)
+	}
+
+	// Reverting 004 drops the column it added, the results stay
+	if _, err := db.Exec("INSERT INTO hole_results (match_id, hole, result, source) VALUES (1, 1, 'A', 'strokes')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Down(len(all) - 3); err != nil {
+		t.Fatal(err)
+	}
+	if got := applied(t, m); len(got) != 3 || got[2] != 3 {
+		t.Fatalf("applied after down: got %v, want 1 to 3", got)
+	}
+	if got := strings.Join(columns(t, db, "hole_results"), ","); got != "match_id,hole,result" {
+		t.Errorf("hole_results after reverting 004: got columns %s", got)
+	}
+	var result string
+	if err := db.QueryRow("SELECT result FROM hole_results WHERE match_id=1 AND hole=1").Scan(&result); err != nil || result != "A" {
+		t.Errorf("hole result after reverting 004: got %q (%v), want A", result, err)
+	}
+
+	// Back up, and all the way down
+	if done, err := m.Up(); err != nil || len(done) != len(all)-3 {
+		t.Fatalf("up again: applied %d (%v), want %d", len(done), err, len(all)-3)
+	}
+	if done, err := m.Down(len(all)); err != nil || len(done) != len(all) {
+		t.Fatalf("down all: reverted %d (%v), want %d", len(done), err, len(all))
+	}
+	for _, table := range []string{"players", "matches", "users", "match_events"} {
+		if got := columns(t, db, table); got != nil {
+			t.Errorf("table %s after reverting everything: got columns %v", table, got)
+		}
+	}
+	if done, err := m.Down(1); err != nil || len(done) != 0 {
+		t.Errorf("down on an empty database: reverted %d (%v), want none", len(done), err)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	db := openSQLite(t)
+	if _, err := newMigrator(t, db).Up(); err != nil {
+		t.Fatal(err)
+	}
+	// As if 005 was edited after it was applied
+	if _, err := db.Exec("UPDATE schema_migrations SET checksum='edited' WHERE version=5"); err != nil {
+		t.Fatal(err)
+	}
+	m := newMigrator(t, db)
+	if _, err := m.Up(); err == nil || !strings.Contains(err.Error(), "005_add_courses was changed") {
+		t.Errorf("up: got %v, want the changed migration", err)
+	}
+	if _, err := m.Down(1); err == nil {
+		t.Error("down: got no error, want the changed migration")
+	}
+	list, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range list {
+		if st.Changed != (st.Version == 5) {
+			t.Errorf("status of %03d: got changed %v", st.Version, st.Changed)
+		}
+	}
+
+	// A migration this binary doesn't know stops it too
+	if _, err := db.Exec("UPDATE schema_migrations SET checksum=? WHERE version=5", list[4].Checksum); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (999, 'future', 'x', '2026-01-01T00:00:00Z')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(); err == nil || !strings.Contains(err.Error(), "unknown to this binary") {
+		t.Errorf("up with a newer database: got %v, want the unknown migration", err)
+	}
+}
+
+func TestAdoptLegacy(t *testing.T) {
+	db := openSQLite(t)
+	// A database an older autoMigrate made: some of the later columns and
+	// tables are missing
+	for _, stmt := range []string{
+		"CREATE TABLE players (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, email TEXT, hcp REAL)",
+		"CREATE TABLE teams (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, color TEXT DEFAULT '#2563eb')",
+		"CREATE TABLE matches (id INTEGER PRIMARY KEY AUTOINCREMENT, team_a_id INTEGER, team_b_id INTEGER, format TEXT NOT NULL, status TEXT NOT NULL, holes TEXT DEFAULT '18')",
+		"INSERT INTO players (name, hcp) VALUES ('Alice', 10)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := newMigrator(t, db)
+	got := applied(t, m)
+	if len(got) != legacyVersion || got[len(got)-1] != legacyVersion {
+		t.Fatalf("adopted: got %v, want 1 to %d", got, legacyVersion)
+	}
+	for _, col := range []string{"start_time", "course_id", "points", "event_id", "session_id"} {
+		if !strings.Contains(","+strings.Join(columns(t, db, "matches"), ",")+",", ","+col+",") {
+			t.Errorf("matches has no %s after the adoption", col)
+		}
+	}
+	done, err := m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) == 0 || done[0].Version != legacyVersion+1 {
+		t.Errorf("up after the adoption: got %v, want from %d on", done, legacyVersion+1)
+	}
+	var name string
+	if err := db.QueryRow("SELECT name FROM players WHERE id=1").Scan(&name); err != nil || name != "Alice" {
+		t.Errorf("legacy player: got %q (%v), want Alice", name, err)
+	}
+	// A database with schema_migrations is never adopted again
+	if got := applied(t, newMigrator(t, db)); len(got) != legacyVersion+len(done) {
+		t.Errorf("applied after a restart: got %v", got)
+	}
+}
+
+func TestPointsDefaultDropped(t *testing.T) {
+	db := openSQLite(t)
+	m := newMigrator(t, db)
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	// Back to the schema that gave every match 1 point
+	if _, err := m.Down(1); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO matches (id, format, status) VALUES (1, 'singles', 'prepared')",
+		"INSERT INTO matches (id, format, status, points) VALUES (2, 'singles', 'prepared', 2)",
+		"INSERT INTO matches (id, format, status, points) VALUES (3, 'singles', 'prepared', NULL)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO matches (id, format, status) VALUES (4, 'singles', 'prepared')"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]string{1: "NULL", 2: "2", 3: "NULL", 4: "NULL"}
+	for id, points := range want {
+		var got sql.NullFloat64
+		if err := db.QueryRow("SELECT points FROM matches WHERE id=?", id).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		s := "NULL"
+		if got.Valid {
+			s = strconv.FormatFloat(got.Float64, 'f', -1, 64)
+		}
+		if s != points {
+			t.Errorf("match %d: got points %s, want %s", id, s, points)
+		}
+	}
+}
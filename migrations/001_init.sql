@@ -2,13 +2,14 @@
 CREATE TABLE IF NOT EXISTS players (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     name TEXT NOT NULL,
-    email TEXT
+    email TEXT,
+    hcp REAL
 );
 
 CREATE TABLE IF NOT EXISTS teams (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     name TEXT NOT NULL,
-    barva TEXT DEFAULT '#2563eb'
+    color TEXT DEFAULT '#2563eb'
 );
 
 CREATE TABLE IF NOT EXISTS team_players (
@@ -25,7 +26,6 @@ CREATE TABLE IF NOT EXISTS matches (
     team_b_id INTEGER,
     format TEXT NOT NULL,
     status TEXT NOT NULL,
-    start_time TEXT,
     FOREIGN KEY (team_a_id) REFERENCES teams(id),
     FOREIGN KEY (team_b_id) REFERENCES teams(id)
 );
@@ -1,3 +1,5 @@
--- Migration: Add start_time to matches
-ALTER TABLE matches ADD COLUMN start_time TEXT;
+-- +migrate Up
 -- Format: h:mm (string, no calculation needed)
+ALTER TABLE matches ADD COLUMN start_time TEXT;
+-- +migrate Down
+ALTER TABLE matches DROP COLUMN start_time;
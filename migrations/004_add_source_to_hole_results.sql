@@ -0,0 +1,10 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS hole_results (
+    match_id INTEGER NOT NULL,
+    hole INTEGER NOT NULL,
+    result TEXT,
+    PRIMARY KEY (match_id, hole)
+);
+ALTER TABLE hole_results ADD COLUMN source TEXT DEFAULT 'manual';
+-- +migrate Down
+ALTER TABLE hole_results DROP COLUMN source;
@@ -0,0 +1,43 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS courses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS course_holes (
+    course_id INTEGER NOT NULL,
+    hole INTEGER NOT NULL,
+    par INTEGER NOT NULL,
+    stroke_index INTEGER NOT NULL,
+    PRIMARY KEY (course_id, hole),
+    FOREIGN KEY (course_id) REFERENCES courses(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS tees (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    course_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    color TEXT,
+    course_rating REAL,
+    slope INTEGER,
+    FOREIGN KEY (course_id) REFERENCES courses(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS tee_holes (
+    tee_id INTEGER NOT NULL,
+    hole INTEGER NOT NULL,
+    yards INTEGER NOT NULL,
+    PRIMARY KEY (tee_id, hole),
+    FOREIGN KEY (tee_id) REFERENCES tees(id) ON DELETE CASCADE
+);
+
+ALTER TABLE matches ADD COLUMN course_id INTEGER REFERENCES courses(id);
+ALTER TABLE matches ADD COLUMN tee_id INTEGER REFERENCES tees(id);
+
+-- +migrate Down
+ALTER TABLE matches DROP COLUMN tee_id;
+ALTER TABLE matches DROP COLUMN course_id;
+DROP TABLE IF EXISTS tee_holes;
+DROP TABLE IF EXISTS tees;
+DROP TABLE IF EXISTS course_holes;
+DROP TABLE IF EXISTS courses;
@@ -0,0 +1,4 @@
+-- +migrate Up
+ALTER TABLE matches ADD COLUMN points REAL DEFAULT 1;
+-- +migrate Down
+ALTER TABLE matches DROP COLUMN points;
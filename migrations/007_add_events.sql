@@ -0,0 +1,19 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    start_date TEXT,
+    archived INTEGER NOT NULL DEFAULT 0
+);
+
+ALTER TABLE teams ADD COLUMN event_id INTEGER REFERENCES events(id);
+ALTER TABLE matches ADD COLUMN event_id INTEGER REFERENCES events(id);
+
+INSERT INTO events (name) SELECT 'Ryder Cup' WHERE NOT EXISTS (SELECT 1 FROM events);
+UPDATE teams SET event_id = (SELECT MIN(id) FROM events) WHERE event_id IS NULL;
+UPDATE matches SET event_id = (SELECT event_id FROM teams WHERE teams.id = matches.team_a_id) WHERE event_id IS NULL;
+
+-- +migrate Down
+ALTER TABLE matches DROP COLUMN event_id;
+ALTER TABLE teams DROP COLUMN event_id;
+DROP TABLE IF EXISTS events;
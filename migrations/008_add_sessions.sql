@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL REFERENCES events(id),
+    name TEXT NOT NULL,
+    date TEXT,
+    format TEXT,
+    position INTEGER NOT NULL DEFAULT 0,
+    points REAL,
+    locked INTEGER NOT NULL DEFAULT 0
+);
+
+ALTER TABLE matches ADD COLUMN session_id INTEGER REFERENCES sessions(id);
+
+-- +migrate Down
+ALTER TABLE matches DROP COLUMN session_id;
+DROP TABLE IF EXISTS sessions;
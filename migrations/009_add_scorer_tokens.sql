@@ -0,0 +1,6 @@
+-- +migrate Up
+ALTER TABLE matches ADD COLUMN scorer_token TEXT;
+UPDATE matches SET scorer_token = lower(hex(randomblob(16))) WHERE scorer_token IS NULL;
+
+-- +migrate Down
+ALTER TABLE matches DROP COLUMN scorer_token;
@@ -0,0 +1,28 @@
+-- +migrate Up
+-- Entries are only ever added, and outlive the matches they are about
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    match_id INTEGER NOT NULL,
+    actor TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    field TEXT NOT NULL, -- status, points, hole or strokes
+    hole INTEGER,
+    player_id INTEGER,
+    old_value TEXT NOT NULL,
+    new_value TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_match ON audit_log(match_id);
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+-- +migrate Down
+DROP TABLE IF EXISTS audit_log;
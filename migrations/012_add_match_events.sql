@@ -0,0 +1,45 @@
+-- +migrate Up
+-- The history of every match as an append-only stream of events. Hole
+-- results, strokes, status and concessions are a projection of it.
+CREATE TABLE IF NOT EXISTS match_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    match_id INTEGER NOT NULL,
+    action INTEGER NOT NULL, -- events of one save share it, 0 is the state from before the stream
+    type TEXT NOT NULL, -- hole_set, hole_cleared, strokes_set, match_conceded, status_changed, undo
+    hole INTEGER,
+    player_id INTEGER,
+    value TEXT NOT NULL DEFAULT '',
+    source TEXT NOT NULL DEFAULT '',
+    actor TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS match_events_match ON match_events(match_id, id);
+
+CREATE TRIGGER IF NOT EXISTS match_events_no_update BEFORE UPDATE ON match_events
+BEGIN
+    SELECT RAISE(ABORT, 'match_events is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS match_events_no_delete BEFORE DELETE ON match_events
+BEGIN
+    SELECT RAISE(ABORT, 'match_events is append-only');
+END;
+
+ALTER TABLE matches ADD COLUMN conceded TEXT; -- the side that conceded the match
+
+INSERT INTO match_events (match_id, action, type, hole, value, source, actor, created_at)
+    SELECT match_id, 0, 'hole_set', hole, result, COALESCE(source, 'manual'), 'import', strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+    FROM hole_results WHERE result IS NOT NULL AND result <> '' AND hole BETWEEN 1 AND 18 ORDER BY match_id, hole;
+
+INSERT INTO match_events (match_id, action, type, hole, player_id, value, actor, created_at)
+    SELECT match_id, 0, 'strokes_set', hole, player_id, CAST(strokes AS TEXT), 'import', strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+    FROM scores WHERE strokes > 0 ORDER BY match_id, hole, player_id;
+
+INSERT INTO match_events (match_id, action, type, value, actor, created_at)
+    SELECT id, 0, 'status_changed', status, 'import', strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+    FROM matches WHERE status IS NOT NULL AND status <> 'prepared' ORDER BY id;
+
+-- +migrate Down
+ALTER TABLE matches DROP COLUMN conceded;
+DROP TABLE IF EXISTS match_events;
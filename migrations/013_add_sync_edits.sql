@@ -0,0 +1,13 @@
+-- +migrate Up
+-- The outcome of every hole edit synced by a score page, by the key the page
+-- gave it, so retries of an edit are answered without applying it again.
+CREATE TABLE IF NOT EXISTS sync_edits (
+    match_id INTEGER NOT NULL,
+    edit_key TEXT NOT NULL,
+    outcome TEXT NOT NULL, -- the JSON the edit was answered with
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (match_id, edit_key)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS sync_edits;
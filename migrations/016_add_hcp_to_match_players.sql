@@ -0,0 +1,6 @@
+-- +migrate Up
+ALTER TABLE match_players ADD COLUMN hcp REAL;
+UPDATE match_players SET hcp = (SELECT hcp FROM players WHERE players.id = match_players.player_id);
+
+-- +migrate Down
+ALTER TABLE match_players DROP COLUMN hcp;
@@ -0,0 +1,15 @@
+-- +migrate Up
+-- A match without points of its own scores its session's, see matchPoints.
+-- Before sessions every match was given 1 point, which would keep their
+-- session's from counting: those 1s are cleared. SQLite can't drop a
+-- default, the column is replaced by one without.
+ALTER TABLE matches ADD COLUMN points_new REAL;
+UPDATE matches SET points_new = points WHERE points <> 1;
+ALTER TABLE matches DROP COLUMN points;
+ALTER TABLE matches RENAME COLUMN points_new TO points;
+
+-- +migrate Down
+ALTER TABLE matches ADD COLUMN points_old REAL DEFAULT 1;
+UPDATE matches SET points_old = points;
+ALTER TABLE matches DROP COLUMN points;
+ALTER TABLE matches RENAME COLUMN points_old TO points;
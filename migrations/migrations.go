@@ -0,0 +1,18 @@
+// Package migrations embeds the versioned schema migrations. The files in this
+// directory are for SQLite, the ones in postgres/ for PostgreSQL. Each file is
+// named NNN_name.sql and has -- +migrate Up and -- +migrate Down sections.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.sql
+var SQLite embed.FS
+
+//go:embed postgres/*.sql
+var postgres embed.FS
+
+// Postgres holds the PostgreSQL migrations.
+var Postgres, _ = fs.Sub(postgres, "postgres")
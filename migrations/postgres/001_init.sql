@@ -0,0 +1,123 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS events (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    start_date TEXT,
+    archived BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id),
+    name TEXT NOT NULL,
+    date TEXT,
+    format TEXT,
+    position INTEGER NOT NULL DEFAULT 0,
+    points DOUBLE PRECISION,
+    locked BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS players (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    email TEXT,
+    hcp DOUBLE PRECISION
+);
+
+CREATE TABLE IF NOT EXISTS teams (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER REFERENCES events(id),
+    name TEXT NOT NULL,
+    color TEXT DEFAULT '#2563eb'
+);
+
+CREATE TABLE IF NOT EXISTS team_players (
+    team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    PRIMARY KEY (team_id, player_id)
+);
+
+CREATE TABLE IF NOT EXISTS courses (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS course_holes (
+    course_id INTEGER NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    hole INTEGER NOT NULL,
+    par INTEGER NOT NULL,
+    stroke_index INTEGER NOT NULL,
+    PRIMARY KEY (course_id, hole)
+);
+
+CREATE TABLE IF NOT EXISTS tees (
+    id SERIAL PRIMARY KEY,
+    course_id INTEGER NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    color TEXT,
+    course_rating DOUBLE PRECISION,
+    slope INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS tee_holes (
+    tee_id INTEGER NOT NULL REFERENCES tees(id) ON DELETE CASCADE,
+    hole INTEGER NOT NULL,
+    yards INTEGER NOT NULL,
+    PRIMARY KEY (tee_id, hole)
+);
+
+CREATE TABLE IF NOT EXISTS matches (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER REFERENCES events(id),
+    session_id INTEGER REFERENCES sessions(id),
+    team_a_id INTEGER REFERENCES teams(id),
+    team_b_id INTEGER REFERENCES teams(id),
+    format TEXT NOT NULL,
+    status TEXT NOT NULL,
+    start_time TEXT,
+    holes TEXT DEFAULT '18',
+    course_id INTEGER REFERENCES courses(id),
+    tee_id INTEGER REFERENCES tees(id),
+    points DOUBLE PRECISION DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS match_players (
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    team_side TEXT NOT NULL, -- 'A' or 'B'
+    seq SERIAL, -- insertion order, rowid in SQLite
+    PRIMARY KEY (match_id, player_id)
+);
+
+CREATE TABLE IF NOT EXISTS scores (
+    id SERIAL PRIMARY KEY,
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    hole INTEGER NOT NULL,
+    strokes INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS hole_results (
+    match_id INTEGER NOT NULL,
+    hole INTEGER NOT NULL,
+    result TEXT,
+    source TEXT DEFAULT 'manual',
+    PRIMARY KEY (match_id, hole)
+);
+
+INSERT INTO events (name) SELECT 'Ryder Cup' WHERE NOT EXISTS (SELECT 1 FROM events);
+
+-- +migrate Down
+DROP TABLE IF EXISTS hole_results;
+DROP TABLE IF EXISTS scores;
+DROP TABLE IF EXISTS match_players;
+DROP TABLE IF EXISTS matches;
+DROP TABLE IF EXISTS tee_holes;
+DROP TABLE IF EXISTS tees;
+DROP TABLE IF EXISTS course_holes;
+DROP TABLE IF EXISTS courses;
+DROP TABLE IF EXISTS team_players;
+DROP TABLE IF EXISTS teams;
+DROP TABLE IF EXISTS players;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS events;
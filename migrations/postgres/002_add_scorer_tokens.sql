@@ -0,0 +1,6 @@
+-- +migrate Up
+ALTER TABLE matches ADD COLUMN scorer_token TEXT;
+UPDATE matches SET scorer_token = md5(random()::text || id::text) WHERE scorer_token IS NULL;
+
+-- +migrate Down
+ALTER TABLE matches DROP COLUMN scorer_token;
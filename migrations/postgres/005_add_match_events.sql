@@ -0,0 +1,45 @@
+-- +migrate Up
+-- The history of every match as an append-only stream of events. Hole
+-- results, strokes, status and concessions are a projection of it.
+CREATE TABLE IF NOT EXISTS match_events (
+    id SERIAL PRIMARY KEY,
+    match_id INTEGER NOT NULL,
+    action INTEGER NOT NULL, -- events of one save share it, 0 is the state from before the stream
+    type TEXT NOT NULL, -- hole_set, hole_cleared, strokes_set, match_conceded, status_changed, undo
+    hole INTEGER,
+    player_id INTEGER,
+    value TEXT NOT NULL DEFAULT '',
+    source TEXT NOT NULL DEFAULT '',
+    actor TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS match_events_match ON match_events(match_id, id);
+
+CREATE OR REPLACE FUNCTION match_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'match_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER match_events_append_only BEFORE UPDATE OR DELETE ON match_events
+    FOR EACH ROW EXECUTE FUNCTION match_events_append_only();
+
+ALTER TABLE matches ADD COLUMN conceded TEXT; -- the side that conceded the match
+
+INSERT INTO match_events (match_id, action, type, hole, value, source, actor, created_at)
+    SELECT match_id, 0, 'hole_set', hole, result, COALESCE(source, 'manual'), 'import', to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+    FROM hole_results WHERE result IS NOT NULL AND result <> '' AND hole BETWEEN 1 AND 18 ORDER BY match_id, hole;
+
+INSERT INTO match_events (match_id, action, type, hole, player_id, value, actor, created_at)
+    SELECT match_id, 0, 'strokes_set', hole, player_id, strokes::text, 'import', to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+    FROM scores WHERE strokes > 0 ORDER BY match_id, hole, player_id;
+
+INSERT INTO match_events (match_id, action, type, value, actor, created_at)
+    SELECT id, 0, 'status_changed', status, 'import', to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+    FROM matches WHERE status IS NOT NULL AND status <> 'prepared' ORDER BY id;
+
+-- +migrate Down
+ALTER TABLE matches DROP COLUMN conceded;
+DROP TABLE IF EXISTS match_events;
+DROP FUNCTION IF EXISTS match_events_append_only();
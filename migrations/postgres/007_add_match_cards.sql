@@ -0,0 +1,31 @@
+-- +migrate Up
+-- The hole results the scorer of each side of a match entered. A hole counts
+-- once both sides entered the same result, see SubmitSideResults.
+CREATE TABLE IF NOT EXISTS side_hole_results (
+    match_id INTEGER NOT NULL,
+    side TEXT NOT NULL, -- A or B
+    hole INTEGER NOT NULL,
+    result TEXT NOT NULL, -- A, B or AS
+    actor TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (match_id, side, hole)
+);
+
+-- The sign-offs of the final cards, with the hole results as they were signed.
+CREATE TABLE IF NOT EXISTS card_signatures (
+    id SERIAL PRIMARY KEY,
+    match_id INTEGER NOT NULL,
+    side TEXT NOT NULL,
+    player_id INTEGER, -- the player who signed, NULL for captains
+    signer TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    holes TEXT NOT NULL, -- the 18 results, comma separated
+    result TEXT NOT NULL,
+    signed_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS card_signatures_match ON card_signatures(match_id, id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS card_signatures;
+DROP TABLE IF EXISTS side_hole_results;
@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE matches ADD COLUMN scorer_token_a TEXT;
+ALTER TABLE matches ADD COLUMN scorer_token_b TEXT;
+UPDATE matches SET scorer_token_a = md5(random()::text || id::text || 'A') WHERE scorer_token_a IS NULL;
+UPDATE matches SET scorer_token_b = md5(random()::text || id::text || 'B') WHERE scorer_token_b IS NULL;
+
+-- +migrate Down
+ALTER TABLE matches DROP COLUMN scorer_token_b;
+ALTER TABLE matches DROP COLUMN scorer_token_a;
@@ -0,0 +1,12 @@
+-- +migrate Up
+-- The results the sides entered on their cards are part of the history of
+-- a match too. The ones entered before are imported as its state from before
+-- the stream, the event's source is the side.
+INSERT INTO match_events (match_id, action, type, hole, value, source, actor, created_at)
+    SELECT match_id, 0, 'side_set', hole, result, side, 'import', to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+    FROM side_hole_results WHERE result <> '' AND hole BETWEEN 1 AND 18 ORDER BY match_id, side, hole;
+
+-- +migrate Down
+-- match_events is append-only, the imported events stay. Older versions skip
+-- the events they don't know.
+SELECT 1;
@@ -0,0 +1,6 @@
+-- +migrate Up
+-- A match without points of its own scores its session's, see matchPoints.
+ALTER TABLE matches ALTER COLUMN points DROP DEFAULT;
+
+-- +migrate Down
+ALTER TABLE matches ALTER COLUMN points SET DEFAULT 1;
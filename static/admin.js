@@ -1,7 +1,7 @@
 // --- Edit Match Handler ---
 window.editMatch = async function(matchId) {
     // Fetch match list and find the match by ID
-    const res = await fetch('/api/match/list');
+    const res = await fetch(withEvent('/api/match/list'));
     if (!res.ok) return;
     const data = await res.json();
     const match = (data.matches || []).find(m => m.id === matchId);
@@ -13,6 +13,12 @@ window.editMatch = async function(matchId) {
     document.getElementById('match-team-a').value = match.team_a.id;
     document.getElementById('match-team-b').value = match.team_b.id;
     document.getElementById('match-holes').value = match.holes || '18';
+    document.getElementById('match-session').value = match.session_id || '';
+    // Only the match's own points, empty keeps it on its session's
+    document.getElementById('match-points').value = match.own_points ?? '';
+    document.getElementById('match-course').value = match.course_id || '';
+    updateMatchTeeSelect();
+    document.getElementById('match-tee').value = match.tee_id || '';
     await updateMatchPlayersSelects();
     // Set selected players for each team
     const playersASelect = document.getElementById('match-players-a');
@@ -27,9 +33,114 @@ window.editMatch = async function(matchId) {
 };
 // Ryder Admin Panel JS
 
+// --- Login ---
+let currentUser = null;
+
+// Send expired logins back to the login page and show what a role may not do
+const baseFetch = window.fetch;
+window.fetch = async function(url, options) {
+    const res = await baseFetch(url, options);
+    if (res.status === 401 && !String(url).startsWith('/api/auth/')) {
+        location.href = '/login?next=/admin';
+    } else if (res.status === 403) {
+        alert(await res.clone().text());
+    }
+    return res;
+};
+
+// alertError shows why a request failed, 403s were shown by fetch already
+async function alertError(res) {
+    if (res.status !== 403) alert(await res.text());
+}
+
+async function fetchMe() {
+    const res = await fetch('/api/auth/me');
+    if (!res.ok) return;
+    currentUser = await res.json();
+    const team = currentUser.team_id ? `, team ${currentUser.team_id}` : '';
+    document.getElementById('user-name').textContent = `${currentUser.username} (${currentUser.role}${team})`;
+    if (currentUser.role === 'admin') {
+        document.getElementById('users-section').style.display = '';
+        fetchUsers();
+    }
+}
+
+window.logout = async function() {
+    await fetch('/api/auth/logout', { method: 'POST' });
+    location.href = '/login?next=/admin';
+};
+
+// --- Events ---
+let currentEventId = null;
+
+function withEvent(url) {
+    if (!currentEventId) return url;
+    return url + (url.includes('?') ? '&' : '?') + 'event_id=' + currentEventId;
+}
+
+async function fetchEvents() {
+    const res = await fetch('/api/event/list');
+    if (!res.ok) return;
+    const data = await res.json();
+    const events = data.events || [];
+    if (!currentEventId) currentEventId = data.current_id;
+    const sel = document.getElementById('event-select');
+    sel.innerHTML = '';
+    events.forEach(ev => {
+        sel.innerHTML += `<option value="${ev.id}">${ev.name}${ev.archived ? ' (archived)' : ''}</option>`;
+    });
+    sel.value = currentEventId;
+    const current = events.find(ev => ev.id === currentEventId);
+    const archiveBtn = document.getElementById('event-archive');
+    archiveBtn.textContent = current && current.archived ? 'Reopen Event' : 'Archive Event';
+    archiveBtn.onclick = async function() {
+        const reopen = current && current.archived;
+        if (reopen && !confirm('Reopen this event? Its results can be changed again.')) return;
+        const res = await fetch(reopen ? '/api/event/unarchive' : '/api/event/archive', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ event_id: currentEventId })
+        });
+        if (!res.ok) await alertError(res);
+        fetchEvents();
+    };
+}
+
+function refreshEventData() {
+    fetchPlayers();
+    fetchTeams();
+    fetchSessions();
+    fetchMatches();
+    populateMatchForm().then(updateMatchPlayersSelects);
+    populatePlayerTeamSelect();
+    if (currentUser && currentUser.role === 'admin') populateUserForm();
+}
+
+document.getElementById('event-select').onchange = function() {
+    currentEventId = parseInt(this.value);
+    fetchEvents();
+    refreshEventData();
+};
+
+document.getElementById('event-form').onsubmit = async function(e) {
+    e.preventDefault();
+    const name = document.getElementById('event-name').value;
+    const start_date = document.getElementById('event-start-date').value;
+    const res = await fetch('/api/event/add', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ name, start_date })
+    });
+    if (!res.ok) return;
+    currentEventId = (await res.json()).id;
+    this.reset();
+    fetchEvents();
+    refreshEventData();
+};
+
 // --- Players ---
 async function fetchPlayers() {
-    const res = await fetch('/api/player/list');
+    const res = await fetch(withEvent('/api/player/list'));
     if (!res.ok) return;
     const data = await res.json();
     renderPlayers(data.players || []);
@@ -59,7 +170,7 @@ function renderPlayers(players) {
 }
 
 async function populatePlayerTeamSelect() {
-    const res = await fetch('/api/team/list');
+    const res = await fetch(withEvent('/api/team/list'));
     const teams = (await res.json()).teams || [];
     const teamSel = document.getElementById('player-team');
     teamSel.innerHTML = '<option value="">(none)</option>';
@@ -76,7 +187,7 @@ document.getElementById('player-form').onsubmit = async function(e) {
     const hcp = document.getElementById('player-hcp').value;
     const team_id = document.getElementById('player-team').value;
     const url = id ? '/api/player/edit' : '/api/player/add';
-    await fetch(url, {
+    await fetch(withEvent(url), {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ id: id ? parseInt(id) : undefined, name, email, hcp: hcp ? parseFloat(hcp) : null, team_id: team_id ? parseInt(team_id) : null })
@@ -99,13 +210,13 @@ window.editPlayer = function(btn) {
 };
 
 window.removePlayer = async function(id) {
-    await fetch(`/api/player/remove?id=${id}`);
+    await fetch(`/api/player/remove?id=${id}`, { method: 'POST' });
     fetchPlayers();
 };
 
 // --- Teams ---
 async function fetchTeams() {
-    const res = await fetch('/api/team/list');
+    const res = await fetch(withEvent('/api/team/list'));
     if (!res.ok) return;
     const data = await res.json();
     renderTeams(data.teams || []);
@@ -134,7 +245,7 @@ document.getElementById('team-form').onsubmit = async function(e) {
     await fetch(url, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({ id: id ? parseInt(id) : undefined, name, color })
+        body: JSON.stringify({ id: id ? parseInt(id) : undefined, event_id: currentEventId || undefined, name, color })
     });
     this.reset();
     document.querySelector('#team-form button').textContent = 'Add Team';
@@ -149,13 +260,205 @@ window.editTeam = function(id, name, color) {
 };
 
 window.removeTeam = async function(id) {
-    await fetch(`/api/team/remove?id=${id}`);
+    const res = await fetch(`/api/team/remove?id=${id}`, { method: 'POST' });
+    if (!res.ok) await alertError(res);
     fetchTeams();
 };
 
+// --- Courses ---
+let courses = [];
+
+async function fetchCourses() {
+    const res = await fetch('/api/course/list');
+    if (!res.ok) return;
+    const data = await res.json();
+    courses = data.courses || [];
+    renderCourses(courses);
+}
+
+function renderCourses(courses) {
+    const ul = document.getElementById('courses-list');
+    ul.innerHTML = '';
+    const teeCourse = document.getElementById('tee-course');
+    const matchCourse = document.getElementById('match-course');
+    teeCourse.innerHTML = '';
+    matchCourse.innerHTML = '<option value="">(none)</option>';
+    courses.forEach(c => {
+        const par = (c.holes || []).reduce((sum, h) => sum + h.par, 0);
+        const tees = (c.tees || []).map(t => `<span style="white-space:nowrap;"><span style="display:inline-block;width:0.8em;height:0.8em;background:${t.color || '#ccc'};border-radius:50%;margin:0 0.3em;"></span>${t.name}${t.slope ? ` (${t.course_rating}/${t.slope})` : ''} <a href="#" onclick="removeTee(${t.id}); return false;">&times;</a></span>`).join(' ');
+        const li = document.createElement('li');
+        li.innerHTML = `<span>${c.name} (Par ${par}) ${tees}</span>` +
+            `<span class="actions">
+                <button class="edit" onclick="editCourse(${c.id})">Edit</button>
+                <button onclick="removeCourse(${c.id})">Remove</button>
+            </span>`;
+        ul.appendChild(li);
+        teeCourse.innerHTML += `<option value="${c.id}">${c.name}</option>`;
+        matchCourse.innerHTML += `<option value="${c.id}">${c.name}</option>`;
+    });
+    updateMatchTeeSelect();
+}
+
+function parseHoleValues(text) {
+    return text.split(',').map(v => parseInt(v.trim())).filter(v => !isNaN(v));
+}
+
+document.getElementById('course-form').onsubmit = async function(e) {
+    e.preventDefault();
+    const id = document.getElementById('course-id').value;
+    const name = document.getElementById('course-name').value;
+    const pars = parseHoleValues(document.getElementById('course-par').value);
+    const indexes = parseHoleValues(document.getElementById('course-si').value);
+    const holes = pars.map((par, i) => ({ hole: i + 1, par, stroke_index: indexes[i] }));
+    const url = id ? '/api/course/edit' : '/api/course/add';
+    const res = await fetch(url, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ id: id ? parseInt(id) : undefined, name, holes })
+    });
+    if (!res.ok) {
+        await alertError(res);
+        return;
+    }
+    this.reset();
+    document.querySelector('#course-form button').textContent = 'Add Course';
+    fetchCourses();
+};
+
+window.editCourse = function(id) {
+    const c = courses.find(c => c.id === id);
+    if (!c) return;
+    document.getElementById('course-id').value = c.id;
+    document.getElementById('course-name').value = c.name;
+    document.getElementById('course-par').value = (c.holes || []).map(h => h.par).join(',');
+    document.getElementById('course-si').value = (c.holes || []).map(h => h.stroke_index).join(',');
+    document.querySelector('#course-form button').textContent = 'Save Course';
+};
+
+window.removeCourse = async function(id) {
+    const res = await fetch(`/api/course/remove?id=${id}`, { method: 'POST' });
+    if (!res.ok) await alertError(res);
+    fetchCourses();
+};
+
+document.getElementById('tee-form').onsubmit = async function(e) {
+    e.preventDefault();
+    const course_id = parseInt(document.getElementById('tee-course').value);
+    const name = document.getElementById('tee-name').value;
+    const color = document.getElementById('tee-color').value;
+    const rating = document.getElementById('tee-rating').value;
+    const slope = document.getElementById('tee-slope').value;
+    await fetch('/api/tee/add', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ course_id, name, color, course_rating: rating ? parseFloat(rating) : null, slope: slope ? parseInt(slope) : null })
+    });
+    this.reset();
+    fetchCourses();
+};
+
+window.removeTee = async function(id) {
+    const res = await fetch(`/api/tee/remove?id=${id}`, { method: 'POST' });
+    if (!res.ok) await alertError(res);
+    fetchCourses();
+};
+
+function updateMatchTeeSelect() {
+    const courseId = parseInt(document.getElementById('match-course').value);
+    const teeSel = document.getElementById('match-tee');
+    teeSel.innerHTML = '<option value="">(none)</option>';
+    const course = courses.find(c => c.id === courseId);
+    if (!course) return;
+    (course.tees || []).forEach(t => {
+        teeSel.innerHTML += `<option value="${t.id}">${t.name}</option>`;
+    });
+}
+
+document.getElementById('match-course').onchange = updateMatchTeeSelect;
+
+// --- Sessions ---
+let sessions = [];
+
+async function fetchSessions() {
+    const res = await fetch(withEvent('/api/session/list'));
+    if (!res.ok) return;
+    sessions = (await res.json()).sessions || [];
+    renderSessions(sessions);
+    const sel = document.getElementById('match-session');
+    const selected = sel.value;
+    sel.innerHTML = '<option value="">No session</option>';
+    sessions.forEach(s => {
+        sel.innerHTML += `<option value="${s.id}">${s.name}${s.locked ? ' (locked)' : ''}</option>`;
+    });
+    sel.value = selected;
+}
+
+function renderSessions(sessions) {
+    const ul = document.getElementById('sessions-list');
+    ul.innerHTML = '';
+    sessions.forEach(s => {
+        const li = document.createElement('li');
+        li.innerHTML = `<span>${s.name}${s.date ? ' | ' + s.date : ''}${s.format ? ' | ' + s.format : ''} | Points: ${s.points ?? 1}${s.locked ? ' | Locked' : ''}</span>` +
+            `<span class="actions">
+                <button class="edit" onclick="lockSession(${s.id}, ${!s.locked})">${s.locked ? 'Unlock' : 'Lock'}</button>
+                <button onclick="removeSession(${s.id})">Remove</button>
+            </span>`;
+        ul.appendChild(li);
+    });
+}
+
+document.getElementById('session-form').onsubmit = async function(e) {
+    e.preventDefault();
+    const points = parseFloat(document.getElementById('session-points').value);
+    const payload = {
+        event_id: currentEventId || undefined,
+        name: document.getElementById('session-name').value,
+        date: document.getElementById('session-date').value,
+        format: document.getElementById('session-format').value,
+        points: isNaN(points) ? null : points
+    };
+    const res = await fetch('/api/session/add', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(payload)
+    });
+    if (!res.ok) {
+        await alertError(res);
+        return;
+    }
+    this.reset();
+    fetchSessions();
+};
+
+window.lockSession = async function(sessionId, locked) {
+    await fetch('/api/session/lock', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ session_id: sessionId, locked })
+    });
+    fetchSessions();
+};
+
+window.removeSession = async function(sessionId) {
+    const res = await fetch(`/api/session/remove?id=${sessionId}`, { method: 'POST' });
+    if (!res.ok) await alertError(res);
+    fetchSessions();
+    fetchMatches();
+};
+
+// Matches of a session default to its format
+document.getElementById('match-session').onchange = function() {
+    const session = sessions.find(s => s.id === parseInt(this.value));
+    if (session && session.format) {
+        const format = document.getElementById('match-format');
+        format.value = session.format;
+        format.onchange();
+    }
+};
+
 // --- Matches ---
 async function fetchMatches() {
-    const res = await fetch('/api/match/list');
+    const res = await fetch(withEvent('/api/match/list'));
     if (!res.ok) return;
     const data = await res.json();
     renderMatches(data.matches || []);
@@ -168,11 +471,13 @@ function renderMatches(matches) {
         const teamAPlayers = (m.team_a.players || []).map(p => `${p.name} (HCP: ${p.hcp ?? ''})`).join(', ');
         const teamBPlayers = (m.team_b.players || []).map(p => `${p.name} (HCP: ${p.hcp ?? ''})`).join(', ');
         const li = document.createElement('li');
-        li.innerHTML = `<span>${m.format.toUpperCase()} | ${m.team_a?.name || ''} [${teamAPlayers}] vs ${m.team_b?.name || ''} [${teamBPlayers}] | Status: ${m.status}</span>` +
+        li.innerHTML = `<span>${m.format.toUpperCase()} | ${m.team_a?.name || ''} [${teamAPlayers}] vs ${m.team_b?.name || ''} [${teamBPlayers}] | Status: ${m.status} | Points: ${m.points}${m.session_id ? ' | ' + ((sessions.find(s => s.id === m.session_id) || {}).name || '') : ''}</span>` +
             `<span class="actions">
                 <button class="edit" onclick="editMatch(${m.id})">Edit</button>
                 <button onclick="removeMatch(${m.id})">Remove</button>
                 <button onclick="openScoreModal(${m.id}, '${m.team_a.name}', '${m.team_b.name}')">Enter Score</button>
+                <button onclick="openQRModal(${m.id}, '${m.team_a.name}', '${m.team_b.name}')">Scorer QR</button>
+                <button onclick="openAuditModal(${m.id}, '${m.team_a.name}', '${m.team_b.name}')">History</button>
             </span>`;
         ul.appendChild(li);
     });
@@ -194,6 +499,110 @@ window.openScoreModal = async function(matchId, teamAName, teamBName) {
     }
 };
 
+// Scorer QR code: only the holders of a match's card can enter its scores.
+// Each side also gets a card of its own to enter and sign its results with.
+let qrMatchId = null;
+
+function showScorerToken(data) {
+    document.getElementById('qr-image').src = `/api/match/qr?match_id=${data.match_id}&side=${data.side}&t=${data.token}`;
+    const link = document.getElementById('qr-link');
+    link.href = link.textContent = data.url;
+}
+
+window.showQRSide = async function() {
+    const side = document.getElementById('qr-side').value;
+    const res = await fetch(`/api/match/token?match_id=${qrMatchId}&side=${side}`);
+    if (!res.ok) {
+        await alertError(res);
+        return false;
+    }
+    showScorerToken(await res.json());
+    return true;
+};
+
+window.openQRModal = async function(matchId, teamAName, teamBName) {
+    qrMatchId = matchId;
+    document.getElementById('qr-title').textContent = `${teamAName} vs ${teamBName}`;
+    const select = document.getElementById('qr-side');
+    select.innerHTML = '';
+    [['', 'Match card'], ['A', `${teamAName} card`], ['B', `${teamBName} card`]].forEach(([side, label]) => {
+        select.add(new Option(label, side));
+    });
+    if (!await showQRSide()) return;
+    document.getElementById('qr-modal').style.display = 'flex';
+};
+
+window.rotateScorerToken = async function() {
+    if (!confirm('Printed cards with the old code will stop working. Continue?')) return;
+    const res = await fetch('/api/match/token', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ match_id: qrMatchId, side: document.getElementById('qr-side').value })
+    });
+    if (!res.ok) {
+        await alertError(res);
+        return;
+    }
+    showScorerToken(await res.json());
+};
+
+window.closeQRModal = function() {
+    document.getElementById('qr-modal').style.display = 'none';
+};
+
+// Audit log: who changed which status, hole result, card entry or strokes of a match, and when.
+// Values, actors and names are set as text, they come from whoever wrote them.
+function auditChange(e, playerNames) {
+    const show = v => v === '' ? '(none)' : v;
+    let what = e.field;
+    if (e.field === 'hole') what = `Hole ${e.hole}`;
+    if (e.field === 'side_a' || e.field === 'side_b') what = `Hole ${e.hole}, card of side ${e.field.slice(-1).toUpperCase()}`;
+    if (e.field === 'strokes') what = `Hole ${e.hole} strokes, ${playerNames[e.player_id] || 'player ' + e.player_id}`;
+    const line = document.createElement('div');
+    const oldValue = document.createElement('del');
+    const newValue = document.createElement('ins');
+    oldValue.textContent = show(e.old);
+    newValue.textContent = show(e.new);
+    line.append(`${what}: `, oldValue, ' \u2192 ', newValue);
+    return line;
+}
+
+window.openAuditModal = async function(matchId, teamAName, teamBName) {
+    document.getElementById('audit-title').textContent = `History: ${teamAName} vs ${teamBName}`;
+    const [auditRes, playersRes] = await Promise.all([
+        fetch(`/api/audit?match_id=${matchId}`),
+        fetch(withEvent('/api/player/list'))
+    ]);
+    if (!auditRes.ok) {
+        await alertError(auditRes);
+        return;
+    }
+    const entries = (await auditRes.json()).entries || [];
+    const playerNames = {};
+    ((await playersRes.json()).players || []).forEach(p => { playerNames[p.id] = p.name; });
+    // One row per save: the entries of a request share actor and time
+    const rows = [];
+    entries.forEach(e => {
+        const last = rows[rows.length - 1];
+        if (last && last.at === e.at && last.actor === e.actor) last.changes.push(e);
+        else rows.push({ at: e.at, actor: e.actor, changes: [e] });
+    });
+    const tbody = document.querySelector('#audit-table tbody');
+    tbody.innerHTML = rows.length ? '' : '<tr><td colspan="3">No changes yet</td></tr>';
+    rows.reverse().forEach(row => {
+        const tr = tbody.insertRow();
+        tr.insertCell().textContent = new Date(row.at).toLocaleString();
+        tr.insertCell().textContent = row.actor;
+        const changes = tr.insertCell();
+        row.changes.forEach(e => changes.appendChild(auditChange(e, playerNames)));
+    });
+    document.getElementById('audit-modal').style.display = 'flex';
+};
+
+window.closeAuditModal = function() {
+    document.getElementById('audit-modal').style.display = 'none';
+};
+
 window.closeScoreModal = function() {
     document.getElementById('score-modal').style.display = 'none';
 };
@@ -240,7 +649,7 @@ document.getElementById('match-format').onchange = function() {
     const format = this.value;
     const playersA = document.getElementById('match-players-a');
     const playersB = document.getElementById('match-players-b');
-    if (format === 'foursome' || format === 'texas_scramble') {
+    if (['foursome', 'four_ball', 'texas_scramble', 'greensomes', 'chapman', 'stableford'].includes(format)) {
         playersA.size = playersB.size = 2;
         playersA.setAttribute('multiple', 'multiple');
         playersB.setAttribute('multiple', 'multiple');
@@ -264,7 +673,7 @@ document.getElementById('match-format').onchange = function() {
 // Fix: define populateMatchForm to avoid ReferenceError
 async function populateMatchForm() {
     // Populate team selects for match creation
-    const teamsRes = await fetch('/api/team/list');
+    const teamsRes = await fetch(withEvent('/api/team/list'));
     const teams = (await teamsRes.json()).teams || [];
     const teamA = document.getElementById('match-team-a');
     const teamB = document.getElementById('match-team-b');
@@ -286,15 +695,26 @@ document.getElementById('match-form').onsubmit = async function(e) {
     const playersA = Array.from(document.getElementById('match-players-a').selectedOptions).map(opt => parseInt(opt.value));
     const playersB = Array.from(document.getElementById('match-players-b').selectedOptions).map(opt => parseInt(opt.value));
     const start_time = document.getElementById('match-start-time').value;
+    const points = parseFloat(document.getElementById('match-points').value);
+    const courseId = document.getElementById('match-course').value;
+    const teeId = document.getElementById('match-tee').value;
+    const sessionId = document.getElementById('match-session').value;
     const id = document.getElementById('match-id').value;
     const url = id ? '/api/match/edit' : '/api/match/add';
-    const payload = { format, holes, team_a: teamA, team_b: teamB, players_a: playersA, players_b: playersB, start_time };
+    const payload = { format, holes, team_a: teamA, team_b: teamB, players_a: playersA, players_b: playersB, start_time,
+        course_id: courseId ? parseInt(courseId) : null, tee_id: teeId ? parseInt(teeId) : null,
+        session_id: sessionId ? parseInt(sessionId) : null,
+        points: isNaN(points) ? null : points };
     if (id) payload.id = parseInt(id);
-    await fetch(url, {
+    const res = await fetch(url, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify(payload)
     });
+    if (!res.ok) {
+        await alertError(res);
+        return;
+    }
     this.reset();
     fetchMatches();
     // Optionally reset selects
@@ -303,19 +723,131 @@ document.getElementById('match-form').onsubmit = async function(e) {
     document.querySelector('#match-form button').textContent = 'Add Match';
 };
 
-window.onload = function() {
+window.onload = async function() {
+    await fetchMe();
+    await fetchEvents();
     fetchPlayers();
     fetchTeams();
+    fetchSessions();
     fetchMatches();
+    fetchCourses();
     populateMatchForm();
     updateMatchPlayersSelects();
     populatePlayerTeamSelect();
     document.querySelector('#player-form button').textContent = 'Add Player';
     document.querySelector('#team-form button').textContent = 'Add Team';
+    document.querySelector('#course-form button').textContent = 'Add Course';
     document.querySelector('#match-form button').textContent = 'Add Match';
 };
 
 window.removeMatch = async function(matchId) {
-    await fetch(`/api/match/remove?id=${matchId}`);
+    await fetch(`/api/match/remove?id=${matchId}`, { method: 'POST' });
     fetchMatches();
 };
+
+// --- Users ---
+let users = [];
+
+async function fetchUsers() {
+    const res = await fetch('/api/user/list');
+    if (!res.ok) return;
+    users = (await res.json()).users || [];
+    renderUsers();
+    populateUserForm();
+}
+
+function renderUsers() {
+    const ul = document.getElementById('users-list');
+    ul.innerHTML = '';
+    users.forEach(u => {
+        const team = u.team_id ? `, team ${u.team_id}` : '';
+        const matches = u.match_ids ? `, matches ${u.match_ids.join(', ')}` : '';
+        const li = document.createElement('li');
+        li.innerHTML = `<span>${u.username} (${u.role}${team}${matches})</span>` +
+            `<span class="actions">
+                <button class="edit" onclick="editUser(${u.id})">Edit</button>
+                <button onclick="removeUser(${u.id})">Remove</button>
+            </span>`;
+        ul.appendChild(li);
+    });
+}
+
+// populateUserForm fills the team and match choices from the selected event
+async function populateUserForm() {
+    const [teamsRes, matchesRes] = await Promise.all([
+        fetch(withEvent('/api/team/list')),
+        fetch(withEvent('/api/match/list'))
+    ]);
+    const teams = (await teamsRes.json()).teams || [];
+    const matches = (await matchesRes.json()).matches || [];
+    const teamSel = document.getElementById('user-team');
+    teamSel.innerHTML = '';
+    teams.forEach(t => {
+        teamSel.innerHTML += `<option value="${t.id}">${t.name}</option>`;
+    });
+    const matchSel = document.getElementById('user-matches');
+    matchSel.innerHTML = '';
+    matches.forEach(m => {
+        matchSel.innerHTML += `<option value="${m.id}">#${m.id} ${m.start_time || ''} ${m.team_a.name} vs ${m.team_b.name}</option>`;
+    });
+    updateUserRoleFields();
+}
+
+function updateUserRoleFields() {
+    const role = document.getElementById('user-role').value;
+    document.getElementById('user-team-field').style.display = role === 'captain' ? '' : 'none';
+    document.getElementById('user-matches-field').style.display = role === 'scorer' ? '' : 'none';
+}
+
+document.getElementById('user-role').onchange = updateUserRoleFields;
+
+document.getElementById('user-form').onsubmit = async function(e) {
+    e.preventDefault();
+    const id = document.getElementById('user-id').value;
+    const role = document.getElementById('user-role').value;
+    const team = document.getElementById('user-team').value;
+    const payload = {
+        id: id ? parseInt(id) : undefined,
+        username: document.getElementById('user-username').value,
+        password: document.getElementById('user-password').value,
+        role,
+        team_id: role === 'captain' && team ? parseInt(team) : null,
+        match_ids: Array.from(document.getElementById('user-matches').selectedOptions).map(opt => parseInt(opt.value))
+    };
+    const res = await fetch(id ? '/api/user/edit' : '/api/user/add', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(payload)
+    });
+    if (!res.ok) {
+        await alertError(res);
+        return;
+    }
+    this.reset();
+    document.getElementById('user-id').value = '';
+    document.querySelector('#user-form button').textContent = 'Add User';
+    updateUserRoleFields();
+    fetchUsers();
+};
+
+window.editUser = function(id) {
+    const u = users.find(u => u.id === id);
+    if (!u) return;
+    document.getElementById('user-id').value = u.id;
+    document.getElementById('user-username').value = u.username;
+    document.getElementById('user-password').value = '';
+    document.getElementById('user-role').value = u.role;
+    document.getElementById('user-team').value = u.team_id || '';
+    Array.from(document.getElementById('user-matches').options).forEach(opt => {
+        opt.selected = (u.match_ids || []).includes(parseInt(opt.value));
+    });
+    updateUserRoleFields();
+    document.querySelector('#user-form button').textContent = 'Save User';
+};
+
+window.removeUser = async function(id) {
+    if (!confirm('Remove this user?')) return;
+    const res = await fetch(`/api/user/remove?id=${id}`, { method: 'POST' });
+    if (!res.ok) await alertError(res);
+    fetchUsers();
+};
@@ -2,17 +2,177 @@ let ws = null;
 let wsConnected = false;
 let pingInterval = null;
 let pongTimeout = null;
+// The seq of the last live message seen, sent on reconnect to get the missed ones
+let lastSeq = null;
 
 // Periodically check WebSocket connection and reconnect if needed
 
 // Remove old interval check, use ping/pong instead
 // Ryder Cup Dashboard
 
+// Past events are shown with ?event=<id>
+const eventId = new URL(window.location.href).searchParams.get('event');
+// A past moment of the event is replayed with ?as_of=<RFC 3339 time>: the
+// results and status of the matches then, with the points and players of now
+let asOf = new URL(window.location.href).searchParams.get('as_of');
+// ?live=sse follows /api/events instead of the WebSocket, for browsers and
+// proxies that break WebSockets
+const useEventStream = new URL(window.location.href).searchParams.get('live') === 'sse';
+
 async function fetchDashboard() {
-    const res = await fetch('/api/dashboard');
-    const data = await res.json();
+    const params = new URLSearchParams();
+    if (eventId) params.set('event_id', eventId);
+    if (asOf) params.set('as_of', asOf);
+    const res = await fetch('/api/dashboard' + (params.size ? `?${params}` : ''));
+    dashboard = await res.json();
+    matchVersions = {};
+    renderDashboard(dashboard);
+}
+
+// The last dashboard fetched, live messages are applied to it
+let dashboard = null;
+// The version of each match the live messages applied, older ones are ignored
+let matchVersions = {};
+
+function renderDashboard(data) {
+    if (data.event && data.event.name) {
+        const title = document.getElementById('event-title');
+        if (title) title.textContent = data.event.name;
+    }
     renderTeams(data.teams || [], data.projectedScores || {});
-    renderMatches(data.matches || {});
+    // Group by session once the event has any, otherwise by status
+    const sessions = (data.sessions || []);
+    const bySession = sessions.some(s => s.id);
+    document.getElementById('sessions').style.display = bySession ? '' : 'none';
+    document.querySelectorAll('.status-section').forEach(el => el.style.display = bySession ? 'none' : '');
+    if (bySession) {
+        renderSessions(sessions, data.teams || []);
+    } else {
+        renderMatches(data.matches || {});
+    }
+}
+
+// trackSeq remembers the seq of the live messages and reports whether a
+// message is new. A resync means the missed messages are gone, so it reloads.
+function trackSeq(msg) {
+    if (msg.type === 'subscribed' || msg.type === 'resync') {
+        lastSeq = (msg.payload || {}).seq || 0;
+        if (msg.type === 'resync') fetchDashboard();
+        return false;
+    }
+    if (!msg.seq) return true;
+    if (lastSeq !== null && msg.seq <= lastSeq) return false;
+    lastSeq = msg.seq;
+    return true;
+}
+
+// Live messages, see LiveMessage in live.go. Deltas of the shown event are
+// applied to the dashboard, anything else reloads it.
+const liveProtocolVersion = 1;
+
+function applyLiveMessage(msg) {
+    if (asOf || msg.type === 'subscribed') return; // a replay doesn't change
+    if (!dashboard || msg.v !== liveProtocolVersion || msg.type === 'refresh') {
+        fetchDashboard();
+        return;
+    }
+    const p = msg.payload || {};
+    switch (msg.type) {
+    case 'hole_changed':
+    case 'status_changed': {
+        const matches = dashboardMatches(msg.id);
+        if (!matches.length) return; // another event
+        if (p.version) {
+            if (p.version < (matchVersions[msg.id] || 0)) return;
+            matchVersions[msg.id] = p.version;
+        }
+        matches.forEach(m => {
+            if (p.results) m.holeResults = p.results;
+            if (p.status) m.status = p.status;
+            // Drop the old standing, a prepared match has none
+            ['score_a', 'score_b', 'dormie', 'holes_remaining', 'result', 'score_text', 'conceded'].forEach(k => delete m[k]);
+            Object.assign(m, p.standing || {});
+        });
+        regroupMatches();
+        break;
+    }
+    case 'totals_changed':
+        if (!dashboard.event || dashboard.event.id !== msg.id) return;
+        (dashboard.teams || []).forEach(t => { t.score = (p.scores || {})[t.id] ?? 0; });
+        dashboard.projectedScores = p.projected || {};
+        (dashboard.sessions || []).forEach(s => {
+            s.scores = {};
+            (dashboard.teams || []).forEach(t => { s.scores[t.id] = ((p.sessions || {})[s.id] || {})[t.id] ?? 0; });
+        });
+        break;
+    case 'strokes_changed':
+        return; // the dashboard shows hole results only
+    default:
+        fetchDashboard();
+        return;
+    }
+    renderDashboard(dashboard);
+}
+
+// dashboardMatches returns the copies of a match in the sessions and status groups
+function dashboardMatches(id) {
+    const all = (dashboard.sessions || []).flatMap(s => s.matches || [])
+        .concat(Object.values(dashboard.matches || {}).flat());
+    return all.filter(m => m.id === id);
+}
+
+// regroupMatches moves the matches to the group of their status
+function regroupMatches() {
+    const grouped = { completed: [], running: [], prepared: [] };
+    Object.values(dashboard.matches || {}).flat().forEach(m => {
+        (grouped[m.status] || grouped.prepared).push(m);
+    });
+    dashboard.matches = grouped;
+}
+
+// Replay the dashboard as of the picked local time, or go back to live scores
+function setupReplay() {
+    const input = document.getElementById('as-of');
+    const liveBtn = document.getElementById('live-btn');
+    function show() {
+        liveBtn.style.display = asOf ? '' : 'none';
+        if (asOf) {
+            const t = new Date(asOf);
+            input.value = new Date(t.getTime() - t.getTimezoneOffset() * 60000).toISOString().slice(0, 16);
+        } else {
+            input.value = '';
+        }
+        const url = new URL(window.location.href);
+        if (asOf) url.searchParams.set('as_of', asOf); else url.searchParams.delete('as_of');
+        history.replaceState(null, '', url);
+    }
+    input.onchange = function() {
+        asOf = input.value ? new Date(input.value).toISOString() : null;
+        show();
+        fetchDashboard();
+    };
+    liveBtn.onclick = function() {
+        asOf = null;
+        show();
+        fetchDashboard();
+    };
+    show();
+}
+
+function renderSessions(sessions, teams) {
+    const div = document.getElementById('sessions');
+    div.innerHTML = '';
+    sessions.forEach(s => {
+        let title = s.name;
+        if (s.format_name) title += ` <span class="session-format">${s.format_name}</span>`;
+        if (s.locked) title += ` <span class="session-locked">Final</span>`;
+        const scores = teams.map(t => `${t.name} ${(s.scores || {})[t.id] ?? 0}`).join(' – ');
+        div.innerHTML += `<div class="matches-section">
+            <h2>${title}</h2>
+            ${s.id ? `<div class="session-score">${scores}</div>` : ''}
+            <ul>${(s.matches || []).map(matchRow).join('')}</ul>
+        </div>`;
+    });
 }
 
 function renderTeams(teams, projectedScores) {
@@ -70,7 +230,7 @@ function matchRow(m) {
     }
     const left = playerList(m.players_a);
     const right = playerList(m.players_b);
-    let format = m.format ? m.format.charAt(0).toUpperCase() + m.format.slice(1).replace('_', ' ') : '';
+    let format = m.format_name || (m.format ? m.format.charAt(0).toUpperCase() + m.format.slice(1).replace('_', ' ') : '');
     let scoreHtml = '';
     let holesLeft = null;
     // Determine holes to play based on format and holeResults
@@ -86,9 +246,10 @@ function matchRow(m) {
     }
     if ((m.status === 'completed' || m.status === 'running') && m.score_text) {
         // Split score_text into team and score if possible
-        const match = m.score_text.match(/^(.*?) (\d+ Up)$/);
+        const match = m.score_text.match(/^(.*?) (\d+ Up|\d+&\d+)$/);
         if (match) {
             scoreHtml = `<div class='score-team'>${match[1]}</div><div class='score-value'>${match[2]}</div>`;
+            if (m.dormie) scoreHtml += `<div class='score-dormie'>Dormie</div>`;
         } else if (m.score_text === 'A/S') {
             scoreHtml = `<div class='score-team'>All Square</div>`;
         } else {
@@ -114,19 +275,23 @@ function matchRow(m) {
 }
 
 window.goToScore = function(matchId) {
-    window.location.href = `/static/score.html?match=${matchId}`;
+    window.location.href = `/static/score.html?match=${matchId}` + (eventId ? `&event=${eventId}` : '');
 };
 
 
 window.onload = function() {
     console.log('Dashboard loaded');
+    setupReplay();
     fetchDashboard();
+    if (useEventStream) {
+        setupEventStream();
+        return;
+    }
     setupWebSocket();
     window.onfocus = function() {
         if (!wsConnected) {
-            console.log('Window focused: WebSocket not connected, reconnecting and fetching scores');
+            console.log('Window focused: WebSocket not connected, reconnecting');
             setupWebSocket();
-            fetchDashboard();
         }
     };
 };
@@ -145,8 +310,12 @@ function setupWebSocket() {
             clearTimeout(reconnectTimeout);
             reconnectTimeout = null;
         }
-        // Manual update on reconnect
-        fetchDashboard();
+        // The messages missed while disconnected are replayed
+        const sub = { type: 'subscribe', topics: ['dashboard'] };
+        if (lastSeq !== null) sub.since = lastSeq;
+        ws.send(JSON.stringify(sub));
+        // Nothing seen yet to continue from
+        if (lastSeq === null) fetchDashboard();
         // Start ping interval
         if (pingInterval) clearInterval(pingInterval);
         pingInterval = setInterval(function() {
@@ -178,6 +347,35 @@ function setupWebSocket() {
             if (pongTimeout) clearTimeout(pongTimeout);
             return;
         }
-        if (e.data === 'update') fetchDashboard();
+        handleLiveData(e.data);
+    };
+}
+
+// setupEventStream follows the live messages as server-sent events. The
+// browser reconnects by itself and resumes after the last event id.
+function setupEventStream() {
+    const es = new EventSource('/api/events?topics=dashboard');
+    es.onopen = function() {
+        console.log('Event stream connected');
+    };
+    es.onerror = function() {
+        console.log('Event stream disconnected, the browser reconnects');
     };
+    es.onmessage = function(e) {
+        console.log('Event stream message:', e.data);
+        handleLiveData(e.data);
+    };
+}
+
+function handleLiveData(data) {
+    let msg;
+    try {
+        msg = JSON.parse(data);
+    } catch (err) {
+        console.warn('Unknown live message, reloading');
+        fetchDashboard();
+        return;
+    }
+    if (!trackSeq(msg)) return;
+    applyLiveMessage(msg);
 }
@@ -3,6 +3,8 @@ let ws = null;
 let wsConnected = false;
 let pingInterval = null;
 let pongTimeout = null;
+// The seq of the last live message seen, sent on reconnect to get the missed ones
+let lastSeq = null;
 
 function setupWebSocket() {
     let wsProto = window.location.protocol === 'https:' ? 'wss' : 'ws';
@@ -18,8 +20,16 @@ function setupWebSocket() {
             clearTimeout(reconnectTimeout);
             reconnectTimeout = null;
         }
-        // Manual update on reconnect
-        if (currentMatch) showMatchScoreSection();
+        // Only the messages of this match, and the ones missed while disconnected
+        if (currentMatch) {
+            const sub = { type: 'subscribe', topics: [`match:${currentMatch.id}`] };
+            if (lastSeq !== null) sub.since = lastSeq;
+            ws.send(JSON.stringify(sub));
+            // Nothing seen yet to continue from
+            if (lastSeq === null) showMatchScoreSection();
+        }
+        // Back online
+        syncEdits();
         // Start ping interval
         if (pingInterval) clearInterval(pingInterval);
         pingInterval = setInterval(function() {
@@ -51,20 +61,130 @@ function setupWebSocket() {
             if (pongTimeout) clearTimeout(pongTimeout);
             return;
         }
-        if (e.data === 'update') {
+        let msg;
+        try {
+            msg = JSON.parse(e.data);
+        } catch (err) {
+            console.warn('Unknown WebSocket message, reloading');
             if (currentMatch) showMatchScoreSection();
+            return;
         }
+        if (!trackSeq(msg)) return;
+        applyLiveMessage(msg);
     };
 }
+
+// trackSeq remembers the seq of the live messages and reports whether a
+// message is new. A resync means the missed messages are gone, so it reloads.
+function trackSeq(msg) {
+    if (msg.type === 'subscribed' || msg.type === 'resync') {
+        lastSeq = (msg.payload || {}).seq || 0;
+        if (msg.type === 'resync' && currentMatch) showMatchScoreSection();
+        return false;
+    }
+    if (!msg.seq) return true;
+    if (lastSeq !== null && msg.seq <= lastSeq) return false;
+    lastSeq = msg.seq;
+    return true;
+}
+
+// Live messages, see LiveMessage in live.go. Deltas of this match are applied
+// as they come, the ones of other matches and the team totals are ignored.
+const liveProtocolVersion = 1;
+
+function applyLiveMessage(msg) {
+    if (!currentMatch || msg.type === 'subscribed') return;
+    if (msg.v !== liveProtocolVersion || msg.type === 'refresh') {
+        showMatchScoreSection();
+        return;
+    }
+    if (msg.entity !== 'match' || msg.id !== currentMatch.id) return;
+    const p = msg.payload || {};
+    // A message older than the match shown would undo newer results
+    if (p.version && p.version < matchVersion) return;
+    if (p.version) matchVersion = p.version;
+    switch (msg.type) {
+    case 'hole_changed':
+        holeResults = p.results || holeResults;
+        applyPendingEdits();
+        for (let i = 0; i < 18; i++) updateHoleButtons(i);
+        updateMatchScoreDisplay();
+        // Signatures of a card that changed don't count anymore
+        loadCard();
+        break;
+    case 'strokes_changed':
+        (p.scores || []).forEach(sc => {
+            strokes[sc.player_id + ':' + sc.hole] = sc.strokes || undefined;
+            applyPendingEdits();
+            const input = document.querySelector(`.stroke-input[data-hole="${sc.hole - 1}"][data-player="${sc.player_id}"]`);
+            if (input && input !== document.activeElement) input.value = sc.strokes || '';
+        });
+        break;
+    case 'status_changed':
+        currentMatch.status = p.status;
+        currentMatch.conceded = p.conceded || '';
+        updateFinishButton();
+        setScoringEnabled(currentMatch.status !== 'completed');
+        setupMatchActions();
+        updateMatchScoreDisplay();
+        break;
+    case 'card_changed':
+        card = p;
+        holeResults = card.holes;
+        applyPendingEdits();
+        renderHoles();
+        renderCard();
+        updateMatchScoreDisplay();
+        break;
+    default:
+        showMatchScoreSection();
+    }
+}
 // Ryder Score Entry Page
 
 let matches = [];
 let currentMatch = null;
 let holeResults = Array(18).fill(null); // 'A', 'B', 'AS'
+let strokes = {}; // key: `${player_id}:${hole}`, value: strokes
+let matchVersion = 0; // the version of the match shown, edits are based on it
+// Hole edits not synced yet, kept per match in localStorage so they survive
+// a lost connection and a reload. See SyncHoleEdits in sync.go.
+let pendingEdits = [];
+let syncing = false;
+// The card of the match, see MatchCard in card.go. With a scoring side the
+// hole buttons enter that side's results, which count once both sides agree.
+let card = null;
+let scoringSide = '';
 sEnabled = false;
 
+// Scorer token of the match, from the QR code link. Kept per match so the
+// card holder can come back through the dashboard.
+function scorerToken() {
+    if (!currentMatch) return '';
+    const key = `ryder-token-${currentMatch.id}`;
+    const fromUrl = getQueryParam('token');
+    if (fromUrl && getQueryParam('match') == currentMatch.id) localStorage.setItem(key, fromUrl);
+    return localStorage.getItem(key) || '';
+}
+
+// POST to a scoring endpoint with the scorer token
+async function postScoring(url, payload) {
+    const res = await fetch(url, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json', 'X-Scorer-Token': scorerToken() },
+        body: JSON.stringify(payload)
+    });
+    if (res.status === 401 || res.status === 403) {
+        alert('Scoring this match needs the QR code on its scorecard, or a scorer login assigned to it.');
+        sEnabled = false;
+        renderHoles();
+        await showMatchScoreSection();
+    }
+    return res;
+}
+
 async function fetchMatches() {
-    const res = await fetch('/api/match/list');
+    const res = await fetch('/api/match/list' + (getQueryParam('event') ? `?event_id=${getQueryParam('event')}` : ''));
     const data = await res.json();
     matches = data.matches || [];
 }
@@ -75,7 +195,12 @@ async function showMatchScoreSection() {
     document.getElementById('team-b').textContent = `${currentMatch.team_b.name}: ${currentMatch.team_b.players.map(p => p.name).join(', ')}`;
     // Load hole results and match status from DB
     await loadHoleResults();
+    await loadStrokes();
     await loadMatchStatus();
+    await loadCard();
+    setupScoringSide();
+    loadPendingEdits();
+    applyPendingEdits();
     renderHoles();
     updateMatchScoreDisplay();
     updateFinishButton();
@@ -98,16 +223,57 @@ async function showMatchScoreSection() {
         };
     }
     setScoringEnabled(currentMatch.status !== 'completed');
+    setupMatchActions();
+}
+
+// Undo and concession buttons, they need scoring enabled like the finish button
+function setupMatchActions() {
+    const undoBtn = document.getElementById('undo-btn');
+    if (undoBtn) {
+        undoBtn.disabled = !sEnabled;
+        undoBtn.onclick = undoLastChange;
+    }
+    [['A', currentMatch.team_a], ['B', currentMatch.team_b]].forEach(([side, team]) => {
+        const btn = document.getElementById(`concede-${side.toLowerCase()}-btn`);
+        if (!btn) return;
+        btn.textContent = `${team.name} concedes`;
+        btn.disabled = !sEnabled || scoringSide !== side || currentMatch.status === 'completed';
+        btn.onclick = () => concedeMatch(side, team.name);
+    });
+    renderCard();
+}
+
+async function undoLastChange() {
+    if (!currentMatch) return;
+    const res = await postScoring('/api/match/undo', { match_id: currentMatch.id });
+    if (res.status === 409) {
+        // Nothing to undo, or the sides keep their own cards
+        alert(await res.text());
+        return;
+    }
+    if (!res.ok) return;
+    await showMatchScoreSection();
+    localStorage.setItem('ryder-dashboard-update', Date.now().toString());
+}
+
+async function concedeMatch(side, teamName) {
+    if (!currentMatch || !confirm(`${teamName} concedes the match?`)) return;
+    const res = await postScoring('/api/match/concede', { match_id: currentMatch.id, side });
+    if (res.status === 409) alert(await res.text());
+    if (!res.ok) return;
+    await showMatchScoreSection();
+    localStorage.setItem('ryder-dashboard-update', Date.now().toString());
 }
 
 async function loadMatchStatus() {
     if (!currentMatch) return;
     // Use match list API to get latest status
-    const res = await fetch('/api/match/list');
+    const res = await fetch('/api/match/list' + (getQueryParam('event') ? `?event_id=${getQueryParam('event')}` : ''));
     if (res.ok) {
         const data = await res.json();
         const match = (data.matches || []).find(m => m.id == currentMatch.id);
         if (match && match.status) currentMatch.status = match.status;
+        if (match) currentMatch.conceded = match.conceded || '';
     }
 }
 
@@ -135,12 +301,17 @@ function renderHoles() {
         }
         const row = document.createElement('div');
         row.className = 'hole-row';
+        if (card && card.disputed.includes(i + 1)) row.classList.add('disputed');
         row.innerHTML = `<span class="hole-label">${i+1}</span>` +
             `<span class="hole-score">
             <button type="button" class="hole-btn" data-hole="${i}" data-val="A">${currentMatch.team_a.name}</button>
             <button type="button" class="hole-btn" data-hole="${i}" data-val="AS">A/S</button>
             <button type="button" class="hole-btn" data-hole="${i}" data-val="B">${currentMatch.team_b.name}</button>
-            </span>`;
+            </span>` +
+            `<span class="hole-strokes">${scoringPlayers().map(p =>
+                `<span class="stroke-cell"><input type="number" min="1" max="20" class="stroke-input" title="${p.name}" placeholder="${p.name.split(' ')[0]}" data-hole="${i}" data-player="${p.id}" value="${strokes[p.id + ':' + (i+1)] ?? ''}"${sEnabled ? '' : ' disabled'}>` +
+                `<span class="stroke-dots">${'&bull;'.repeat((p.strokes && p.strokes[i]) || 0)}</span></span>`
+            ).join('')}</span>`;
         holesDiv.appendChild(row);
     }
     if (sEnabled) {
@@ -148,6 +319,10 @@ function renderHoles() {
             btn.onclick = function() {
                 const hole = parseInt(this.getAttribute('data-hole'));
                 const val = this.getAttribute('data-val');
+                if (scoringSide) {
+                    submitSideResult(hole, val);
+                    return;
+                }
                 if (holeResults[hole] === val) {
                     // Unset if already selected
                     holeResults[hole] = undefined;
@@ -156,7 +331,16 @@ function renderHoles() {
                 }
                 updateHoleButtons(hole);
                 updateMatchScoreDisplay();
-                saveHoleResults();
+                queueEdit({ hole: hole + 1, result: holeResults[hole] || '' });
+            };
+        });
+        document.querySelectorAll('.stroke-input').forEach(input => {
+            input.onchange = function() {
+                const hole = parseInt(this.getAttribute('data-hole'));
+                const playerId = parseInt(this.getAttribute('data-player'));
+                const value = parseInt(this.value) || 0;
+                strokes[playerId + ':' + (hole + 1)] = value || undefined;
+                queueEdit({ hole: hole + 1, player_id: playerId, strokes: value });
             };
         });
     }   
@@ -179,7 +363,7 @@ function updateHoleButtons(hole) {
         btn.classList.remove('selected');
         btn.style.background = '';
         btn.style.color = '';
-        if (val === holeResults[hole]) {
+        if (val === shownResult(hole)) {
             btn.classList.add('selected');
             if (val === 'A') {
                 btn.style.background = currentMatch.team_a.color;
@@ -205,38 +389,262 @@ function updateMatchScoreDisplay() {
         if (holeResults[i] === 'A') aUp++;
         else if (holeResults[i] === 'B') bUp++;
     }
-    let scoreText = '';
-    if (aUp > bUp) scoreText = `${currentMatch.team_a.name} ${aUp-bUp} Up`;
-    else if (bUp > aUp) scoreText = `${currentMatch.team_b.name} ${bUp-aUp} Up`;
-    else scoreText = 'All Square';
     // Count holes still to play
     let holesLeft = 0;
     for (let i = start; i < end; i++) {
         if (!holeResults[i]) holesLeft++;
     }
-    scoreText += `  (${holesLeft} to play)`;
+    if (currentMatch.status === 'completed' && currentMatch.conceded) {
+        const winner = currentMatch.conceded === 'A' ? currentMatch.team_b.name : currentMatch.team_a.name;
+        document.getElementById('match-score').textContent = `${winner} wins, conceded`;
+        return;
+    }
+    const lead = Math.abs(aUp - bUp);
+    const leader = aUp > bUp ? currentMatch.team_a.name : currentMatch.team_b.name;
+    let scoreText = '';
+    if (lead > holesLeft && holesLeft > 0) scoreText = `${leader} wins ${lead}&${holesLeft}`;
+    else if (holesLeft === 0) scoreText = lead > 0 ? `${leader} wins ${lead} Up` : 'Halved';
+    else if (lead > 0) scoreText = `${leader} ${lead} Up` + (lead === holesLeft ? ' (Dormie)' : '');
+    else scoreText = 'All Square';
+    if (holesLeft > 0 && lead <= holesLeft) scoreText += `  (${holesLeft} to play)`;
     document.getElementById('match-score').textContent = scoreText;
 }
 
-async function saveHoleResults() {
-    if (!currentMatch) return;
-    await fetch(`/api/match/holescore`, {
-        method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({ match_id: currentMatch.id, holes: holeResults })
+// --- Offline edits ---
+
+function pendingKey() {
+    return `ryder-pending-${currentMatch.id}`;
+}
+
+function loadPendingEdits() {
+    pendingEdits = JSON.parse(localStorage.getItem(pendingKey()) || '[]');
+    updateSyncStatus();
+}
+
+function savePendingEdits() {
+    localStorage.setItem(pendingKey(), JSON.stringify(pendingEdits));
+    updateSyncStatus();
+}
+
+function updateSyncStatus() {
+    const el = document.getElementById('sync-status');
+    if (!el) return;
+    const n = pendingEdits.length;
+    el.textContent = n ? `${n} change${n === 1 ? '' : 's'} waiting to be saved` : '';
+}
+
+// Shows the edits not synced yet over what the server has
+function applyPendingEdits() {
+    pendingEdits.forEach(e => {
+        if (e.player_id) strokes[e.player_id + ':' + e.hole] = e.strokes || undefined;
+        else holeResults[e.hole - 1] = e.result || undefined;
     });
-    // If any hole is set, set match as running
-    if (holeResults.some(v => v === 'A' || v === 'B' || v === 'AS')) {
-        if (currentMatch.status !== 'running') {
-            await setMatchStatus('running', true);
-        }
+}
+
+function newEditKey() {
+    if (window.crypto && crypto.randomUUID) return crypto.randomUUID();
+    return Date.now().toString(36) + '-' + Math.random().toString(36).slice(2);
+}
+
+// Queues a hole edit based on the version shown, and syncs it right away
+function queueEdit(edit) {
+    pendingEdits.push({ key: newEditKey(), base_version: matchVersion, ...edit });
+    savePendingEdits();
+    syncEdits();
+}
+
+// Sends the queued edits. They stay queued while the server can't be reached;
+// edits of holes someone else changed meanwhile come back as conflicts, the
+// scorer decides whether to keep theirs.
+async function syncEdits() {
+    if (!currentMatch || syncing || !pendingEdits.length) return;
+    syncing = true;
+    const edits = pendingEdits.slice();
+    let res;
+    try {
+        res = await postScoring('/api/match/sync', { match_id: currentMatch.id, edits });
+    } catch (err) {
+        console.warn('Offline, the edits are saved later');
+        return;
+    } finally {
+        syncing = false;
+    }
+    if (res.status === 401 || res.status === 403 || res.status >= 500) return;
+    if (!res.ok) {
+        // Never taken, for example in a locked session
+        alert(`Your last changes could not be saved: ${await res.text()}`);
+        pendingEdits = pendingEdits.filter(e => !edits.includes(e));
+        savePendingEdits();
+        await showMatchScoreSection();
+        return;
+    }
+    const data = await res.json();
+    const handled = new Set(data.results.map(r => r.key));
+    pendingEdits = pendingEdits.filter(e => !handled.has(e.key));
+    savePendingEdits();
+    await showMatchScoreSection();
+    data.results.filter(r => r.status === 'rejected').forEach(r => console.warn('Edit rejected:', r.error));
+    data.results.filter(r => r.status === 'conflict').forEach(r => resolveConflict(r.conflict));
+    // The first result starts the match
+    if (currentMatch.status === 'prepared' && holeResults.some(v => v === 'A' || v === 'B' || v === 'AS')) {
+        await setMatchStatus('running', true);
     }
-    // Notify dashboard to update
     localStorage.setItem('ryder-dashboard-update', Date.now().toString());
+    if (pendingEdits.length) syncEdits();
+}
+
+// Tells the scorer about a change made while their edit was waiting, and
+// queues their value again when they keep it.
+function resolveConflict(c) {
+    const players = currentMatch.team_a.players.concat(currentMatch.team_b.players);
+    const player = players.find(p => p.id === c.player_id);
+    const result = v => ({ A: currentMatch.team_a.name, B: currentMatch.team_b.name, AS: 'A/S' })[v] || v || 'nothing';
+    const what = c.field === 'strokes' ? `the strokes of ${player ? player.name : 'a player'} on hole ${c.hole}` : `hole ${c.hole}`;
+    const text = c.field === 'strokes' ? [c.theirs || 'nothing', c.yours || 'nothing'] : [result(c.theirs), result(c.yours)];
+    if (!confirm(`${c.actor} set ${what} to ${text[0]} while your ${text[1]} was waiting to be saved. Keep yours?`)) return;
+    queueEdit(c.field === 'strokes'
+        ? { hole: c.hole, player_id: c.player_id, strokes: parseInt(c.yours) || 0 }
+        : { hole: c.hole, result: c.yours });
+}
+
+// --- Card ---
+
+async function loadCard() {
+    if (!currentMatch) return;
+    const res = await fetch(`/api/match/card?match_id=${currentMatch.id}`);
+    if (res.ok) {
+        card = await res.json();
+        renderCard();
+    }
+}
+
+// The result the hole buttons show: the scoring side's own, or the one that counts
+function shownResult(hole) {
+    if (scoringSide && card) return card.sides[scoringSide][hole] || undefined;
+    return holeResults[hole];
+}
+
+// The side this page scores for, kept per match. Without one the page enters
+// the results that count directly, as a single scorer. The QR code on a side's
+// card sets it, with that side's token, which only writes that side's card.
+function setupScoringSide() {
+    const select = document.getElementById('scoring-side');
+    if (!select) return;
+    const key = `ryder-side-${currentMatch.id}`;
+    const fromUrl = getQueryParam('side');
+    if (getQueryParam('match') == currentMatch.id && getQueryParam('token')) localStorage.setItem(key, fromUrl || '');
+    scoringSide = localStorage.getItem(key) || '';
+    select.innerHTML = `<option value="">Both sides</option>` +
+        `<option value="A">${currentMatch.team_a.name}</option>` +
+        `<option value="B">${currentMatch.team_b.name}</option>`;
+    select.value = scoringSide;
+    select.disabled = !!fromUrl;
+    select.onchange = function() {
+        scoringSide = this.value;
+        localStorage.setItem(key, scoringSide);
+        renderHoles();
+        if (sEnabled) setupMatchActions();
+    };
+}
+
+// Enters the scoring side's result of a hole, a second click on it clears it
+async function submitSideResult(hole, val) {
+    if (!card) return;
+    const holes = card.sides[scoringSide].slice();
+    holes[hole] = holes[hole] === val ? '' : val;
+    const res = await postScoring('/api/match/card', { match_id: currentMatch.id, side: scoringSide, holes });
+    if (!res.ok) {
+        if (res.status !== 401 && res.status !== 403) alert(`Your result could not be saved: ${await res.text()}`);
+        return;
+    }
+    card = await res.json();
+    holeResults = card.holes;
+    matchVersion = Math.max(matchVersion, card.version);
+    renderHoles();
+    renderCard();
+    updateMatchScoreDisplay();
+    // The first result starts the match
+    if (currentMatch.status === 'prepared' && holes.some(v => v)) await setMatchStatus('running', true);
+    localStorage.setItem('ryder-dashboard-update', Date.now().toString());
+}
+
+function renderCard() {
+    if (!currentMatch || !card) return;
+    const teams = { A: currentMatch.team_a, B: currentMatch.team_b };
+    const disputed = document.getElementById('card-disputed');
+    if (disputed) {
+        disputed.textContent = card.disputed.length
+            ? `The sides entered different results on hole ${card.disputed.join(', ')}`
+            : '';
+    }
+    const signatures = document.getElementById('card-signatures');
+    if (signatures) {
+        signatures.innerHTML = ['A', 'B'].map(side => {
+            const sig = card.signatures.find(s => s.side === side);
+            if (!sig) return `${teams[side].name}: not signed`;
+            const at = new Date(sig.signed_at).toLocaleString();
+            return `${teams[side].name}: signed by ${sig.signer}, ${at}`;
+        }).join('<br>');
+    }
+    ['A', 'B'].forEach(side => {
+        const btn = document.getElementById(`sign-${side.toLowerCase()}-btn`);
+        if (!btn) return;
+        btn.textContent = `Sign for ${teams[side].name}`;
+        btn.disabled = !sEnabled || scoringSide !== side || currentMatch.status === 'completed' || !card.result || card.disputed.length > 0;
+        btn.onclick = () => signCard(side);
+    });
+}
+
+// Signs the card for a side by one of its players. Once both sides signed
+// it, the server finishes the match.
+async function signCard(side) {
+    const team = side === 'A' ? currentMatch.team_a : currentMatch.team_b;
+    const players = team.players || [];
+    let player = players[0];
+    if (players.length > 1) {
+        const n = parseInt(prompt(`Who signs for ${team.name}?\n` + players.map((p, i) => `${i + 1}: ${p.name}`).join('\n')));
+        player = players[n - 1];
+    }
+    if (!player || !confirm(`${player.name} signs the card for ${team.name}: ${card.result}?`)) return;
+    const res = await postScoring('/api/match/sign', { match_id: currentMatch.id, side, player_id: player.id });
+    if (!res.ok) {
+        if (res.status !== 401 && res.status !== 403) alert(`The card could not be signed: ${await res.text()}`);
+        await loadCard();
+        return;
+    }
+    card = await res.json();
+    renderCard();
+    if (card.attested) {
+        await loadMatchStatus();
+        updateFinishButton();
+        setScoringEnabled(currentMatch.status !== 'completed');
+        localStorage.setItem('ryder-dashboard-update', Date.now().toString());
+    }
+}
+
+// Players whose strokes are entered: one ball per side in team ball formats
+function scoringPlayers() {
+    if (!currentMatch) return [];
+    const a = currentMatch.team_a.players || [];
+    const b = currentMatch.team_b.players || [];
+    if (['foursome', 'texas_scramble', 'greensomes', 'chapman'].includes(currentMatch.format)) {
+        return a.slice(0, 1).concat(b.slice(0, 1));
+    }
+    return a.concat(b);
+}
+
+async function loadStrokes() {
+    if (!currentMatch) return;
+    const res = await fetch(`/api/score/list?match_id=${currentMatch.id}`);
+    if (res.ok) {
+        const data = await res.json();
+        strokes = {};
+        (data.scores || []).forEach(s => { strokes[s.player_id + ':' + s.hole] = s.strokes; });
+    }
 }
 
 function setScoringEnabled(enabled) {
-    document.querySelectorAll('.hole-btn').forEach(btn => {
+    document.querySelectorAll('.hole-btn, .stroke-input').forEach(btn => {
         btn.disabled = !enabled;
         if (!enabled) {
             btn.classList.add('disabled');
@@ -251,13 +659,17 @@ async function setMatchStatus(status, silent) {
     if (!currentMatch) return;
     // Optimistically update UI
     currentMatch.status = status;
+    if (status !== 'completed') currentMatch.conceded = '';
     updateFinishButton();
     setScoringEnabled(status !== 'completed');
-    await fetch('/api/match/status', {
-        method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({ match_id: currentMatch.id, status })
-    });
+    const res = await postScoring('/api/match/status', { match_id: currentMatch.id, status });
+    if (res.status === 409) {
+        // Not signed by both sides yet
+        alert(await res.text());
+        await showMatchScoreSection();
+        return;
+    }
+    if (!res.ok) return;
     if (!silent) localStorage.setItem('ryder-dashboard-update', Date.now().toString());
 }
 
@@ -269,6 +681,7 @@ async function loadHoleResults() {
         if (Array.isArray(data.holes)) {
             holeResults = data.holes;
         }
+        matchVersion = data.version || 0;
     }
 }
 
@@ -292,6 +705,7 @@ window.onload = async function() {
         sEnabled = true;
         if (finishBtn) finishBtn.disabled = false;
         renderHoles();
+        if (currentMatch) setupMatchActions();
     }
     function resetClicks() {
         clickCount = 0;
@@ -341,11 +755,13 @@ window.onload = async function() {
             scoringEnableBtn.style.background = '#90ee90'; // light green
         };
     }
+    // Edits queued while offline are retried
+    window.addEventListener('online', syncEdits);
+    setInterval(syncEdits, 15000);
     window.onfocus = function() {
         if (!wsConnected) {
-            console.log('Window focused: WebSocket not connected, reconnecting and updating match');
+            console.log('Window focused: WebSocket not connected, reconnecting');
             setupWebSocket();
-            if (currentMatch) showMatchScoreSection();
         }
     };
     const matchId = getQueryParam('match');